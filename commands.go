@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/uptrace/bun"
+)
+
+const commandsUsage = `Usage: %s [command]
+
+Without a command the HTTP server is started.
+
+Commands:
+  import-users  Create pending users from a CSV file
+`
+
+// runCommand runs the CLI command named by args[0] instead of the server and
+// returns the process exit code.
+func runCommand(ctx context.Context, db *bun.DB, args []string) int {
+	switch args[0] {
+	case "import-users":
+		return importUsersCommand(ctx, db, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, commandsUsage, os.Args[0])
+		return 2
+	}
+}
+
+// importUsersCommand imports users like POST /users/import and prints the
+// report as JSON. No invitations are sent; admins send them from the API.
+func importUsersCommand(ctx context.Context, db *bun.DB, args []string) int {
+	flags := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only validate the file and report, create nobody")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s import-users [-dry-run] <file.csv | ->\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var file io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", path, err)
+			return 1
+		}
+		defer f.Close()
+		file = f
+	}
+
+	importService := service.NewUserImportService(userImportConfig, repository.NewUserRepository(db), nil)
+	report, err := importService.ImportUsers(ctx, nil, file, request.UserImportRequest{DryRun: *dryRun})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to import users: %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the report: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d rows: %d valid, %d created, %d rejected\n", report.Total, report.Valid, report.Created, report.Failed)
+	return 0
+}
@@ -0,0 +1,35 @@
+// Package buildinfo describes the running binary. The values are set at
+// build time with
+//
+//	go build -ldflags "-X github.com/lamkn06/user-app-golang.git/internal/buildinfo.Version=v1.2.3 ..."
+//
+// Commit and BuildTime fall back to the VCS details Go records when building
+// from a checkout.
+package buildinfo
+
+import "runtime/debug"
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = setting.Value
+			}
+		case "vcs.time":
+			if BuildTime == "" {
+				BuildTime = setting.Value
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lamkn06/user-app-golang.git/migrations"
+	"github.com/uptrace/bun"
+)
+
+// Database checks that the database answers.
+func Database(db *bun.DB) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		return "", db.PingContext(ctx)
+	}
+}
+
+// Migrations checks the schema version recorded by golang-migrate against
+// the newest migration embedded in the binary. A newer schema passes, so
+// instances of the previous release stay ready while a rollout is under way.
+func Migrations(db *bun.DB) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		expected, err := migrations.LatestVersion()
+		if err != nil {
+			return "", err
+		}
+
+		var version uint
+		var dirty bool
+		err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("no migrations applied, expected version %d", expected)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		detail := fmt.Sprintf("version %d, expected %d", version, expected)
+		switch {
+		case dirty:
+			return detail, fmt.Errorf("migration %d failed and left the schema dirty", version)
+		case version < expected:
+			return detail, fmt.Errorf("schema is at version %d, expected %d", version, expected)
+		}
+		return detail, nil
+	}
+}
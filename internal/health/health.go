@@ -0,0 +1,117 @@
+// Package health runs the checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrShuttingDown fails readiness once the instance has started to drain.
+var ErrShuttingDown = errors.New("shutting down")
+
+// CheckFunc reports whether a dependency is usable, with an optional detail
+// such as the version found.
+type CheckFunc func(ctx context.Context) (detail string, err error)
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string
+	Detail   string
+	Err      error
+	Duration time.Duration
+}
+
+// Registry holds the checks a ready instance must pass.
+type Registry struct {
+	timeout  time.Duration
+	draining atomic.Bool
+	mu       sync.Mutex
+	names    []string
+	checks   map[string]CheckFunc
+}
+
+// NewRegistry returns an empty registry giving each check up to timeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout, checks: map[string]CheckFunc{}}
+}
+
+// Register adds a check, replacing any check with the same name.
+func (r *Registry) Register(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.checks[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.checks[name] = check
+}
+
+// Drain makes every later Run fail with a "shutdown" result, telling load
+// balancers to stop routing to the instance.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Run runs all checks concurrently and returns their results in the order
+// they were registered. A check that outlives the timeout fails with the
+// context's error.
+func (r *Registry) Run(ctx context.Context) []Result {
+	if r.draining.Load() {
+		return []Result{{Name: "shutdown", Err: ErrShuttingDown}}
+	}
+
+	r.mu.Lock()
+	names := append([]string(nil), r.names...)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.Unlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = r.run(ctx, names[i], checks[i])
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Registry) run(ctx context.Context, name string, check CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() {
+		detail, err := check(ctx)
+		done <- Result{Name: name, Detail: detail, Err: err}
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		// Checks that ignore their context are abandoned
+		result = Result{Name: name, Err: ctx.Err()}
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// Healthy reports whether all results passed.
+func Healthy(results []Result) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+// Package lifecycle starts the application's components in order and stops
+// them in reverse order on shutdown.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Hook is a component of the application. Start must not block: components
+// that keep running, such as servers, run in their own goroutine and report
+// a failure with Manager.Fail. Either function may be nil; a hook without
+// Start wraps a resource that is already open, like the database pool.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Manager runs the hooks of the application.
+type Manager struct {
+	logger  *zap.SugaredLogger
+	hooks   []Hook
+	running []bool
+	failed  chan error
+}
+
+func NewManager(logger *zap.SugaredLogger) *Manager {
+	return &Manager{logger: logger, failed: make(chan error, 1)}
+}
+
+// Append adds a hook. Hooks start in the order they are appended and stop in
+// reverse, so a component is stopped before the ones it depends on.
+func (m *Manager) Append(hook Hook) {
+	m.hooks = append(m.hooks, hook)
+	m.running = append(m.running, hook.Start == nil)
+}
+
+// Fail reports that a running component stopped unexpectedly, which shuts
+// the application down. Only the first failure is kept.
+func (m *Manager) Fail(name string, err error) {
+	select {
+	case m.failed <- fmt.Errorf("%s: %w", name, err):
+	default:
+	}
+}
+
+// Start runs the start hooks in order. When one fails, the hooks already
+// started are stopped again.
+func (m *Manager) Start(ctx context.Context) error {
+	for i, hook := range m.hooks {
+		if m.running[i] {
+			continue
+		}
+		if err := hook.Start(ctx); err != nil {
+			return errors.Join(fmt.Errorf("starting %s: %w", hook.Name, err), m.Stop(ctx))
+		}
+		m.running[i] = true
+		m.logger.Debugw("Started component", "component", hook.Name)
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the running components in reverse order. Every
+// hook runs even when an earlier one fails; the errors are joined.
+func (m *Manager) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(m.hooks) - 1; i >= 0; i-- {
+		hook := m.hooks[i]
+		if !m.running[i] {
+			continue
+		}
+		m.running[i] = false
+		if hook.Stop == nil {
+			continue
+		}
+		if err := hook.Stop(ctx); err != nil {
+			m.logger.Errorw("Failed to stop component", "component", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", hook.Name, err))
+			continue
+		}
+		m.logger.Debugw("Stopped component", "component", hook.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// Run starts the application, waits for SIGINT, SIGTERM or a component
+// failure, and stops it again. Stopping may take up to shutdownTimeout.
+func (m *Manager) Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var failure error
+	select {
+	case sig := <-signals:
+		m.logger.Infow("Shutting down", "signal", sig.String())
+	case failure = <-m.failed:
+		m.logger.Errorw("Shutting down after a component failed", "error", failure)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return errors.Join(failure, m.Stop(stopCtx))
+}
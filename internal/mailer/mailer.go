@@ -0,0 +1,139 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns the mailer selected by MAILER_DRIVER. The log driver
+// writes messages to the application log and is meant for local development.
+func NewMailer(config runtime.MailerConfig) (Mailer, error) {
+	switch config.Driver {
+	case "log", "":
+		return NewLogMailer(config), nil
+	case "smtp":
+		return NewSMTPMailer(config), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", config.Driver)
+	}
+}
+
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(config runtime.MailerConfig) *LogMailer {
+	return &LogMailer{from: config.From}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	logging.NewSugaredLogger("mailer").Infow("Sending email",
+		"from", m.from,
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body,
+	)
+	return nil
+}
+
+type SMTPMailer struct {
+	config runtime.MailerConfig
+}
+
+func NewSMTPMailer(config runtime.MailerConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+// Send delivers msg like smtp.SendMail, upgrading to TLS when the server
+// offers STARTTLS. The connection is closed when ctx ends, so a stalled
+// server cannot hold the request past its deadline.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	err := m.send(ctx, msg)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (m *SMTPMailer) send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.config.SMTPHost, strconv.Itoa(m.config.SMTPPort))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.config.SMTPHost)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.SMTPHost}); err != nil {
+			return err
+		}
+	}
+	if m.config.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", m.config.SMTPUsername, m.config.SMTPPassword, m.config.SMTPHost)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.config.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.build(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *SMTPMailer) build(msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + m.config.From + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
@@ -1,60 +1,69 @@
 package middleware
 
 import (
+	"encoding/json"
 	"errors"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
 	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
 )
 
-func unwrapRecursive(err error) error {
-	var originalErr = err
+// MIMEApplicationProblemJSON is the media type of RFC 9457 problem details.
+const MIMEApplicationProblemJSON = "application/problem+json"
 
-	for originalErr != nil {
-		var internalErr = errors.Unwrap(originalErr)
-
-		if internalErr == nil {
-			break
+// ErrorHandler writes the errors returned by handlers and middleware. Clients
+// whose Accept header prefers application/problem+json get RFC 9457 problem
+// details whose type is problemTypeBase followed by the error code, such as
+// ".../problems/not-found", and whose instance is the request ID. Other
+// clients get the ErrorResponse body. Messages are translated into the
+// language of the Accept-Language header.
+func ErrorHandler(problemTypeBase string) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
 		}
 
-		originalErr = internalErr
-	}
-
-	return originalErr
-}
-
-func ErrorHandler(err error, c echo.Context) {
-	var (
-		statusCode    int
-		errorResponse interface{}
-	)
+		trans := i18n.Translator(c.Request().Header.Get("Accept-Language"))
+		header := c.Response().Header()
+		header.Add(echo.HeaderVary, echo.HeaderAccept)
+		header.Add(echo.HeaderVary, "Accept-Language")
 
-	switch e := err.(type) {
-	case *exception.ApplicationError:
-		statusCode = http.StatusBadRequest
-		errorResponse = toErrorResponse(*e)
-	default:
-		var he *echo.HTTPError
-		ok := errors.As(err, &he)
+		e, ok := err.(*exception.ApplicationError)
+		statusCode := 0
 		if !ok {
-			he = &echo.HTTPError{
-				Code: http.StatusInternalServerError,
-				Message: toErrorResponse(exception.ApplicationError{
-					Message: unwrapRecursive(err).Error(),
-					Code:    exception.ErrorCodeInternalServerError,
-				}),
+			// Echo's own errors keep their status, such as 405
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				statusCode = he.Code
 			}
+			// Unexpected errors only ever show a generic message
+			e = exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+		}
+		if statusCode == 0 {
+			statusCode = e.HTTPStatus()
 		}
+		appErr := e.Localize(trans)
+		header.Set("Content-Language", trans.Locale())
+		errorResponse := toErrorResponse(appErr)
 
-		statusCode = he.Code
-		errorResponse = he.Message
-	}
+		c.Set("errorCode", appErr.Code)
 
-	_err := c.JSON(statusCode, errorResponse)
-	if _err != nil {
-		c.Echo().Logger.Error(_err)
+		var _err error
+		if acceptsProblem(c.Request().Header.Get(echo.HeaderAccept)) {
+			_err = writeProblem(c, statusCode, toProblemResponse(c, problemTypeBase, statusCode, appErr))
+		} else {
+			_err = c.JSON(statusCode, errorResponse)
+		}
+		if _err != nil {
+			c.Echo().Logger.Error(_err)
+		}
 	}
 }
 
@@ -65,3 +74,54 @@ func toErrorResponse(error exception.ApplicationError) response.ErrorResponse {
 		Details: error.Details,
 	}
 }
+
+func toProblemResponse(c echo.Context, typeBase string, statusCode int, error exception.ApplicationError) response.ProblemResponse {
+	problem := response.ProblemResponse{
+		Type:   typeBase + strings.ReplaceAll(strings.ToLower(error.Code), "_", "-"),
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: error.Message,
+		Code:   error.Code,
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		problem.Instance = url.PathEscape(requestID)
+	}
+	if len(error.Details) > 0 {
+		problem.Details = error.Details
+	}
+	return problem
+}
+
+func writeProblem(c echo.Context, statusCode int, problem response.ProblemResponse) error {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return c.Blob(statusCode, MIMEApplicationProblemJSON, body)
+}
+
+// acceptsProblem reports whether the Accept header names
+// application/problem+json with at least the quality of application/json.
+// Wildcards alone keep the ErrorResponse body for older clients.
+func acceptsProblem(accept string) bool {
+	problemQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case MIMEApplicationProblemJSON:
+			problemQ = max(problemQ, q)
+		case echo.MIMEApplicationJSON:
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
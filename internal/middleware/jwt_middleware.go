@@ -1,61 +1,131 @@
 package middleware
 
 import (
+	"errors"
+	"slices"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/lamkn06/user-app-golang.git/internal/service"
 	"github.com/lamkn06/user-app-golang.git/pkg/exception"
 )
 
-func JWTMiddleware(jwtService service.JWTService) echo.MiddlewareFunc {
+func JWTMiddleware(jwtService service.JWTService, userService service.UserService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
-				appErr := &exception.ApplicationError{
-					Code:    exception.ErrorCodeUnauthorized,
-					Message: "Authorization header required",
-					Details: []exception.ErrorDetail{},
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeUnauthorized,
+					Message:   "Authorization header required",
+					MessageID: "authorization_header_required",
+					Details:   []exception.ErrorDetail{},
 				}
-				return c.JSON(appErr.HTTPStatus(), appErr)
 			}
 
 			// Extract token from "Bearer <token>"
 			tokenParts := strings.Split(authHeader, " ")
 			if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-				appErr := &exception.ApplicationError{
-					Code:    exception.ErrorCodeUnauthorized,
-					Message: "Invalid authorization header format",
-					Details: []exception.ErrorDetail{},
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeUnauthorized,
+					Message:   "Invalid authorization header format",
+					MessageID: "invalid_authorization_header",
+					Details:   []exception.ErrorDetail{},
 				}
-				return c.JSON(appErr.HTTPStatus(), appErr)
 			}
 
 			tokenString := tokenParts[1]
 			token, err := jwtService.ValidateToken(tokenString)
 			if err != nil {
-				appErr := &exception.ApplicationError{
-					Code:    exception.ErrorCodeUnauthorized,
-					Message: "Invalid token",
-					Details: []exception.ErrorDetail{},
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeUnauthorized,
+					Message:   "Invalid token",
+					MessageID: "invalid_token",
+					Details:   []exception.ErrorDetail{},
 				}
-				return c.JSON(appErr.HTTPStatus(), appErr)
 			}
 
 			// Extract user ID and add to context
 			userID, err := jwtService.ExtractUserID(token)
 			if err != nil {
-				appErr := &exception.ApplicationError{
-					Code:    exception.ErrorCodeUnauthorized,
-					Message: "Invalid token claims",
-					Details: []exception.ErrorDetail{},
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeUnauthorized,
+					Message:   "Invalid token claims",
+					MessageID: "invalid_token_claims",
+					Details:   []exception.ErrorDetail{},
 				}
-				return c.JSON(appErr.HTTPStatus(), appErr)
 			}
 
-			// Add user ID to context
+			// Reject tokens of users that have since been deleted or deactivated
+			user, err := userService.GetActiveUser(c.Request().Context(), userID)
+			if err != nil {
+				message, messageID := "User not found", "user_not_found"
+				if errors.Is(err, service.ErrUserInactive) {
+					message, messageID = "User account is deactivated", "user_inactive"
+				} else if !errors.Is(err, service.ErrUserNotFound) {
+					return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+				}
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeUnauthorized,
+					Message:   message,
+					MessageID: messageID,
+					Details:   []exception.ErrorDetail{},
+				}
+			}
+
+			// Add user ID, role and active organization to context
 			c.Set("userID", userID)
+			c.Set("userRole", user.Role)
+			c.Set("organizationID", jwtService.ExtractOrganizationID(token))
+			withUser(c, userID)
+			return next(c)
+		}
+	}
+}
+
+// RequireRole only lets through users authenticated by JWTMiddleware whose
+// role is one of roles.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("userRole").(string)
+			if !slices.Contains(roles, role) {
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeForbidden,
+					Message:   "Insufficient permissions",
+					MessageID: "insufficient_permissions",
+					Details:   []exception.ErrorDetail{},
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireWorkspace resolves the active workspace of a user authenticated by
+// JWTMiddleware and stores it in the context under "workspace". Tokens for an
+// organization the user has since left are rejected.
+func RequireWorkspace(organizationService service.OrganizationService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID := c.Get("userID").(uuid.UUID)
+			organizationID, _ := c.Get("organizationID").(*uuid.UUID)
+
+			workspace, err := organizationService.ResolveWorkspace(c.Request().Context(), userID, organizationID)
+			if err != nil {
+				if !errors.Is(err, service.ErrOrganizationNotFound) {
+					return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+				}
+				return &exception.ApplicationError{
+					Code:      exception.ErrorCodeForbidden,
+					Message:   "You are no longer a member of the active organization",
+					MessageID: "organization_membership_revoked",
+					Details:   []exception.ErrorDetail{},
+				}
+			}
+
+			c.Set("workspace", workspace)
 			return next(c)
 		}
 	}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/metrics"
+)
+
+// Metrics records the count, latency and status of requests by route, and
+// the code of error responses, which ErrorHandler leaves in the context
+// under "errorCode".
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			metrics.HTTPRequestsInFlight.Inc()
+			defer metrics.HTTPRequestsInFlight.Dec()
+			start := time.Now()
+
+			err := next(c)
+			if err != nil && !c.Response().Committed {
+				// Write the error response now so its status is recorded
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				// Keep unknown paths from creating a series each
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			if code, _ := c.Get("errorCode").(string); code != "" {
+				metrics.ApplicationErrors.WithLabelValues(code).Inc()
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/ratelimit"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+// RateLimitKeyFunc returns who a request is counted against.
+type RateLimitKeyFunc func(c echo.Context) string
+
+// RateLimitPolicy is the limit shared by the requests of a route group.
+type RateLimitPolicy struct {
+	// Name separates the buckets of different policies.
+	Name  string
+	Limit ratelimit.Limit
+	Key   RateLimitKeyFunc
+	// Skip, when set, exempts the requests it returns true for.
+	Skip func(c echo.Context) bool
+}
+
+// KeyByIP counts requests per client IP.
+func KeyByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// KeyByUser counts requests per user when they carry a valid access token,
+// and per client IP otherwise. It runs before JWTMiddleware, so it only
+// checks the token's signature.
+func KeyByUser(jwtService service.JWTService) RateLimitKeyFunc {
+	return func(c echo.Context) string {
+		tokenString, ok := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if ok {
+			if token, err := jwtService.ValidateToken(tokenString); err == nil {
+				if userID, err := jwtService.ExtractUserID(token); err == nil {
+					return "user:" + userID.String()
+				}
+			}
+		}
+		return KeyByIP(c)
+	}
+}
+
+// RateLimit rejects requests beyond the policy's limit with 429 and reports
+// the bucket in RateLimit-* headers. A nil store disables limiting. When the
+// store fails, requests are let through.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if store == nil {
+			return next
+		}
+		return func(c echo.Context) error {
+			if policy.Skip != nil && policy.Skip(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			result, err := store.Take(req.Context(), policy.Name+":"+policy.Key(c), policy.Limit)
+			if err != nil {
+				logging.LoggerFromContext(req.Context()).Errorw("Failed to check rate limit", "policy", policy.Name, "error", err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(policy.Limit.Burst))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit.Burst, ceilSeconds(policy.Limit.Period)))
+			if result.Allowed {
+				return next(c)
+			}
+
+			retryAfter := max(1, ceilSeconds(result.RetryAfter))
+			header.Set(echo.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return &exception.ApplicationError{
+				Code:        exception.ErrorCodeTooManyRequests,
+				Message:     fmt.Sprintf("Too many requests, try again in %d seconds", retryAfter),
+				MessageID:   "rate_limited",
+				MessageArgs: []string{strconv.Itoa(retryAfter)},
+				Details:     []exception.ErrorDetail{},
+			}
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
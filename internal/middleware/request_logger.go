@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLength bounds request IDs taken from clients so they cannot
+// flood the logs.
+const maxRequestIDLength = 128
+
+// RequestLogger gives every request an ID and a logger carrying it, and logs
+// one access line per request. The ID is taken from the X-Request-ID header
+// when the client or a proxy sent a usable one, and echoed in the response.
+// Errors are passed on to the outer middleware after they are logged.
+func RequestLogger(base *zap.SugaredLogger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+			c.Set("requestID", requestID)
+
+			logger := base.With(
+				"request_id", requestID,
+				"method", req.Method,
+				"route", c.Path(),
+				"client_ip", c.RealIP(),
+			)
+			// Tie the log lines to the request's trace
+			if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+				logger = logger.With("trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+			}
+			c.SetRequest(req.WithContext(logging.AddLoggerToContext(req.Context(), logger)))
+
+			err := next(c)
+			if err != nil && !c.Response().Committed {
+				// Write the error response now so its status is logged
+				c.Error(err)
+			}
+
+			// JWTMiddleware adds the user to the request logger
+			logger = logging.LoggerFromContext(c.Request().Context())
+			res := c.Response()
+			fields := []any{
+				"status", res.Status,
+				"latency", time.Since(start),
+				"bytes_in", req.ContentLength,
+				"bytes_out", res.Size,
+				"path", req.URL.Path,
+			}
+			if err != nil {
+				fields = append(fields, "error", err)
+			}
+			switch {
+			case res.Status >= 500:
+				logger.Errorw("request", fields...)
+			case res.Status >= 400:
+				logger.Warnw("request", fields...)
+			default:
+				logger.Infow("request", fields...)
+			}
+			return err
+		}
+	}
+}
+
+// withUser adds the authenticated user to the request logger.
+func withUser(c echo.Context, userID uuid.UUID) {
+	req := c.Request()
+	logger := logging.LoggerFromContext(req.Context()).With("user_id", userID.String())
+	c.SetRequest(req.WithContext(logging.AddLoggerToContext(req.Context(), logger)))
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestTimeout sets the deadline of each request's context. routes maps
+// "METHOD /route/:param" to a timeout replacing fallback; zero means no
+// deadline. Handlers are not interrupted: the services, queries and outgoing
+// calls they make fail once the deadline passes.
+func RequestTimeout(fallback time.Duration, routes map[string]time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			timeout, ok := routes[req.Method+" "+c.Path()]
+			if !ok {
+				timeout = fallback
+			}
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/lamkn06/user-app-golang.git/pkg/exception"
@@ -11,25 +10,13 @@ import (
 func ParseValidationError(err error) *exception.ApplicationError {
 	var validationErrs validator.ValidationErrors
 	if errors.As(err, &validationErrs) {
-		var details []exception.ErrorDetail
-		for _, vErr := range validationErrs {
-			details = append(details, exception.ErrorDetail{
-				Key:     vErr.Namespace(),
-				Field:   vErr.Field(),
-				Message: fmt.Sprintf("Failed on the '%s' tag", vErr.Tag()),
-			})
-		}
-
 		return &exception.ApplicationError{
-			Code:    exception.ErrorCodeValidation,
-			Message: "Validation failed",
-			Details: details,
+			Code:      exception.ErrorCodeValidation,
+			Message:   "Validation failed",
+			MessageID: "validation_failed",
+			Details:   exception.ValidationDetails(validationErrs),
 		}
 	}
 
-	return &exception.ApplicationError{
-		Code:    exception.ErrorCodeBadRequest,
-		Message: err.Error(),
-		Details: []exception.ErrorDetail{},
-	}
+	return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 }
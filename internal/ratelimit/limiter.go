@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/uptrace/bun"
+)
+
+// Limit is a token bucket holding up to Burst requests that refills at Burst
+// requests per Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", such as "10/1m".
+func ParseLimit(s string) (Limit, error) {
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <requests>/<period>", s)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive number", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+	return Limit{Burst: burst, Period: d}, nil
+}
+
+// rate is the refill speed in requests per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result describes a bucket after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, zero when
+	// one is allowed now.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	result := Result{
+		Allowed:    allowed,
+		Remaining:  int(math.Max(0, math.Floor(tokens))),
+		ResetAfter: seconds((float64(limit.Burst) - tokens) / limit.rate()),
+	}
+	if tokens < 1 {
+		result.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take counts one request against the bucket at key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Cleanup forgets buckets that have refilled completely.
+	Cleanup(ctx context.Context) error
+}
+
+// NewStore returns the store selected by RATE_LIMIT_BACKEND.
+func NewStore(config runtime.RateLimitConfig, db *bun.DB) (Store, error) {
+	switch config.Backend {
+	case "memory", "":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", config.Backend)
+	}
+}
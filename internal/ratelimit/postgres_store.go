@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// takeQuery refills the bucket for the time since its last request, then
+// takes a token if a whole one is left. New buckets start full. The row lock
+// taken by the upsert serializes concurrent requests across replicas, and the
+// database clock is the only one used.
+var takeQuery = strings.ReplaceAll(`
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at, full_at)
+VALUES (?0, ?1 - 1, true, now(), now() + ?3 * interval '1 second')
+ON CONFLICT (key) DO UPDATE SET
+	allowed = ?4 >= 1,
+	tokens = ?4 - CASE WHEN ?4 >= 1 THEN 1 ELSE 0 END,
+	updated_at = now(),
+	full_at = now() + (?1 - (?4 - CASE WHEN ?4 >= 1 THEN 1 ELSE 0 END)) / ?2 * interval '1 second'
+RETURNING tokens, allowed`,
+	// Tokens of the existing bucket before this request
+	"?4", "LEAST(?1::double precision, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::double precision * ?2)",
+)
+
+type bucketRow struct {
+	Tokens  float64 `bun:"tokens"`
+	Allowed bool    `bun:"allowed"`
+}
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so all
+// replicas share the same limits.
+type PostgresStore struct {
+	db *bun.DB
+}
+
+func NewPostgresStore(db *bun.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var out bucketRow
+	rate := limit.rate()
+	err := s.db.NewRaw(takeQuery, key, limit.Burst, rate, 1/rate).
+		Scan(ctx, &out)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(limit, out.Tokens, out.Allowed), nil
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context) error {
+	_, err := s.db.NewDelete().
+		TableExpr("rate_limit_buckets").
+		Where("full_at <= now()").
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	AccountDeletionStatusScheduled = "scheduled"
+	AccountDeletionStatusCancelled = "cancelled"
+	AccountDeletionStatusPurging   = "purging"
+	AccountDeletionStatusCompleted = "completed"
+)
+
+// userOwnedTables are purged in this order before the user row itself. Any
+// other rows referencing the user are removed by ON DELETE CASCADE.
+var userOwnedTables = []string{
+	"resource_grants",
+	"todos",
+	"categories",
+	"webauthn_credentials",
+	"webauthn_sessions",
+	"action_tokens",
+	"data_exports",
+}
+
+type AccountDeletionEntity struct {
+	bun.BaseModel `bun:"account_deletions"`
+
+	Id           uuid.UUID  `bun:"id,pk,type:uuid"`
+	UserId       uuid.UUID  `bun:"user_id,notnull,type:uuid"`
+	Status       string     `bun:"status,notnull,default:'scheduled'"`
+	ScheduledFor time.Time  `bun:"scheduled_for,notnull"`
+	TotalRows    int64      `bun:"total_rows,notnull"`
+	DeletedRows  int64      `bun:"deleted_rows,notnull"`
+	Error        string     `bun:"error,nullzero"`
+	RequestedAt  time.Time  `bun:"requested_at,notnull,default:current_timestamp"`
+	CancelledAt  *time.Time `bun:"cancelled_at"`
+	StartedAt    *time.Time `bun:"started_at"`
+	UpdatedAt    time.Time  `bun:"updated_at,notnull,default:current_timestamp"`
+	CompletedAt  *time.Time `bun:"completed_at"`
+}
+
+type UserTombstoneEntity struct {
+	bun.BaseModel `bun:"user_tombstones"`
+
+	UserId     uuid.UUID `bun:"user_id,pk,type:uuid"`
+	DeletionId uuid.UUID `bun:"deletion_id,notnull,type:uuid"`
+	PurgedAt   time.Time `bun:"purged_at,notnull"`
+}
+
+type AccountDeletionRepository interface {
+	InsertDeletion(ctx context.Context, deletion AccountDeletionEntity) (out AccountDeletionEntity, err error)
+	GetDeletionById(ctx context.Context, id uuid.UUID) (out AccountDeletionEntity, err error)
+	GetOpenDeletionByUserId(ctx context.Context, userId uuid.UUID) (out AccountDeletionEntity, err error)
+	CancelScheduledDeletion(ctx context.Context, userId uuid.UUID) (cancelled bool, err error)
+	ClaimDueDeletion(ctx context.Context, staleAfter time.Duration) (out AccountDeletionEntity, err error)
+	CountUserRows(ctx context.Context, userId uuid.UUID) (int64, error)
+	SetDeletionTotal(ctx context.Context, id uuid.UUID, total int64) error
+	PurgeUserRowsBatch(ctx context.Context, id uuid.UUID, userId uuid.UUID, limit int) (int64, error)
+	RecordDeletionError(ctx context.Context, id uuid.UUID, reason string) error
+	CompleteDeletion(ctx context.Context, deletion AccountDeletionEntity) error
+}
+
+type DefaultAccountDeletionRepository struct {
+	db *bun.DB
+}
+
+func NewAccountDeletionRepository(db *bun.DB) AccountDeletionRepository {
+	return &DefaultAccountDeletionRepository{db: db}
+}
+
+func (r *DefaultAccountDeletionRepository) InsertDeletion(ctx context.Context, deletion AccountDeletionEntity) (out AccountDeletionEntity, err error) {
+	_, err = r.db.NewInsert().Model(&deletion).Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return deletion, nil
+}
+
+func (r *DefaultAccountDeletionRepository) GetDeletionById(ctx context.Context, id uuid.UUID) (out AccountDeletionEntity, err error) {
+	err = r.db.NewSelect().Model(&out).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+// GetOpenDeletionByUserId returns the user's deletion that is scheduled or
+// being purged, if any.
+func (r *DefaultAccountDeletionRepository) GetOpenDeletionByUserId(ctx context.Context, userId uuid.UUID) (out AccountDeletionEntity, err error) {
+	err = r.db.NewSelect().
+		Model(&out).
+		Where("user_id = ?", userId).
+		Where("status IN (?)", bun.In([]string{AccountDeletionStatusScheduled, AccountDeletionStatusPurging})).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+// CancelScheduledDeletion cancels the user's deletion unless the purge has
+// already started.
+func (r *DefaultAccountDeletionRepository) CancelScheduledDeletion(ctx context.Context, userId uuid.UUID) (cancelled bool, err error) {
+	now := time.Now()
+	res, err := r.db.NewUpdate().
+		Model((*AccountDeletionEntity)(nil)).
+		Set("status = ?", AccountDeletionStatusCancelled).
+		Set("cancelled_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("user_id = ?", userId).
+		Where("status = ?", AccountDeletionStatusScheduled).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// ClaimDueDeletion marks the oldest deletion whose grace period has ended as
+// purging and returns it. Purges that have made no progress for staleAfter
+// are claimed again so another replica can resume them.
+func (r *DefaultAccountDeletionRepository) ClaimDueDeletion(ctx context.Context, staleAfter time.Duration) (out AccountDeletionEntity, err error) {
+	now := time.Now()
+	next := r.db.NewSelect().
+		Model((*AccountDeletionEntity)(nil)).
+		Column("id").
+		Where("status = ? AND scheduled_for <= ?", AccountDeletionStatusScheduled, now).
+		WhereOr("status = ? AND updated_at < ?", AccountDeletionStatusPurging, now.Add(-staleAfter)).
+		Order("scheduled_for ASC").
+		Limit(1).
+		For("UPDATE SKIP LOCKED")
+
+	_, err = r.db.NewUpdate().
+		Model(&out).
+		Set("status = ?", AccountDeletionStatusPurging).
+		Set("started_at = COALESCE(started_at, ?)", now).
+		Set("updated_at = ?", now).
+		Where("id = (?)", next).
+		Returning("*").
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+// CountUserRows counts the rows the purge will delete, including soft-deleted
+// ones and the user row itself.
+func (r *DefaultAccountDeletionRepository) CountUserRows(ctx context.Context, userId uuid.UUID) (int64, error) {
+	total := int64(1)
+	for _, table := range userOwnedTables {
+		count, err := r.db.NewSelect().
+			TableExpr("?", bun.Ident(table)).
+			Where("user_id = ?", userId).
+			Count(ctx)
+		if err != nil {
+			return 0, translateError(err)
+		}
+		total += int64(count)
+	}
+	return total, nil
+}
+
+func (r *DefaultAccountDeletionRepository) SetDeletionTotal(ctx context.Context, id uuid.UUID, total int64) error {
+	_, err := r.db.NewUpdate().
+		Model((*AccountDeletionEntity)(nil)).
+		Set("total_rows = ?", total).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return translateError(err)
+}
+
+// PurgeUserRowsBatch hard-deletes up to limit rows owned by the user from the
+// first table that still has any, records the progress on the deletion and
+// returns how many rows were deleted. It returns 0 once only the user row is
+// left.
+func (r *DefaultAccountDeletionRepository) PurgeUserRowsBatch(ctx context.Context, id uuid.UUID, userId uuid.UUID, limit int) (int64, error) {
+	for _, table := range userOwnedTables {
+		var deleted int64
+		err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if err := setStatementTimeout(ctx, tx); err != nil {
+				return err
+			}
+			batch := tx.NewSelect().
+				TableExpr("?", bun.Ident(table)).
+				Column("id").
+				Where("user_id = ?", userId).
+				Limit(limit)
+			res, err := tx.NewDelete().
+				TableExpr("?", bun.Ident(table)).
+				Where("id IN (?)", batch).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+			deleted = rowsAffected(res)
+			if deleted == 0 {
+				return nil
+			}
+
+			_, err = tx.NewUpdate().
+				Model((*AccountDeletionEntity)(nil)).
+				Set("deleted_rows = deleted_rows + ?", deleted).
+				Set("updated_at = ?", time.Now()).
+				Where("id = ?", id).
+				Exec(ctx)
+			return err
+		})
+		if err != nil {
+			return 0, translateError(err)
+		}
+		if deleted > 0 {
+			return deleted, nil
+		}
+	}
+	return 0, nil
+}
+
+func (r *DefaultAccountDeletionRepository) RecordDeletionError(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.db.NewUpdate().
+		Model((*AccountDeletionEntity)(nil)).
+		Set("error = ?", reason).
+		Where("id = ?", id).
+		Exec(ctx)
+	return translateError(err)
+}
+
+// CompleteDeletion removes the user row, leaves a tombstone in its place and
+// marks the deletion completed, all in one transaction.
+func (r *DefaultAccountDeletionRepository) CompleteDeletion(ctx context.Context, deletion AccountDeletionEntity) error {
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+		now := time.Now()
+
+		res, err := tx.NewDelete().
+			Model((*UserEntity)(nil)).
+			Where("id = ?", deletion.UserId).
+			ForceDelete().
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewInsert().
+			Model(&UserTombstoneEntity{UserId: deletion.UserId, DeletionId: deletion.Id, PurgedAt: now}).
+			On("CONFLICT (user_id) DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*AccountDeletionEntity)(nil)).
+			Set("status = ?", AccountDeletionStatusCompleted).
+			Set("deleted_rows = deleted_rows + ?", rowsAffected(res)).
+			Set("error = NULL").
+			Set("updated_at = ?", now).
+			Set("completed_at = ?", now).
+			Where("id = ?", deletion.Id).
+			Exec(ctx)
+		return err
+	})
+	return translateError(err)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	ActionTokenPurposeMagicLink          = "magic_link"
+	ActionTokenPurposeEmailChange        = "email_change"
+	ActionTokenPurposeEmailChangeRevert  = "email_change_revert"
+	ActionTokenPurposeInvitation         = "invitation"
+	ActionTokenPurposeOrganizationInvite = "organization_invitation"
+)
+
+// ActionTokenEntity records a single-use token sent to a user by email. The
+// token itself is a signed JWT carrying the row id; the row tracks expiry and
+// whether it has already been used.
+type ActionTokenEntity struct {
+	bun.BaseModel `bun:"action_tokens"`
+
+	Id         uuid.UUID         `bun:"id,pk,type:uuid"`
+	UserId     *uuid.UUID        `bun:"user_id,type:uuid"`
+	Purpose    string            `bun:"purpose,notnull"`
+	Email      string            `bun:"email,notnull"`
+	Payload    map[string]string `bun:"payload,type:jsonb,notnull"`
+	ExpiresAt  time.Time         `bun:"expires_at,notnull"`
+	ConsumedAt *time.Time        `bun:"consumed_at"`
+	CreatedAt  time.Time         `bun:"created_at,notnull,default:current_timestamp"`
+}
+
+type ActionTokenRepository interface {
+	InsertToken(ctx context.Context, token ActionTokenEntity) (out ActionTokenEntity, err error)
+	GetUsableToken(ctx context.Context, id uuid.UUID, purpose string) (out ActionTokenEntity, err error)
+	ConsumeToken(ctx context.Context, id uuid.UUID, purpose string) (out ActionTokenEntity, err error)
+	GetTokensByUserId(ctx context.Context, userId uuid.UUID) ([]ActionTokenEntity, error)
+	RevokeTokens(ctx context.Context, userId uuid.UUID, purposes ...string) error
+	DeleteExpiredTokens(ctx context.Context) error
+}
+
+type DefaultActionTokenRepository struct {
+	db *bun.DB
+}
+
+func NewActionTokenRepository(db *bun.DB) ActionTokenRepository {
+	return &DefaultActionTokenRepository{db: db}
+}
+
+func (r *DefaultActionTokenRepository) InsertToken(ctx context.Context, token ActionTokenEntity) (out ActionTokenEntity, err error) {
+	if token.Payload == nil {
+		token.Payload = map[string]string{}
+	}
+	_, err = r.db.NewInsert().Model(&token).Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return token, nil
+}
+
+// GetUsableToken returns an unexpired, unused token without consuming it.
+func (r *DefaultActionTokenRepository) GetUsableToken(ctx context.Context, id uuid.UUID, purpose string) (out ActionTokenEntity, err error) {
+	err = r.db.NewSelect().
+		Model(&out).
+		Where("id = ?", id).
+		Where("purpose = ?", purpose).
+		Where("consumed_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+// ConsumeToken marks an unexpired, unused token as consumed and returns it.
+// The check and the update happen in one statement so a token can only be
+// redeemed once even under concurrent requests.
+func (r *DefaultActionTokenRepository) ConsumeToken(ctx context.Context, id uuid.UUID, purpose string) (out ActionTokenEntity, err error) {
+	now := time.Now()
+	_, err = r.db.NewUpdate().
+		Model(&out).
+		Set("consumed_at = ?", now).
+		Where("id = ?", id).
+		Where("purpose = ?", purpose).
+		Where("consumed_at IS NULL").
+		Where("expires_at > ?", now).
+		Returning("*").
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+func (r *DefaultActionTokenRepository) GetTokensByUserId(ctx context.Context, userId uuid.UUID) ([]ActionTokenEntity, error) {
+	var tokens []ActionTokenEntity
+	err := r.db.NewSelect().
+		Model(&tokens).
+		Where("user_id = ?", userId).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return []ActionTokenEntity{}, translateError(err)
+	}
+	return tokens, nil
+}
+
+// RevokeTokens marks the user's unused tokens for the given purposes as
+// consumed so their links stop working.
+func (r *DefaultActionTokenRepository) RevokeTokens(ctx context.Context, userId uuid.UUID, purposes ...string) error {
+	_, err := r.db.NewUpdate().
+		Model((*ActionTokenEntity)(nil)).
+		Set("consumed_at = ?", time.Now()).
+		Where("user_id = ?", userId).
+		Where("purpose IN (?)", bun.In(purposes)).
+		Where("consumed_at IS NULL").
+		Exec(ctx)
+	return translateError(err)
+}
+
+func (r *DefaultActionTokenRepository) DeleteExpiredTokens(ctx context.Context) error {
+	_, err := r.db.NewDelete().
+		Model((*ActionTokenEntity)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Exec(ctx)
+	return translateError(err)
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type CategoryEntity struct {
+	bun.BaseModel `bun:"categories"`
+
+	Id             uuid.UUID  `bun:"id,pk,type:uuid"`
+	UserId         *uuid.UUID `bun:"user_id,type:uuid"`
+	OrganizationId *uuid.UUID `bun:"organization_id,type:uuid"`
+	CreatedBy      *uuid.UUID `bun:"created_by,type:uuid"`
+	Name           string     `bun:"name,notnull"`
+	Color          string     `bun:"color,nullzero"`
+	Description    string     `bun:"description,nullzero"`
+	CreatedAt      time.Time  `bun:"created_at,notnull,default:current_timestamp"`
+	UpdatedAt      time.Time  `bun:"updated_at,notnull,default:current_timestamp"`
+	DeletedAt      time.Time  `bun:"deleted_at,soft_delete,nullzero"`
+
+	// Access is what the acting workspace may do with the category: owner,
+	// editor or viewer. Only reads that check access fill it.
+	Access string `bun:"access,scanonly"`
+}
+
+func (c CategoryEntity) Key() Cursor {
+	return Cursor{CreatedAt: c.CreatedAt, Id: c.Id}
+}
+
+// Owner returns the workspace the category belongs to.
+func (c CategoryEntity) Owner() Workspace {
+	return ownerWorkspace(c.UserId, c.OrganizationId)
+}
+
+// CategoryRepository reads and writes categories of one workspace at a time.
+// Listings only return the workspace's own rows; single categories can also
+// be reached through a grant to the workspace's user. Viewers may read,
+// editors may also update, and only the owning workspace may delete.
+type CategoryRepository interface {
+	GetCategories(ctx context.Context, workspace Workspace, page Page) ([]CategoryEntity, PageInfo, error)
+	GetCategoriesCount(ctx context.Context, workspace Workspace) (int64, error)
+	GetAllCategories(ctx context.Context, workspace Workspace, opts ...ReadOption) ([]CategoryEntity, error)
+	GetAuthoredCategories(ctx context.Context, userId uuid.UUID, opts ...ReadOption) ([]CategoryEntity, error)
+	GetCategoryById(ctx context.Context, workspace Workspace, id uuid.UUID) (out CategoryEntity, err error)
+	InsertCategory(ctx context.Context, workspace Workspace, category CategoryEntity) (out CategoryEntity, err error)
+	UpdateCategory(ctx context.Context, workspace Workspace, category CategoryEntity) (out CategoryEntity, err error)
+	DeleteCategory(ctx context.Context, workspace Workspace, id uuid.UUID) (deleted bool, err error)
+	GetSharedCategories(ctx context.Context, userId uuid.UUID, page Page) ([]CategoryEntity, PageInfo, error)
+	GetSharedCategoriesCount(ctx context.Context, userId uuid.UUID) (int64, error)
+}
+
+type DefaultCategoryRepository struct {
+	db *bun.DB
+}
+
+func NewCategoryRepository(db *bun.DB) CategoryRepository {
+	return &DefaultCategoryRepository{db: db}
+}
+
+func (r *DefaultCategoryRepository) GetCategories(ctx context.Context, workspace Workspace, page Page) ([]CategoryEntity, PageInfo, error) {
+	var categories []CategoryEntity
+	q := r.db.NewSelect().
+		Model(&categories).
+		Where(workspace.where())
+	q = selectAccess(q, workspace, categoryGrants)
+	info, err := selectPage(ctx, q, &categories, page)
+	return categories, info, translateError(err)
+}
+
+func (r *DefaultCategoryRepository) GetCategoriesCount(ctx context.Context, workspace Workspace) (int64, error) {
+	count, err := r.db.NewSelect().
+		Model((*CategoryEntity)(nil)).
+		Where(workspace.where()).
+		Count(ctx)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int64(count), nil
+}
+
+// GetAllCategories reads every category of the workspace, oldest first.
+func (r *DefaultCategoryRepository) GetAllCategories(ctx context.Context, workspace Workspace, opts ...ReadOption) ([]CategoryEntity, error) {
+	var categories []CategoryEntity
+	q := r.db.NewSelect().
+		Model(&categories).
+		Where(workspace.where()).
+		Order("created_at ASC")
+	err := applyReadOptions(q, opts).Scan(ctx)
+	if err != nil {
+		return []CategoryEntity{}, translateError(err)
+	}
+	return categories, nil
+}
+
+// GetAuthoredCategories reads the categories the user created outside their
+// personal workspace, oldest first.
+func (r *DefaultCategoryRepository) GetAuthoredCategories(ctx context.Context, userId uuid.UUID, opts ...ReadOption) ([]CategoryEntity, error) {
+	var categories []CategoryEntity
+	q := r.db.NewSelect().
+		Model(&categories).
+		Where("created_by = ?", userId).
+		Where("user_id IS DISTINCT FROM ?", userId).
+		Order("created_at ASC")
+	err := applyReadOptions(q, opts).Scan(ctx)
+	if err != nil {
+		return []CategoryEntity{}, translateError(err)
+	}
+	return categories, nil
+}
+
+func (r *DefaultCategoryRepository) GetCategoryById(ctx context.Context, workspace Workspace, id uuid.UUID) (out CategoryEntity, err error) {
+	accessible, args := whereAccessible(workspace, categoryGrants, GrantRoleViewer, GrantRoleEditor)
+	q := r.db.NewSelect().
+		Model(&out).
+		Where("id = ?", id).
+		Where(accessible, args...)
+	err = selectAccess(q, workspace, categoryGrants).Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+func (r *DefaultCategoryRepository) InsertCategory(ctx context.Context, workspace Workspace, category CategoryEntity) (out CategoryEntity, err error) {
+	category.UserId, category.OrganizationId = workspace.owner()
+	_, err = r.db.NewInsert().Model(&category).Returning("*").Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	category.Access = AccessOwner
+	return category, nil
+}
+
+// UpdateCategory saves the category's fields. It returns sql.ErrNoRows when
+// the workspace neither owns the category nor may edit it.
+func (r *DefaultCategoryRepository) UpdateCategory(ctx context.Context, workspace Workspace, category CategoryEntity) (out CategoryEntity, err error) {
+	accessible, args := whereAccessible(workspace, categoryGrants, GrantRoleEditor)
+	access, accessArgs := accessColumn(workspace, categoryGrants)
+	_, err = r.db.NewUpdate().
+		Model(&out).
+		Set("name = ?", category.Name).
+		Set("color = ?", nullString(category.Color)).
+		Set("description = ?", nullString(category.Description)).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", category.Id).
+		Where(accessible, args...).
+		Returning("*, "+access, accessArgs...).
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+// DeleteCategory soft-deletes the category and stops sharing it. Its todos
+// keep pointing at it until it is purged.
+func (r *DefaultCategoryRepository) DeleteCategory(ctx context.Context, workspace Workspace, id uuid.UUID) (deleted bool, err error) {
+	err = r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+		res, err := tx.NewDelete().
+			Model((*CategoryEntity)(nil)).
+			Where("id = ?", id).
+			Where(workspace.where()).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		deleted = rowsAffected(res) > 0
+		if !deleted {
+			return nil
+		}
+		_, err = tx.NewDelete().
+			Model((*ResourceGrantEntity)(nil)).
+			Where("g.category_id = ?", id).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return false, translateError(err)
+	}
+	return deleted, nil
+}
+
+// GetSharedCategories reads the categories shared with userId, newest first.
+func (r *DefaultCategoryRepository) GetSharedCategories(ctx context.Context, userId uuid.UUID, page Page) ([]CategoryEntity, PageInfo, error) {
+	var categories []CategoryEntity
+	shared, args := whereShared(userId, categoryGrants)
+	role, roleArgs := grantedRole(userId, categoryGrants)
+	q := r.db.NewSelect().
+		Model(&categories).
+		ColumnExpr("?TableAlias.*").
+		ColumnExpr(role+" AS access", roleArgs...).
+		Where(shared, args...)
+	info, err := selectPage(ctx, q, &categories, page)
+	return categories, info, translateError(err)
+}
+
+func (r *DefaultCategoryRepository) GetSharedCategoriesCount(ctx context.Context, userId uuid.UUID) (int64, error) {
+	shared, args := whereShared(userId, categoryGrants)
+	count, err := r.db.NewSelect().
+		Model((*CategoryEntity)(nil)).
+		Where(shared, args...).
+		Count(ctx)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int64(count), nil
+}
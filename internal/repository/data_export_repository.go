@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusCompleted  = "completed"
+	DataExportStatusFailed     = "failed"
+	DataExportStatusExpired    = "expired"
+)
+
+type DataExportEntity struct {
+	bun.BaseModel `bun:"data_exports"`
+
+	Id          uuid.UUID  `bun:"id,pk,type:uuid"`
+	UserId      uuid.UUID  `bun:"user_id,notnull,type:uuid"`
+	RequestedBy *uuid.UUID `bun:"requested_by,type:uuid"`
+	Status      string     `bun:"status,notnull,default:'pending'"`
+	Error       string     `bun:"error,nullzero"`
+	ArchiveKey  string     `bun:"archive_key,nullzero"`
+	SizeBytes   int64      `bun:"size_bytes,notnull"`
+	CreatedAt   time.Time  `bun:"created_at,notnull,default:current_timestamp"`
+	StartedAt   *time.Time `bun:"started_at"`
+	CompletedAt *time.Time `bun:"completed_at"`
+	ExpiresAt   *time.Time `bun:"expires_at"`
+}
+
+type DataExportRepository interface {
+	InsertExport(ctx context.Context, export DataExportEntity) (out DataExportEntity, err error)
+	GetExportById(ctx context.Context, id uuid.UUID) (out DataExportEntity, err error)
+	GetExportsByUserId(ctx context.Context, userId uuid.UUID) ([]DataExportEntity, error)
+	GetOpenExportByUserId(ctx context.Context, userId uuid.UUID) (out DataExportEntity, err error)
+	ClaimNextExport(ctx context.Context, staleAfter time.Duration) (out DataExportEntity, err error)
+	CompleteExport(ctx context.Context, id uuid.UUID, archiveKey string, size int64, expiresAt time.Time) error
+	FailExport(ctx context.Context, id uuid.UUID, reason string) error
+	ExpireExports(ctx context.Context) (archiveKeys []string, err error)
+}
+
+type DefaultDataExportRepository struct {
+	db *bun.DB
+}
+
+func NewDataExportRepository(db *bun.DB) DataExportRepository {
+	return &DefaultDataExportRepository{db: db}
+}
+
+func (r *DefaultDataExportRepository) InsertExport(ctx context.Context, export DataExportEntity) (out DataExportEntity, err error) {
+	_, err = r.db.NewInsert().Model(&export).Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return export, nil
+}
+
+func (r *DefaultDataExportRepository) GetExportById(ctx context.Context, id uuid.UUID) (out DataExportEntity, err error) {
+	err = r.db.NewSelect().Model(&out).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+// GetExportsByUserId lists a user's exports, newest first.
+func (r *DefaultDataExportRepository) GetExportsByUserId(ctx context.Context, userId uuid.UUID) ([]DataExportEntity, error) {
+	var exports []DataExportEntity
+	err := r.db.NewSelect().
+		Model(&exports).
+		Where("user_id = ?", userId).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return []DataExportEntity{}, translateError(err)
+	}
+	return exports, nil
+}
+
+// GetOpenExportByUserId returns the user's export that is still waiting or
+// being built, if any.
+func (r *DefaultDataExportRepository) GetOpenExportByUserId(ctx context.Context, userId uuid.UUID) (out DataExportEntity, err error) {
+	err = r.db.NewSelect().
+		Model(&out).
+		Where("user_id = ?", userId).
+		Where("status IN (?)", bun.In([]string{DataExportStatusPending, DataExportStatusProcessing})).
+		Order("created_at DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+// ClaimNextExport marks the oldest pending export as processing and returns
+// it. SKIP LOCKED lets several replicas poll the table without picking the
+// same export. Exports stuck in processing for longer than staleAfter, e.g.
+// because a replica died mid-build, are picked up again.
+func (r *DefaultDataExportRepository) ClaimNextExport(ctx context.Context, staleAfter time.Duration) (out DataExportEntity, err error) {
+	now := time.Now()
+	next := r.db.NewSelect().
+		Model((*DataExportEntity)(nil)).
+		Column("id").
+		Where("status = ?", DataExportStatusPending).
+		WhereOr("status = ? AND started_at < ?", DataExportStatusProcessing, now.Add(-staleAfter)).
+		Order("created_at ASC").
+		Limit(1).
+		For("UPDATE SKIP LOCKED")
+
+	_, err = r.db.NewUpdate().
+		Model(&out).
+		Set("status = ?", DataExportStatusProcessing).
+		Set("started_at = ?", now).
+		Where("id = (?)", next).
+		Returning("*").
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+// CompleteExport records the blob store key of the finished archive.
+func (r *DefaultDataExportRepository) CompleteExport(ctx context.Context, id uuid.UUID, archiveKey string, size int64, expiresAt time.Time) error {
+	_, err := r.db.NewUpdate().
+		Model((*DataExportEntity)(nil)).
+		Set("status = ?", DataExportStatusCompleted).
+		Set("archive_key = ?", archiveKey).
+		Set("size_bytes = ?", size).
+		Set("completed_at = ?", time.Now()).
+		Set("expires_at = ?", expiresAt).
+		Where("id = ?", id).
+		Exec(ctx)
+	return translateError(err)
+}
+
+func (r *DefaultDataExportRepository) FailExport(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.db.NewUpdate().
+		Model((*DataExportEntity)(nil)).
+		Set("status = ?", DataExportStatusFailed).
+		Set("error = ?", reason).
+		Set("completed_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return translateError(err)
+}
+
+// ExpireExports marks completed exports whose download link has expired and
+// returns the keys of their archives, which the caller removes from the blob
+// store.
+func (r *DefaultDataExportRepository) ExpireExports(ctx context.Context) (archiveKeys []string, err error) {
+	expired := r.db.NewSelect().
+		Model((*DataExportEntity)(nil)).
+		Column("id", "archive_key").
+		Where("status = ?", DataExportStatusCompleted).
+		Where("expires_at < ?", time.Now()).
+		For("UPDATE SKIP LOCKED")
+
+	var rows []DataExportEntity
+	_, err = r.db.NewUpdate().
+		With("expired", expired).
+		Model(&rows).
+		TableExpr("expired").
+		Set("status = ?", DataExportStatusExpired).
+		Set("archive_key = NULL").
+		Where("data_export_entity.id = expired.id").
+		Returning("expired.archive_key").
+		Exec(ctx, &rows)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	archiveKeys = make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.ArchiveKey != "" {
+			archiveKeys = append(archiveKeys, row.ArchiveKey)
+		}
+	}
+	return archiveKeys, nil
+}
@@ -9,12 +9,16 @@ import (
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/extra/bunotel"
 )
 
 func NewBunDB(ctx context.Context, connectionString string) (db *bun.DB, closeFunc func() error) {
 	connector := pgdriver.NewConnector(pgdriver.WithDSN(connectionString))
 	postgresql := sql.OpenDB(connector)
 	db = bun.NewDB(postgresql, pgdialect.New(), bun.WithDiscardUnknownColumns())
+	// Every query becomes a span of the trace in its context. Spans carry the
+	// query with placeholders only, so bound values never leave the process.
+	db.AddQueryHook(bunotel.NewQueryHook())
 
 	if err := db.PingContext(ctx); err != nil {
 		fmt.Printf("FATAL: %s (err=%s)\n", err, "could not ping postgres")
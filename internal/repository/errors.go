@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// SQLSTATE codes the repositories translate.
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgForeignKeyViolation   = "23503"
+	pgUniqueViolation       = "23505"
+	pgInsufficientPrivilege = "42501"
+	pgQueryCanceled         = "57014"
+)
+
+// translateError wraps query errors in the typed errors of the exception
+// package: a missing row becomes ErrNotFound, unique and foreign key
+// violations ErrConflict, and a missing privilege ErrForbidden. A statement
+// cancelled by statement_timeout wraps context.DeadlineExceeded. The original
+// error stays in the chain, so errors.Is(err, sql.ErrNoRows) keeps working.
+func translateError(err error) error {
+	if err == nil || errors.Is(err, exception.ErrNotFound) || errors.Is(err, exception.ErrConflict) ||
+		errors.Is(err, exception.ErrForbidden) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", exception.ErrNotFound, err)
+	}
+
+	var pgErr pgdriver.Error
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Field('C') {
+	case pgUniqueViolation, pgForeignKeyViolation:
+		return fmt.Errorf("%w: %w", exception.ErrConflict, err)
+	case pgInsufficientPrivilege:
+		return fmt.Errorf("%w: %w", exception.ErrForbidden, err)
+	case pgQueryCanceled:
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+type OrganizationEntity struct {
+	bun.BaseModel `bun:"organizations"`
+
+	Id        uuid.UUID `bun:"id,pk,type:uuid"`
+	Name      string    `bun:"name,notnull"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,notnull,default:current_timestamp"`
+}
+
+type OrganizationMemberEntity struct {
+	bun.BaseModel `bun:"organization_members,alias:m"`
+
+	OrganizationId uuid.UUID   `bun:"organization_id,pk,type:uuid"`
+	UserId         uuid.UUID   `bun:"user_id,pk,type:uuid"`
+	Role           string      `bun:"role,notnull"`
+	CreatedAt      time.Time   `bun:"created_at,notnull,default:current_timestamp"`
+	UpdatedAt      time.Time   `bun:"updated_at,notnull,default:current_timestamp"`
+	User           *UserEntity `bun:"rel:belongs-to,join:user_id=id"`
+}
+
+// UserOrganization is an organization together with the role a user has in it.
+type UserOrganization struct {
+	OrganizationEntity `bun:",extend"`
+
+	Role string `bun:"role"`
+}
+
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, organization OrganizationEntity, ownerId uuid.UUID) (out OrganizationEntity, err error)
+	GetOrganizationById(ctx context.Context, id uuid.UUID) (out OrganizationEntity, err error)
+	GetOrganizationsByUserId(ctx context.Context, userId uuid.UUID) ([]UserOrganization, error)
+	UpdateOrganization(ctx context.Context, id uuid.UUID, name string) (out OrganizationEntity, err error)
+	DeleteOrganization(ctx context.Context, id uuid.UUID) (deleted bool, err error)
+	GetMember(ctx context.Context, organizationId, userId uuid.UUID) (out OrganizationMemberEntity, err error)
+	GetMembers(ctx context.Context, organizationId uuid.UUID) ([]OrganizationMemberEntity, error)
+	AddMember(ctx context.Context, member OrganizationMemberEntity) (added bool, err error)
+	UpdateMemberRole(ctx context.Context, organizationId, userId uuid.UUID, role string) (updated bool, err error)
+	RemoveMember(ctx context.Context, organizationId, userId uuid.UUID) (removed bool, err error)
+	GetSoleOwnedOrganizations(ctx context.Context, userId uuid.UUID) ([]OrganizationEntity, error)
+	PromoteSuccessor(ctx context.Context, organizationId, userId uuid.UUID) (promoted bool, err error)
+}
+
+type DefaultOrganizationRepository struct {
+	db *bun.DB
+}
+
+func NewOrganizationRepository(db *bun.DB) OrganizationRepository {
+	return &DefaultOrganizationRepository{db: db}
+}
+
+// CreateOrganization inserts the organization with ownerId as its first owner.
+func (r *DefaultOrganizationRepository) CreateOrganization(ctx context.Context, organization OrganizationEntity, ownerId uuid.UUID) (out OrganizationEntity, err error) {
+	err = r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+		if _, err := tx.NewInsert().Model(&organization).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewInsert().Model(&OrganizationMemberEntity{
+			OrganizationId: organization.Id,
+			UserId:         ownerId,
+			Role:           OrganizationRoleOwner,
+		}).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return out, translateError(err)
+	}
+	return organization, nil
+}
+
+func (r *DefaultOrganizationRepository) GetOrganizationById(ctx context.Context, id uuid.UUID) (out OrganizationEntity, err error) {
+	err = r.db.NewSelect().Model(&out).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+func (r *DefaultOrganizationRepository) GetOrganizationsByUserId(ctx context.Context, userId uuid.UUID) ([]UserOrganization, error) {
+	var organizations []UserOrganization
+	err := r.db.NewSelect().
+		Model(&organizations).
+		ModelTableExpr("organizations AS o").
+		ColumnExpr("o.*").
+		ColumnExpr("m.role").
+		Join("JOIN organization_members AS m ON m.organization_id = o.id").
+		Where("m.user_id = ?", userId).
+		Order("o.name ASC").
+		Scan(ctx)
+	if err != nil {
+		return []UserOrganization{}, translateError(err)
+	}
+	return organizations, nil
+}
+
+func (r *DefaultOrganizationRepository) UpdateOrganization(ctx context.Context, id uuid.UUID, name string) (out OrganizationEntity, err error) {
+	_, err = r.db.NewUpdate().
+		Model(&out).
+		Set("name = ?", name).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Returning("*").
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+// DeleteOrganization removes the organization; its members, categories and
+// todos go with it.
+func (r *DefaultOrganizationRepository) DeleteOrganization(ctx context.Context, id uuid.UUID) (deleted bool, err error) {
+	res, err := r.db.NewDelete().
+		Model((*OrganizationEntity)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+func (r *DefaultOrganizationRepository) GetMember(ctx context.Context, organizationId, userId uuid.UUID) (out OrganizationMemberEntity, err error) {
+	err = r.db.NewSelect().
+		Model(&out).
+		Where("m.organization_id = ?", organizationId).
+		Where("m.user_id = ?", userId).
+		Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+func (r *DefaultOrganizationRepository) GetMembers(ctx context.Context, organizationId uuid.UUID) ([]OrganizationMemberEntity, error) {
+	var members []OrganizationMemberEntity
+	err := r.db.NewSelect().
+		Model(&members).
+		Relation("User").
+		Where("m.organization_id = ?", organizationId).
+		Order("m.created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return []OrganizationMemberEntity{}, translateError(err)
+	}
+	return members, nil
+}
+
+// AddMember inserts the membership unless the user already belongs to the
+// organization.
+func (r *DefaultOrganizationRepository) AddMember(ctx context.Context, member OrganizationMemberEntity) (added bool, err error) {
+	res, err := r.db.NewInsert().
+		Model(&member).
+		On("CONFLICT (organization_id, user_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// UpdateMemberRole changes a member's role. The last owner of an organization
+// cannot be demoted, so nothing is updated in that case.
+func (r *DefaultOrganizationRepository) UpdateMemberRole(ctx context.Context, organizationId, userId uuid.UUID, role string) (updated bool, err error) {
+	q := r.db.NewUpdate().
+		Model((*OrganizationMemberEntity)(nil)).
+		Set("role = ?", role).
+		Set("updated_at = ?", time.Now()).
+		Where("m.organization_id = ?", organizationId).
+		Where("m.user_id = ?", userId)
+	if role != OrganizationRoleOwner {
+		q = q.Where("m.role <> ? OR EXISTS (?)", OrganizationRoleOwner, r.otherOwners(organizationId, userId))
+	}
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// RemoveMember deletes a membership unless it is the organization's last owner.
+func (r *DefaultOrganizationRepository) RemoveMember(ctx context.Context, organizationId, userId uuid.UUID) (removed bool, err error) {
+	res, err := r.db.NewDelete().
+		Model((*OrganizationMemberEntity)(nil)).
+		Where("m.organization_id = ?", organizationId).
+		Where("m.user_id = ?", userId).
+		Where("m.role <> ? OR EXISTS (?)", OrganizationRoleOwner, r.otherOwners(organizationId, userId)).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// GetSoleOwnedOrganizations lists the organizations userId is the only owner of.
+func (r *DefaultOrganizationRepository) GetSoleOwnedOrganizations(ctx context.Context, userId uuid.UUID) ([]OrganizationEntity, error) {
+	var organizations []OrganizationEntity
+	err := r.db.NewSelect().
+		Model(&organizations).
+		ModelTableExpr("organizations AS o").
+		ColumnExpr("o.*").
+		Join("JOIN organization_members AS m ON m.organization_id = o.id").
+		Where("m.user_id = ?", userId).
+		Where("m.role = ?", OrganizationRoleOwner).
+		Where("NOT EXISTS (?)", r.db.NewSelect().
+			TableExpr("organization_members AS other").
+			ColumnExpr("1").
+			Where("other.organization_id = o.id").
+			Where("other.user_id <> ?", userId).
+			Where("other.role = ?", OrganizationRoleOwner)).
+		Order("o.created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return []OrganizationEntity{}, translateError(err)
+	}
+	return organizations, nil
+}
+
+// PromoteSuccessor makes the longest-standing admin, or failing that member,
+// other than userId an owner of the organization. It reports false when
+// nobody else belongs to it.
+func (r *DefaultOrganizationRepository) PromoteSuccessor(ctx context.Context, organizationId, userId uuid.UUID) (promoted bool, err error) {
+	successor := r.db.NewSelect().
+		TableExpr("organization_members AS other").
+		Column("other.user_id").
+		Where("other.organization_id = ?", organizationId).
+		Where("other.user_id <> ?", userId).
+		OrderExpr("other.role = ? DESC, other.created_at ASC", OrganizationRoleAdmin).
+		Limit(1).
+		For("UPDATE")
+
+	res, err := r.db.NewUpdate().
+		Model((*OrganizationMemberEntity)(nil)).
+		Set("role = ?", OrganizationRoleOwner).
+		Set("updated_at = ?", time.Now()).
+		Where("m.organization_id = ?", organizationId).
+		Where("m.user_id = (?)", successor).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// otherOwners selects the owners of the organization besides userId.
+func (r *DefaultOrganizationRepository) otherOwners(organizationId, userId uuid.UUID) *bun.SelectQuery {
+	return r.db.NewSelect().
+		TableExpr("organization_members AS other").
+		ColumnExpr("1").
+		Where("other.organization_id = ?", organizationId).
+		Where("other.user_id <> ?", userId).
+		Where("other.role = ?", OrganizationRoleOwner)
+}
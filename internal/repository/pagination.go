@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Cursor is a position in a list ordered by (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	Id        uuid.UUID
+}
+
+// Keyed is implemented by entities that can be paged with a Cursor.
+type Keyed interface {
+	Key() Cursor
+}
+
+// Page selects one page of a list. With After or Before set the page is read
+// by keyset from that position and Offset is ignored; otherwise Offset is
+// used.
+type Page struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+	Before *Cursor
+}
+
+// PageInfo describes the page that was read. First and Last are the keys of
+// its first and last rows and are nil for an empty page.
+type PageInfo struct {
+	HasNext bool
+	HasPrev bool
+	First   *Cursor
+	Last    *Cursor
+}
+
+// selectPage reads a page of q, which must select into items, newest first by
+// (created_at, id). One extra row is read to find out whether the list goes
+// on past the page.
+func selectPage[T Keyed](ctx context.Context, q *bun.SelectQuery, items *[]T, page Page) (PageInfo, error) {
+	backward := page.Before != nil
+	switch {
+	case backward:
+		q = q.Where("(created_at, id) > (?, ?)", page.Before.CreatedAt, page.Before.Id).
+			OrderExpr("created_at ASC, id ASC")
+	case page.After != nil:
+		q = q.Where("(created_at, id) < (?, ?)", page.After.CreatedAt, page.After.Id).
+			OrderExpr("created_at DESC, id DESC")
+	default:
+		q = q.OrderExpr("created_at DESC, id DESC").Offset(page.Offset)
+	}
+
+	if err := q.Limit(page.Limit + 1).Scan(ctx); err != nil {
+		*items = []T{}
+		return PageInfo{}, err
+	}
+
+	more := len(*items) > page.Limit
+	if more {
+		*items = (*items)[:page.Limit]
+	}
+	if backward {
+		slices.Reverse(*items)
+	}
+
+	info := PageInfo{}
+	if backward {
+		info.HasPrev = more
+		info.HasNext = true
+	} else {
+		info.HasNext = more
+		info.HasPrev = page.After != nil || page.Offset > 0
+	}
+	if n := len(*items); n > 0 {
+		first, last := (*items)[0].Key(), (*items)[n-1].Key()
+		info.First, info.Last = &first, &last
+	}
+	return info, nil
+}
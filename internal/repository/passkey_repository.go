@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	PasskeyCeremonyRegistration = "registration"
+	PasskeyCeremonyLogin        = "login"
+)
+
+type PasskeyCredentialEntity struct {
+	bun.BaseModel `bun:"webauthn_credentials"`
+
+	Id              uuid.UUID  `bun:"id,pk,type:uuid"`
+	UserId          uuid.UUID  `bun:"user_id,notnull,type:uuid"`
+	CredentialId    []byte     `bun:"credential_id,notnull"`
+	PublicKey       []byte     `bun:"public_key,notnull"`
+	AttestationType string     `bun:"attestation_type,notnull"`
+	Transports      []string   `bun:"transports,array"`
+	AAGUID          []byte     `bun:"aaguid"`
+	SignCount       uint32     `bun:"sign_count,notnull"`
+	BackupEligible  bool       `bun:"backup_eligible,notnull"`
+	BackupState     bool       `bun:"backup_state,notnull"`
+	Name            string     `bun:"name,notnull"`
+	CreatedAt       time.Time  `bun:"created_at,notnull,default:current_timestamp"`
+	LastUsedAt      *time.Time `bun:"last_used_at"`
+}
+
+type PasskeySessionEntity struct {
+	bun.BaseModel `bun:"webauthn_sessions"`
+
+	Id        uuid.UUID       `bun:"id,pk,type:uuid"`
+	UserId    *uuid.UUID      `bun:"user_id,type:uuid"`
+	Ceremony  string          `bun:"ceremony,notnull"`
+	Data      json.RawMessage `bun:"data,type:jsonb,notnull"`
+	ExpiresAt time.Time       `bun:"expires_at,notnull"`
+	CreatedAt time.Time       `bun:"created_at,notnull,default:current_timestamp"`
+}
+
+type PasskeyRepository interface {
+	GetCredentialsByUserId(ctx context.Context, userId uuid.UUID) ([]PasskeyCredentialEntity, error)
+	GetCredentialByCredentialId(ctx context.Context, credentialId []byte) (out PasskeyCredentialEntity, err error)
+	InsertCredential(ctx context.Context, credential PasskeyCredentialEntity) (out PasskeyCredentialEntity, err error)
+	UpdateCredentialUsage(ctx context.Context, id uuid.UUID, previousSignCount, signCount uint32, backupState bool) (updated bool, err error)
+	DeleteCredential(ctx context.Context, userId, id uuid.UUID) (deleted bool, err error)
+	InsertSession(ctx context.Context, session PasskeySessionEntity) error
+	ConsumeSession(ctx context.Context, id uuid.UUID, ceremony string) (out PasskeySessionEntity, err error)
+	DeleteExpiredSessions(ctx context.Context) error
+}
+
+type DefaultPasskeyRepository struct {
+	db *bun.DB
+}
+
+func NewPasskeyRepository(db *bun.DB) PasskeyRepository {
+	return &DefaultPasskeyRepository{db: db}
+}
+
+func (r *DefaultPasskeyRepository) GetCredentialsByUserId(ctx context.Context, userId uuid.UUID) ([]PasskeyCredentialEntity, error) {
+	var credentials []PasskeyCredentialEntity
+	err := r.db.NewSelect().
+		Model(&credentials).
+		Where("user_id = ?", userId).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return []PasskeyCredentialEntity{}, translateError(err)
+	}
+	return credentials, nil
+}
+
+func (r *DefaultPasskeyRepository) GetCredentialByCredentialId(ctx context.Context, credentialId []byte) (out PasskeyCredentialEntity, err error) {
+	err = r.db.NewSelect().Model(&out).Where("credential_id = ?", credentialId).Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+func (r *DefaultPasskeyRepository) InsertCredential(ctx context.Context, credential PasskeyCredentialEntity) (out PasskeyCredentialEntity, err error) {
+	_, err = r.db.NewInsert().Model(&credential).Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return credential, nil
+}
+
+// UpdateCredentialUsage stores the new signature counter only if the stored
+// counter still matches previousSignCount, so two concurrent assertions with
+// the same counter cannot both succeed.
+func (r *DefaultPasskeyRepository) UpdateCredentialUsage(ctx context.Context, id uuid.UUID, previousSignCount, signCount uint32, backupState bool) (updated bool, err error) {
+	res, err := r.db.NewUpdate().
+		Model((*PasskeyCredentialEntity)(nil)).
+		Set("sign_count = ?", signCount).
+		Set("backup_state = ?", backupState).
+		Set("last_used_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("sign_count = ?", previousSignCount).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+func (r *DefaultPasskeyRepository) DeleteCredential(ctx context.Context, userId, id uuid.UUID) (deleted bool, err error) {
+	res, err := r.db.NewDelete().
+		Model((*PasskeyCredentialEntity)(nil)).
+		Where("id = ?", id).
+		Where("user_id = ?", userId).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+func (r *DefaultPasskeyRepository) InsertSession(ctx context.Context, session PasskeySessionEntity) error {
+	_, err := r.db.NewInsert().Model(&session).Exec(ctx)
+	return translateError(err)
+}
+
+// ConsumeSession deletes and returns a ceremony session, making every
+// challenge single-use.
+func (r *DefaultPasskeyRepository) ConsumeSession(ctx context.Context, id uuid.UUID, ceremony string) (out PasskeySessionEntity, err error) {
+	_, err = r.db.NewDelete().
+		Model(&out).
+		Where("id = ?", id).
+		Where("ceremony = ?", ceremony).
+		Returning("*").
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+func (r *DefaultPasskeyRepository) DeleteExpiredSessions(ctx context.Context) error {
+	_, err := r.db.NewDelete().
+		Model((*PasskeySessionEntity)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Exec(ctx)
+	return translateError(err)
+}
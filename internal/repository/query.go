@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// ReadOption changes how repository reads treat soft-deleted rows.
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	includeDeleted bool
+}
+
+// IncludeDeleted makes a read also return soft-deleted rows.
+func IncludeDeleted() ReadOption {
+	return func(o *readOptions) {
+		o.includeDeleted = true
+	}
+}
+
+func applyReadOptions(q *bun.SelectQuery, opts []ReadOption) *bun.SelectQuery {
+	options := readOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.includeDeleted {
+		return q.WhereAllWithDeleted()
+	}
+	return q
+}
+
+func rowsAffected(res sql.Result) int64 {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// OrderBy sorts a list by one field. Field is the API name; each repository
+// maps it to a column through a whitelist.
+type OrderBy struct {
+	Field string
+	Desc  bool
+}
+
+// applyOrder orders q by the whitelisted fields, falling back to fallback
+// when none are given. The primary key is always the last key, in the same
+// direction as the one before it, so that rows with equal values come back
+// in a stable order.
+func applyOrder(q *bun.SelectQuery, orderBy []OrderBy, columns map[string]string, fallback OrderBy) *bun.SelectQuery {
+	if len(orderBy) == 0 {
+		orderBy = []OrderBy{fallback}
+	}
+	desc := false
+	for _, o := range orderBy {
+		column, ok := columns[o.Field]
+		if !ok {
+			continue
+		}
+		q = q.OrderExpr("? "+direction(o.Desc), bun.Ident(column))
+		desc = o.Desc
+	}
+	return q.OrderExpr("? "+direction(desc), bun.Ident("id"))
+}
+
+// containsPattern builds an ILIKE pattern matching s anywhere in a value,
+// with LIKE wildcards in s matched literally.
+func containsPattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + escaped + "%"
+}
+
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// nullString stores an empty string as NULL, like the nullzero tag does on insert.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	GrantRoleViewer = "viewer"
+	GrantRoleEditor = "editor"
+
+	// AccessOwner is reported for rows the acting workspace owns; grants
+	// report their role instead.
+	AccessOwner = "owner"
+)
+
+const (
+	ResourceTypeCategory = "category"
+	ResourceTypeTodo     = "todo"
+)
+
+// Conditions matching the grants of the category or todo row of the outer
+// query. A todo is also shared through a grant on its category.
+const (
+	categoryGrants     = "g.category_id = ?TableAlias.id"
+	todoGrants         = "(g.todo_id = ?TableAlias.id OR g.category_id = ?TableAlias.category_id)"
+	todoCategoryGrants = "g.category_id = ?TableAlias.category_id"
+)
+
+// Resource identifies a category or todo that can be shared.
+type Resource struct {
+	Type string
+	Id   uuid.UUID
+}
+
+func (r Resource) column() string {
+	if r.Type == ResourceTypeTodo {
+		return "g.todo_id"
+	}
+	return "g.category_id"
+}
+
+type ResourceGrantEntity struct {
+	bun.BaseModel `bun:"resource_grants,alias:g"`
+
+	Id         uuid.UUID   `bun:"id,pk,type:uuid"`
+	CategoryId *uuid.UUID  `bun:"category_id,type:uuid"`
+	TodoId     *uuid.UUID  `bun:"todo_id,type:uuid"`
+	UserId     uuid.UUID   `bun:"user_id,notnull,type:uuid"`
+	Role       string      `bun:"role,notnull"`
+	GrantedBy  *uuid.UUID  `bun:"granted_by,type:uuid"`
+	CreatedAt  time.Time   `bun:"created_at,notnull,default:current_timestamp"`
+	UpdatedAt  time.Time   `bun:"updated_at,notnull,default:current_timestamp"`
+	User       *UserEntity `bun:"rel:belongs-to,join:user_id=id"`
+}
+
+// ResourceGrantRepository manages who a category or todo is shared with.
+// Callers check that the acting user owns the resource.
+type ResourceGrantRepository interface {
+	GetGrants(ctx context.Context, resource Resource) ([]ResourceGrantEntity, error)
+	SaveGrant(ctx context.Context, resource Resource, grant ResourceGrantEntity) (out ResourceGrantEntity, err error)
+	DeleteGrant(ctx context.Context, resource Resource, userId uuid.UUID) (deleted bool, err error)
+}
+
+type DefaultResourceGrantRepository struct {
+	db *bun.DB
+}
+
+func NewResourceGrantRepository(db *bun.DB) ResourceGrantRepository {
+	return &DefaultResourceGrantRepository{db: db}
+}
+
+func (r *DefaultResourceGrantRepository) GetGrants(ctx context.Context, resource Resource) ([]ResourceGrantEntity, error) {
+	var grants []ResourceGrantEntity
+	err := r.db.NewSelect().
+		Model(&grants).
+		Relation("User").
+		Where(resource.column()+" = ?", resource.Id).
+		Order("g.created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return []ResourceGrantEntity{}, translateError(err)
+	}
+	return grants, nil
+}
+
+// SaveGrant shares the resource with grant.UserId, or changes the role when
+// it is already shared with that user.
+func (r *DefaultResourceGrantRepository) SaveGrant(ctx context.Context, resource Resource, grant ResourceGrantEntity) (out ResourceGrantEntity, err error) {
+	grant.CategoryId, grant.TodoId = nil, nil
+	if resource.Type == ResourceTypeTodo {
+		grant.TodoId = &resource.Id
+	} else {
+		grant.CategoryId = &resource.Id
+	}
+
+	target := "(category_id, user_id) WHERE category_id IS NOT NULL"
+	if resource.Type == ResourceTypeTodo {
+		target = "(todo_id, user_id) WHERE todo_id IS NOT NULL"
+	}
+	_, err = r.db.NewInsert().
+		Model(&grant).
+		On("CONFLICT " + target + " DO UPDATE").
+		Set("role = EXCLUDED.role").
+		Set("granted_by = EXCLUDED.granted_by").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return grant, nil
+}
+
+func (r *DefaultResourceGrantRepository) DeleteGrant(ctx context.Context, resource Resource, userId uuid.UUID) (deleted bool, err error) {
+	res, err := r.db.NewDelete().
+		Model((*ResourceGrantEntity)(nil)).
+		Where(resource.column()+" = ?", resource.Id).
+		Where("g.user_id = ?", userId).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// whereAccessible returns the condition for rows the workspace owns or that
+// grants matches for the workspace's user with one of roles.
+func whereAccessible(workspace Workspace, grants string, roles ...string) (string, []any) {
+	owned, owner := workspace.where()
+	return "(" + owned + ") OR EXISTS (SELECT 1 FROM resource_grants AS g WHERE " + grants + " AND g.user_id = ? AND g.role IN (?))",
+		[]any{owner, workspace.UserId, bun.In(roles)}
+}
+
+// whereShared returns the condition for rows that grants matches for userId.
+func whereShared(userId uuid.UUID, grants string) (string, []any) {
+	return "EXISTS (SELECT 1 FROM resource_grants AS g WHERE " + grants + " AND g.user_id = ?)", []any{userId}
+}
+
+// grantedRole is the strongest role grants give userId on the row, or NULL.
+func grantedRole(userId uuid.UUID, grants string) (string, []any) {
+	return "(SELECT CASE WHEN bool_or(g.role = 'editor') THEN 'editor' ELSE 'viewer' END " +
+			"FROM resource_grants AS g WHERE " + grants + " AND g.user_id = ? HAVING count(*) > 0)",
+		[]any{userId}
+}
+
+// accessColumn is the expression filling the scan-only access field:
+// AccessOwner for rows of the workspace, otherwise the granted role.
+func accessColumn(workspace Workspace, grants string) (string, []any) {
+	owned, owner := workspace.where()
+	role, args := grantedRole(workspace.UserId, grants)
+	return "CASE WHEN " + owned + " THEN 'owner' ELSE " + role + " END AS access", append([]any{owner}, args...)
+}
+
+// selectAccess adds the access column next to the model's own columns.
+func selectAccess(q *bun.SelectQuery, workspace Workspace, grants string) *bun.SelectQuery {
+	expr, args := accessColumn(workspace, grants)
+	return q.ColumnExpr("?TableAlias.*").ColumnExpr(expr, args...)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// runQuery runs fn against db and translates its error. When ctx has a
+// deadline, fn runs in a transaction whose statement_timeout is the time
+// left, so Postgres stops the work itself even if the client's cancel request
+// is lost. Without a deadline fn runs on db directly.
+func runQuery(ctx context.Context, db *bun.DB, fn func(ctx context.Context, db bun.IDB) error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return translateError(fn(ctx, db))
+	}
+	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+		return fn(ctx, tx)
+	})
+	return translateError(err)
+}
+
+// setStatementTimeout limits the statements of the transaction tx to the time
+// left before the deadline of ctx. It does nothing when ctx has no deadline.
+func setStatementTimeout(ctx context.Context, tx bun.Tx) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	left := time.Until(deadline).Milliseconds()
+	if left < 1 {
+		return context.DeadlineExceeded
+	}
+	_, err := tx.ExecContext(ctx, "SELECT set_config('statement_timeout', ?, true)", strconv.FormatInt(left, 10))
+	return err
+}
+
+// execQuery is an update or delete query ready to run.
+type execQuery interface {
+	Exec(ctx context.Context, dest ...any) (sql.Result, error)
+}
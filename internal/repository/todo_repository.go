@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	TodoStatusPending    = "pending"
+	TodoStatusInProgress = "in_progress"
+	TodoStatusCompleted  = "completed"
+	TodoStatusCancelled  = "cancelled"
+)
+
+type TodoEntity struct {
+	bun.BaseModel `bun:"todos"`
+
+	Id             uuid.UUID  `bun:"id,pk,type:uuid"`
+	UserId         *uuid.UUID `bun:"user_id,type:uuid"`
+	OrganizationId *uuid.UUID `bun:"organization_id,type:uuid"`
+	CategoryId     *uuid.UUID `bun:"category_id,type:uuid"`
+	CreatedBy      *uuid.UUID `bun:"created_by,type:uuid"`
+	Title          string     `bun:"title,notnull"`
+	Description    string     `bun:"description,nullzero"`
+	Priority       string     `bun:"priority,notnull,default:'medium'"`
+	Status         string     `bun:"status,notnull,default:'pending'"`
+	DueDate        *time.Time `bun:"due_date"`
+	CompletedAt    *time.Time `bun:"completed_at"`
+	CreatedAt      time.Time  `bun:"created_at,notnull,default:current_timestamp"`
+	UpdatedAt      time.Time  `bun:"updated_at,notnull,default:current_timestamp"`
+	DeletedAt      time.Time  `bun:"deleted_at,soft_delete,nullzero"`
+
+	// Access is what the acting workspace may do with the todo: owner, editor
+	// or viewer. Only reads that check access fill it.
+	Access string `bun:"access,scanonly"`
+}
+
+func (t TodoEntity) Key() Cursor {
+	return Cursor{CreatedAt: t.CreatedAt, Id: t.Id}
+}
+
+// Owner returns the workspace the todo belongs to.
+func (t TodoEntity) Owner() Workspace {
+	return ownerWorkspace(t.UserId, t.OrganizationId)
+}
+
+// TodoFilter narrows a todo listing. Zero values do not filter.
+type TodoFilter struct {
+	Status     string
+	CategoryId *uuid.UUID
+}
+
+func (f TodoFilter) apply(q *bun.SelectQuery) *bun.SelectQuery {
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.CategoryId != nil {
+		q = q.Where("category_id = ?", *f.CategoryId)
+	}
+	return q
+}
+
+// TodoRepository reads and writes todos of one workspace at a time. Listings
+// only return the workspace's own rows; single todos can also be reached
+// through a grant on the todo or its category. Viewers may read and editors
+// may update; deleting needs the owning workspace or an editor grant on the
+// category.
+type TodoRepository interface {
+	GetTodos(ctx context.Context, workspace Workspace, filter TodoFilter, page Page) ([]TodoEntity, PageInfo, error)
+	GetTodosCount(ctx context.Context, workspace Workspace, filter TodoFilter) (int64, error)
+	GetAllTodos(ctx context.Context, workspace Workspace, opts ...ReadOption) ([]TodoEntity, error)
+	GetAuthoredTodos(ctx context.Context, userId uuid.UUID, opts ...ReadOption) ([]TodoEntity, error)
+	GetTodoById(ctx context.Context, workspace Workspace, id uuid.UUID) (out TodoEntity, err error)
+	InsertTodo(ctx context.Context, workspace Workspace, todo TodoEntity) (out TodoEntity, err error)
+	UpdateTodo(ctx context.Context, workspace Workspace, todo TodoEntity) (out TodoEntity, err error)
+	DeleteTodo(ctx context.Context, workspace Workspace, id uuid.UUID) (deleted bool, err error)
+	GetSharedTodos(ctx context.Context, userId uuid.UUID, filter TodoFilter, page Page) ([]TodoEntity, PageInfo, error)
+	GetSharedTodosCount(ctx context.Context, userId uuid.UUID, filter TodoFilter) (int64, error)
+}
+
+type DefaultTodoRepository struct {
+	db *bun.DB
+}
+
+func NewTodoRepository(db *bun.DB) TodoRepository {
+	return &DefaultTodoRepository{db: db}
+}
+
+func (r *DefaultTodoRepository) GetTodos(ctx context.Context, workspace Workspace, filter TodoFilter, page Page) ([]TodoEntity, PageInfo, error) {
+	var todos []TodoEntity
+	q := filter.apply(r.db.NewSelect().Model(&todos).Where(workspace.where()))
+	q = selectAccess(q, workspace, todoGrants)
+	info, err := selectPage(ctx, q, &todos, page)
+	return todos, info, translateError(err)
+}
+
+func (r *DefaultTodoRepository) GetTodosCount(ctx context.Context, workspace Workspace, filter TodoFilter) (int64, error) {
+	count, err := filter.apply(r.db.NewSelect().Model((*TodoEntity)(nil)).Where(workspace.where())).Count(ctx)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int64(count), nil
+}
+
+// GetAllTodos reads every todo of the workspace, oldest first.
+func (r *DefaultTodoRepository) GetAllTodos(ctx context.Context, workspace Workspace, opts ...ReadOption) ([]TodoEntity, error) {
+	var todos []TodoEntity
+	q := r.db.NewSelect().
+		Model(&todos).
+		Where(workspace.where()).
+		Order("created_at ASC")
+	err := applyReadOptions(q, opts).Scan(ctx)
+	if err != nil {
+		return []TodoEntity{}, translateError(err)
+	}
+	return todos, nil
+}
+
+// GetAuthoredTodos reads the todos the user created outside their personal
+// workspace, in organizations or in categories shared with them, oldest
+// first.
+func (r *DefaultTodoRepository) GetAuthoredTodos(ctx context.Context, userId uuid.UUID, opts ...ReadOption) ([]TodoEntity, error) {
+	var todos []TodoEntity
+	q := r.db.NewSelect().
+		Model(&todos).
+		Where("created_by = ?", userId).
+		Where("user_id IS DISTINCT FROM ?", userId).
+		Order("created_at ASC")
+	err := applyReadOptions(q, opts).Scan(ctx)
+	if err != nil {
+		return []TodoEntity{}, translateError(err)
+	}
+	return todos, nil
+}
+
+func (r *DefaultTodoRepository) GetTodoById(ctx context.Context, workspace Workspace, id uuid.UUID) (out TodoEntity, err error) {
+	accessible, args := whereAccessible(workspace, todoGrants, GrantRoleViewer, GrantRoleEditor)
+	q := r.db.NewSelect().
+		Model(&out).
+		Where("id = ?", id).
+		Where(accessible, args...)
+	err = selectAccess(q, workspace, todoGrants).Scan(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return out, nil
+}
+
+// InsertTodo adds the todo to the workspace. Callers adding a todo to a
+// category shared with them pass the category's Owner.
+func (r *DefaultTodoRepository) InsertTodo(ctx context.Context, workspace Workspace, todo TodoEntity) (out TodoEntity, err error) {
+	todo.UserId, todo.OrganizationId = workspace.owner()
+	_, err = r.db.NewInsert().Model(&todo).Returning("*").Exec(ctx)
+	if err != nil {
+		return out, translateError(err)
+	}
+	return todo, nil
+}
+
+// UpdateTodo saves the todo's fields. It returns sql.ErrNoRows when the
+// workspace neither owns the todo nor may edit it.
+func (r *DefaultTodoRepository) UpdateTodo(ctx context.Context, workspace Workspace, todo TodoEntity) (out TodoEntity, err error) {
+	accessible, args := whereAccessible(workspace, todoGrants, GrantRoleEditor)
+	access, accessArgs := accessColumn(workspace, todoGrants)
+	_, err = r.db.NewUpdate().
+		Model(&out).
+		Set("category_id = ?", todo.CategoryId).
+		Set("title = ?", todo.Title).
+		Set("description = ?", nullString(todo.Description)).
+		Set("priority = ?", todo.Priority).
+		Set("status = ?", todo.Status).
+		Set("due_date = ?", todo.DueDate).
+		Set("completed_at = ?", todo.CompletedAt).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", todo.Id).
+		Where(accessible, args...).
+		Returning("*, "+access, accessArgs...).
+		Exec(ctx, &out)
+	if err != nil {
+		return out, translateError(err)
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+func (r *DefaultTodoRepository) DeleteTodo(ctx context.Context, workspace Workspace, id uuid.UUID) (deleted bool, err error) {
+	accessible, args := whereAccessible(workspace, todoCategoryGrants, GrantRoleEditor)
+	res, err := r.db.NewDelete().
+		Model((*TodoEntity)(nil)).
+		Where("id = ?", id).
+		Where(accessible, args...).
+		Exec(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return rowsAffected(res) > 0, nil
+}
+
+// GetSharedTodos reads the todos shared with userId, directly or through
+// their category, newest first.
+func (r *DefaultTodoRepository) GetSharedTodos(ctx context.Context, userId uuid.UUID, filter TodoFilter, page Page) ([]TodoEntity, PageInfo, error) {
+	var todos []TodoEntity
+	shared, args := whereShared(userId, todoGrants)
+	role, roleArgs := grantedRole(userId, todoGrants)
+	q := r.db.NewSelect().
+		Model(&todos).
+		ColumnExpr("?TableAlias.*").
+		ColumnExpr(role+" AS access", roleArgs...).
+		Where(shared, args...)
+	info, err := selectPage(ctx, filter.apply(q), &todos, page)
+	return todos, info, translateError(err)
+}
+
+func (r *DefaultTodoRepository) GetSharedTodosCount(ctx context.Context, userId uuid.UUID, filter TodoFilter) (int64, error) {
+	shared, args := whereShared(userId, todoGrants)
+	q := r.db.NewSelect().
+		Model((*TodoEntity)(nil)).
+		Where(shared, args...)
+	count, err := filter.apply(q).Count(ctx)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int64(count), nil
+}
@@ -2,84 +2,420 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DeletedFilter selects how a user listing treats soft-deleted users.
+type DeletedFilter int
+
+const (
+	WithoutDeleted DeletedFilter = iota
+	WithDeleted
+	OnlyDeleted
+)
+
+// userSortColumns whitelists the fields users can be ordered by.
+var userSortColumns = map[string]string{
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 type UserEntity struct {
 	bun.BaseModel `bun:"users"`
 
-	Id        uuid.UUID `bun:"id,pk,type:uuid"`
-	Name      string    `bun:"name,notnull"`
-	Email     string    `bun:"email,notnull"`
-	Password  string    `bun:"password,notnull"`
-	CreatedAt time.Time `bun:"created_at,notnull,default:current_timestamp"`
-	UpdatedAt time.Time `bun:"updated_at,notnull,default:current_timestamp"`
+	Id              uuid.UUID  `bun:"id,pk,type:uuid"`
+	Name            string     `bun:"name,notnull"`
+	Email           string     `bun:"email,notnull"`
+	Password        string     `bun:"password,nullzero"`
+	Role            string     `bun:"role,notnull,default:'user'"`
+	IsActive        bool       `bun:"is_active,notnull"`
+	EmailVerifiedAt *time.Time `bun:"email_verified_at"`
+	AvatarKey       string     `bun:"avatar_key,nullzero"`
+	InvitedAt       *time.Time `bun:"invited_at"`
+	InvitedBy       *uuid.UUID `bun:"invited_by,type:uuid"`
+	CreatedAt       time.Time  `bun:"created_at,notnull,default:current_timestamp"`
+	UpdatedAt       time.Time  `bun:"updated_at,notnull,default:current_timestamp"`
+	DeletedAt       time.Time  `bun:"deleted_at,soft_delete,nullzero"`
+}
+
+func (u UserEntity) Key() Cursor {
+	return Cursor{CreatedAt: u.CreatedAt, Id: u.Id}
+}
+
+// UserFilter narrows a user listing. Zero values do not filter.
+type UserFilter struct {
+	// Query matches a substring of the name or email, case-insensitively.
+	Query         string
+	Active        *bool
+	Verified      *bool
+	Deleted       DeletedFilter
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	OrderBy       []OrderBy
+}
+
+func (f UserFilter) apply(q *bun.SelectQuery) *bun.SelectQuery {
+	switch f.Deleted {
+	case WithDeleted:
+		q = q.WhereAllWithDeleted()
+	case OnlyDeleted:
+		q = q.WhereDeleted()
+	}
+	if f.Query != "" {
+		pattern := containsPattern(f.Query)
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("name ILIKE ?", pattern).WhereOr("email ILIKE ?", pattern)
+		})
+	}
+	if f.Active != nil {
+		q = q.Where("is_active = ?", *f.Active)
+	}
+	if f.Verified != nil {
+		if *f.Verified {
+			q = q.Where("email_verified_at IS NOT NULL")
+		} else {
+			q = q.Where("email_verified_at IS NULL")
+		}
+	}
+	if f.CreatedAfter != nil {
+		q = q.Where("created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		q = q.Where("created_at < ?", *f.CreatedBefore)
+	}
+	return q
 }
 
 type UserRepository interface {
-	GetUsers(offset, limit int) ([]UserEntity, error)
-	GetUsersCount() (int64, error)
-	InsertUser(user UserEntity) (out UserEntity, err error)
-	GetUserById(id uuid.UUID) (out UserEntity, err error)
-	GetUserByEmail(email string) (out UserEntity, err error)
+	GetUsers(ctx context.Context, filter UserFilter, page Page) ([]UserEntity, PageInfo, error)
+	GetUsersCount(ctx context.Context, filter UserFilter) (int64, error)
+	InsertUser(ctx context.Context, user UserEntity) (out UserEntity, err error)
+	GetUserById(ctx context.Context, id uuid.UUID, opts ...ReadOption) (out UserEntity, err error)
+	GetUserByEmail(ctx context.Context, email string, opts ...ReadOption) (out UserEntity, err error)
+	UpdateUser(ctx context.Context, user UserEntity, expectedUpdatedAt time.Time) (out UserEntity, err error)
+	SoftDeleteUser(ctx context.Context, id uuid.UUID) (deleted bool, err error)
+	RestoreUser(ctx context.Context, id uuid.UUID) (restored bool, err error)
+	SetUserActive(ctx context.Context, id uuid.UUID, active bool) (updated bool, err error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	ChangeEmail(ctx context.Context, id uuid.UUID, oldEmail, newEmail string) (changed bool, err error)
+	UpdateAvatarKey(ctx context.Context, id uuid.UUID, key string) (updated bool, err error)
+	RenewInvitation(ctx context.Context, id uuid.UUID) (renewed bool, err error)
+	AcceptInvitation(ctx context.Context, id uuid.UUID, name, password string) (accepted bool, err error)
+	DeleteInvitedUser(ctx context.Context, id uuid.UUID) (deleted bool, err error)
+	GetExistingEmails(ctx context.Context, emails []string) ([]string, error)
+	InsertUsers(ctx context.Context, users []UserEntity, batchSize int) error
+	IsBeingPurged(ctx context.Context, id uuid.UUID) (bool, error)
 }
 
+// DefaultUserRepository runs every query in the caller's context; when it has
+// a deadline, Postgres enforces it as the statement_timeout.
 type DefaultUserRepository struct {
-	db  *bun.DB
-	ctx context.Context
+	db *bun.DB
 }
 
-func NewUserRepository(db *bun.DB, ctx context.Context) UserRepository {
-	return &DefaultUserRepository{db: db, ctx: ctx}
+func NewUserRepository(db *bun.DB) UserRepository {
+	return &DefaultUserRepository{db: db}
 }
 
-func (r *DefaultUserRepository) GetUsers(offset, limit int) ([]UserEntity, error) {
+// GetUsers reads a page of users matching filter. Keyset pages are only
+// available in the default newest-first order; with OrderBy set the page is
+// read by offset and PageInfo carries no cursors.
+func (r *DefaultUserRepository) GetUsers(ctx context.Context, filter UserFilter, page Page) ([]UserEntity, PageInfo, error) {
 	var users []UserEntity
-	err := r.db.NewSelect().
-		Model(&users).
-		Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Scan(r.ctx)
+	var info PageInfo
+	err := runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		q := filter.apply(db.NewSelect().Model(&users))
+		if len(filter.OrderBy) == 0 {
+			var err error
+			info, err = selectPage(ctx, q, &users, page)
+			return err
+		}
+		return applyOrder(q, filter.OrderBy, userSortColumns, OrderBy{Field: "created_at", Desc: true}).
+			Offset(page.Offset).
+			Limit(page.Limit).
+			Scan(ctx)
+	})
 	if err != nil {
-		return []UserEntity{}, err
+		return []UserEntity{}, PageInfo{}, err
 	}
-	return users, nil
+	return users, info, nil
 }
 
-func (r *DefaultUserRepository) GetUsersCount() (int64, error) {
-	count, err := r.db.NewSelect().
-		Model((*UserEntity)(nil)).
-		Count(r.ctx)
+// GetUsersCount counts the users matching filter; the ordering is ignored.
+func (r *DefaultUserRepository) GetUsersCount(ctx context.Context, filter UserFilter) (count int64, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		n, err := filter.apply(db.NewSelect().Model((*UserEntity)(nil))).Count(ctx)
+		count = int64(n)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
-	return int64(count), nil
+	return count, nil
 }
 
-func (r *DefaultUserRepository) InsertUser(user UserEntity) (out UserEntity, err error) {
-	_, err = r.db.NewInsert().Model(&user).Exec(r.ctx)
+func (r *DefaultUserRepository) InsertUser(ctx context.Context, user UserEntity) (out UserEntity, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		_, err := db.NewInsert().Model(&user).Exec(ctx)
+		return err
+	})
 	if err != nil {
 		return out, err
 	}
 	return user, nil
 }
 
-func (r *DefaultUserRepository) GetUserById(id uuid.UUID) (out UserEntity, err error) {
-	err = r.db.NewSelect().Model(&out).Where("id = ?", id).Scan(r.ctx)
+func (r *DefaultUserRepository) GetUserById(ctx context.Context, id uuid.UUID, opts ...ReadOption) (out UserEntity, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		q := db.NewSelect().Model(&out).Where("id = ?", id)
+		return applyReadOptions(q, opts).Scan(ctx)
+	})
 	if err != nil {
 		return out, err
 	}
 	return out, nil
 }
 
-func (r *DefaultUserRepository) GetUserByEmail(email string) (out UserEntity, err error) {
-	err = r.db.NewSelect().Model(&out).Where("email = ?", email).Scan(r.ctx)
+func (r *DefaultUserRepository) GetUserByEmail(ctx context.Context, email string, opts ...ReadOption) (out UserEntity, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		q := db.NewSelect().Model(&out).Where("email = ?", email)
+		return applyReadOptions(q, opts).Scan(ctx)
+	})
 	if err != nil {
 		return out, err
 	}
 	return out, nil
 }
+
+// UpdateUser saves the user's name only if the row still has
+// expectedUpdatedAt. It returns sql.ErrNoRows when the row is missing or has
+// been modified since it was read.
+func (r *DefaultUserRepository) UpdateUser(ctx context.Context, user UserEntity, expectedUpdatedAt time.Time) (out UserEntity, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		_, err := db.NewUpdate().
+			Model(&out).
+			Set("name = ?", user.Name).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", user.Id).
+			Where("updated_at = ?", expectedUpdatedAt).
+			Returning("*").
+			Exec(ctx, &out)
+		return err
+	})
+	if err != nil {
+		return out, err
+	}
+	if out.Id == uuid.Nil {
+		return out, translateError(sql.ErrNoRows)
+	}
+	return out, nil
+}
+
+// SoftDeleteUser sets deleted_at; the row is kept but hidden from reads.
+func (r *DefaultUserRepository) SoftDeleteUser(ctx context.Context, id uuid.UUID) (deleted bool, err error) {
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewDelete().
+			Model((*UserEntity)(nil)).
+			Where("id = ?", id)
+	})
+}
+
+func (r *DefaultUserRepository) RestoreUser(ctx context.Context, id uuid.UUID) (restored bool, err error) {
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			WhereAllWithDeleted().
+			Set("deleted_at = NULL").
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", id).
+			Where("deleted_at IS NOT NULL")
+	})
+}
+
+func (r *DefaultUserRepository) SetUserActive(ctx context.Context, id uuid.UUID, active bool) (updated bool, err error) {
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			Set("is_active = ?", active).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", id)
+	})
+}
+
+// MarkEmailVerified records that the user proved ownership of their current
+// address. An earlier verification time is kept.
+func (r *DefaultUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	_, err := r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			Set("email_verified_at = ?", time.Now()).
+			Where("id = ?", id).
+			Where("email_verified_at IS NULL")
+	})
+	return err
+}
+
+// ChangeEmail moves the user from oldEmail to newEmail and marks the new
+// address verified. Nothing changes if the user no longer has oldEmail or if
+// another account, deleted or not, already holds newEmail.
+func (r *DefaultUserRepository) ChangeEmail(ctx context.Context, id uuid.UUID, oldEmail, newEmail string) (changed bool, err error) {
+	now := time.Now()
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			Set("email = ?", newEmail).
+			Set("email_verified_at = ?", now).
+			Set("updated_at = ?", now).
+			Where("id = ?", id).
+			Where("email = ?", oldEmail).
+			Where("NOT EXISTS (SELECT 1 FROM users AS other WHERE other.email = ? AND other.id <> ?)", newEmail, id)
+	})
+}
+
+// UpdateAvatarKey points the user at a new set of avatar thumbnails; an empty
+// key removes the avatar.
+func (r *DefaultUserRepository) UpdateAvatarKey(ctx context.Context, id uuid.UUID, key string) (updated bool, err error) {
+	var avatarKey *string
+	if key != "" {
+		avatarKey = &key
+	}
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			Set("avatar_key = ?", avatarKey).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", id)
+	})
+}
+
+// RenewInvitation moves invited_at forward when an invitation is sent again.
+func (r *DefaultUserRepository) RenewInvitation(ctx context.Context, id uuid.UUID) (renewed bool, err error) {
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			Set("invited_at = ?", time.Now()).
+			Where("id = ?", id).
+			Where("invited_at IS NOT NULL")
+	})
+}
+
+// AcceptInvitation sets the password of an invited user and activates the
+// account. Following the invite link also verifies the email address. An
+// empty name keeps the one the admin entered.
+func (r *DefaultUserRepository) AcceptInvitation(ctx context.Context, id uuid.UUID, name, password string) (accepted bool, err error) {
+	now := time.Now()
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		q := db.NewUpdate().
+			Model((*UserEntity)(nil)).
+			Set("password = ?", password).
+			Set("is_active = TRUE").
+			Set("invited_at = NULL").
+			Set("email_verified_at = COALESCE(email_verified_at, ?)", now).
+			Set("updated_at = ?", now).
+			Where("id = ?", id).
+			Where("invited_at IS NOT NULL")
+		if name != "" {
+			q = q.Set("name = ?", name)
+		}
+		return q
+	})
+}
+
+// DeleteInvitedUser removes a user whose invitation was never accepted. The
+// account was never used, so the row is deleted for good and the address is
+// freed.
+func (r *DefaultUserRepository) DeleteInvitedUser(ctx context.Context, id uuid.UUID) (deleted bool, err error) {
+	return r.exec(ctx, func(db bun.IDB) execQuery {
+		return db.NewDelete().
+			Model((*UserEntity)(nil)).
+			Where("id = ?", id).
+			Where("invited_at IS NOT NULL").
+			ForceDelete()
+	})
+}
+
+// GetExistingEmails returns which of emails already belong to a user,
+// ignoring case. Soft-deleted users count because they can be restored.
+func (r *DefaultUserRepository) GetExistingEmails(ctx context.Context, emails []string) ([]string, error) {
+	existing := []string{}
+	if len(emails) == 0 {
+		return existing, nil
+	}
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+	err := runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		return db.NewSelect().
+			Model((*UserEntity)(nil)).
+			Column("email").
+			Where("lower(email) IN (?)", bun.In(lowered)).
+			WhereAllWithDeleted().
+			Scan(ctx, &existing)
+	})
+	if err != nil {
+		return []string{}, err
+	}
+	return existing, nil
+}
+
+// InsertUsers inserts all users in one transaction, batchSize rows per
+// statement. Either every user is inserted or none is.
+func (r *DefaultUserRepository) InsertUsers(ctx context.Context, users []UserEntity, batchSize int) error {
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+		for start := 0; start < len(users); start += batchSize {
+			batch := users[start:min(start+batchSize, len(users))]
+			if _, err := tx.NewInsert().Model(&batch).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return translateError(err)
+}
+
+// IsBeingPurged reports whether the user's account deletion has started
+// erasing their data.
+func (r *DefaultUserRepository) IsBeingPurged(ctx context.Context, id uuid.UUID) (purging bool, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		purging, err = db.NewSelect().
+			Model((*AccountDeletionEntity)(nil)).
+			Where("user_id = ?", id).
+			Where("status = ?", AccountDeletionStatusPurging).
+			Exists(ctx)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return purging, nil
+}
+
+// exec runs the update or delete built by build and reports whether it
+// changed any row.
+func (r *DefaultUserRepository) exec(ctx context.Context, build func(db bun.IDB) execQuery) (changed bool, err error) {
+	err = runQuery(ctx, r.db, func(ctx context.Context, db bun.IDB) error {
+		res, err := build(db).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		changed = rowsAffected(res) > 0
+		return nil
+	})
+	return changed, err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import "github.com/google/uuid"
+
+// Workspace is the owner that categories and todos are read and written in:
+// the acting user's personal workspace, or an organization the user belongs
+// to when OrganizationId is set.
+type Workspace struct {
+	UserId         uuid.UUID
+	OrganizationId *uuid.UUID
+}
+
+func PersonalWorkspace(userId uuid.UUID) Workspace {
+	return Workspace{UserId: userId}
+}
+
+// where returns the condition that limits a query to rows of the workspace.
+// Rows have exactly one owner, so personal rows never match an organization.
+func (w Workspace) where() (string, any) {
+	if w.OrganizationId != nil {
+		return "organization_id = ?", *w.OrganizationId
+	}
+	return "user_id = ?", w.UserId
+}
+
+// owner returns the user_id and organization_id values for a new row.
+func (w Workspace) owner() (userId *uuid.UUID, organizationId *uuid.UUID) {
+	if w.OrganizationId != nil {
+		id := *w.OrganizationId
+		return nil, &id
+	}
+	id := w.UserId
+	return &id, nil
+}
+
+// ownerWorkspace returns the workspace owning a row with these owner columns.
+func ownerWorkspace(userId, organizationId *uuid.UUID) Workspace {
+	if organizationId != nil {
+		return Workspace{OrganizationId: organizationId}
+	}
+	if userId != nil {
+		return Workspace{UserId: *userId}
+	}
+	return Workspace{}
+}
+
+// SameOwner reports whether both workspaces stand for the same owner, ignoring
+// which member of an organization is acting.
+func (w Workspace) SameOwner(other Workspace) bool {
+	if w.OrganizationId != nil || other.OrganizationId != nil {
+		return w.OrganizationId != nil && other.OrganizationId != nil && *w.OrganizationId == *other.OrganizationId
+	}
+	return w.UserId == other.UserId
+}
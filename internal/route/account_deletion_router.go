@@ -0,0 +1,153 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type AccountDeletionRouter struct {
+	config                 runtime.ServerConfig
+	accountDeletionService service.AccountDeletionService
+	jwtService             service.JWTService
+	userService            service.UserService
+}
+
+func NewAccountDeletionRouter(config runtime.ServerConfig, accountDeletionService service.AccountDeletionService, jwtService service.JWTService, userService service.UserService) *AccountDeletionRouter {
+	return &AccountDeletionRouter{
+		config:                 config,
+		accountDeletionService: accountDeletionService,
+		jwtService:             jwtService,
+		userService:            userService,
+	}
+}
+
+func (r *AccountDeletionRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	admin := middleware.RequireRole(repository.RoleAdmin)
+
+	e.POST("/api/"+r.config.APIVersion+"/users/me/deletion", r.RequestDeletion, jwt)
+	e.GET("/api/"+r.config.APIVersion+"/users/me/deletion", r.GetDeletion, jwt)
+	e.DELETE("/api/"+r.config.APIVersion+"/users/me/deletion", r.CancelDeletion, jwt)
+	e.GET("/api/"+r.config.APIVersion+"/account-deletions/:id", r.GetDeletionById, jwt, admin)
+}
+
+// RequestDeletion godoc
+// @Summary Delete my account
+// @Description Schedule the signed-in user's account for permanent deletion. Signing in before the grace period ends cancels it.
+// @Tags account-deletion
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} response.AccountDeletionResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/deletion [post]
+func (r *AccountDeletionRouter) RequestDeletion(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	deletion, err := r.accountDeletionService.RequestDeletion(c.Request().Context(), userID)
+	if err != nil {
+		logger.Errorw("Failed to request account deletion", "error", err)
+		return toAccountDeletionApplicationError(err)
+	}
+
+	return c.JSON(http.StatusAccepted, deletion)
+}
+
+// GetDeletion godoc
+// @Summary Get my account deletion
+// @Description Get the signed-in user's scheduled account deletion
+// @Tags account-deletion
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.AccountDeletionResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/deletion [get]
+func (r *AccountDeletionRouter) GetDeletion(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	deletion, err := r.accountDeletionService.GetDeletion(c.Request().Context(), userID)
+	if err != nil {
+		logger.Errorw("Failed to get account deletion", "error", err)
+		return toAccountDeletionApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, deletion)
+}
+
+// CancelDeletion godoc
+// @Summary Cancel my account deletion
+// @Description Cancel the signed-in user's scheduled account deletion
+// @Tags account-deletion
+// @Produce json
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/deletion [delete]
+func (r *AccountDeletionRouter) CancelDeletion(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	if err := r.accountDeletionService.CancelDeletion(c.Request().Context(), userID); err != nil {
+		logger.Errorw("Failed to cancel account deletion", "error", err)
+		return toAccountDeletionApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetDeletionById godoc
+// @Summary Get an account deletion
+// @Description Get an account deletion and its purge progress (admin only). Still available after the user is gone.
+// @Tags account-deletion
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account deletion ID"
+// @Success 200 {object} response.AccountDeletionResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /account-deletions/{id} [get]
+func (r *AccountDeletionRouter) GetDeletionById(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	deletion, err := r.accountDeletionService.GetDeletionById(c.Request().Context(), id)
+	if err != nil {
+		logger.Errorw("Failed to get account deletion", "error", err)
+		return toAccountDeletionApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, deletion)
+}
+
+func toAccountDeletionApplicationError(err error) *exception.ApplicationError {
+	if errors.Is(err, service.ErrAccountDeletionNotFound) || errors.Is(err, service.ErrUserNotFound) {
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	}
+	if errors.Is(err, service.ErrSoleOrganizationOwner) {
+		return exception.ToApplicationError(err, exception.ErrorCodeConflict)
+	}
+	return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+}
@@ -1,36 +1,45 @@
 package route
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/metrics"
 	"github.com/lamkn06/user-app-golang.git/internal/middleware"
 	"github.com/lamkn06/user-app-golang.git/internal/runtime"
 	"github.com/lamkn06/user-app-golang.git/internal/service"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
 	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
 	"github.com/lamkn06/user-app-golang.git/pkg/logging"
 )
 
 type AuthRouter struct {
 	config      runtime.ServerConfig
 	authService service.AuthService
+	rateLimit   echo.MiddlewareFunc
 	validator   *validator.Validate
 }
 
-func NewAuthRouter(config runtime.ServerConfig, authService service.AuthService) *AuthRouter {
+// NewAuthRouter returns the auth routes. rateLimit guards the routes that
+// check credentials or send email.
+func NewAuthRouter(config runtime.ServerConfig, authService service.AuthService, rateLimit echo.MiddlewareFunc) *AuthRouter {
 	return &AuthRouter{
 		config:      config,
 		authService: authService,
-		validator:   validator.New(),
+		rateLimit:   rateLimit,
+		validator:   i18n.Validator(),
 	}
 }
 
 func (r *AuthRouter) Configure(e *echo.Echo) {
-	e.POST("/api/"+r.config.APIVersion+"/auth/signup", r.SignUp)
-	e.POST("/api/"+r.config.APIVersion+"/auth/signin", r.SignIn)
+	e.POST("/api/"+r.config.APIVersion+"/auth/signup", r.SignUp, r.rateLimit)
+	e.POST("/api/"+r.config.APIVersion+"/auth/signin", r.SignIn, r.rateLimit)
 	e.POST("/api/"+r.config.APIVersion+"/auth/signout", r.SignOut)
+	e.POST("/api/"+r.config.APIVersion+"/auth/magic-link", r.RequestMagicLink, r.rateLimit)
+	e.POST("/api/"+r.config.APIVersion+"/auth/magic-link/consume", r.ConsumeMagicLink, r.rateLimit)
 }
 
 // SignUp godoc
@@ -42,6 +51,7 @@ func (r *AuthRouter) Configure(e *echo.Echo) {
 // @Param user body request.SignUpRequest true "User registration information"
 // @Success 200 {object} response.AuthResponse
 // @Failure 400 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
 // @Failure 500 {object} exception.ApplicationError
 // @Router /auth/signup [post]
 func (r *AuthRouter) SignUp(c echo.Context) error {
@@ -49,22 +59,23 @@ func (r *AuthRouter) SignUp(c echo.Context) error {
 	req := request.SignUpRequest{}
 
 	if err := c.Bind(&req); err != nil {
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 		logger.Errorw("Failed to bind signup request", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 	}
 
 	if err := r.validator.Struct(req); err != nil {
-		appErr := middleware.ParseValidationError(err)
 		logger.Errorw("Failed to validate signup request", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		return middleware.ParseValidationError(err)
 	}
 
-	authResp, err := r.authService.SignUp(req)
+	authResp, err := r.authService.SignUp(c.Request().Context(), req)
 	if err != nil {
 		logger.Errorw("Failed to sign up user", "error", err)
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		code := exception.ErrorCodeInternalServerError
+		if errors.Is(err, service.ErrEmailTaken) {
+			code = exception.ErrorCodeConflict
+		}
+		return exception.ToApplicationError(err, code)
 	}
 
 	return c.JSON(http.StatusOK, authResp)
@@ -80,6 +91,7 @@ func (r *AuthRouter) SignUp(c echo.Context) error {
 // @Success 200 {object} response.AuthResponse
 // @Failure 400 {object} exception.ApplicationError
 // @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
 // @Failure 500 {object} exception.ApplicationError
 // @Router /auth/signin [post]
 func (r *AuthRouter) SignIn(c echo.Context) error {
@@ -87,26 +99,28 @@ func (r *AuthRouter) SignIn(c echo.Context) error {
 	req := request.SignInRequest{}
 
 	if err := c.Bind(&req); err != nil {
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 		logger.Errorw("Failed to bind signin request", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 	}
 
 	if err := r.validator.Struct(req); err != nil {
-		appErr := middleware.ParseValidationError(err)
 		logger.Errorw("Failed to validate signin request", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		return middleware.ParseValidationError(err)
 	}
 
-	authResp, err := r.authService.SignIn(req)
+	authResp, err := r.authService.SignIn(c.Request().Context(), req)
+	metrics.ObserveSignIn(metrics.SignInMethodPassword, err)
 	if err != nil {
 		logger.Errorw("Failed to sign in user", "error", err)
-		appErr := &exception.ApplicationError{
-			Code:    exception.ErrorCodeUnauthorized,
-			Message: "Invalid credentials",
-			Details: []exception.ErrorDetail{},
+		if errors.Is(err, service.ErrUserInactive) {
+			return inactiveUserError()
+		}
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid credentials",
+			MessageID: "invalid_credentials",
+			Details:   []exception.ErrorDetail{},
 		}
-		return c.JSON(appErr.HTTPStatus(), appErr)
 	}
 
 	return c.JSON(http.StatusOK, authResp)
@@ -129,20 +143,110 @@ func (r *AuthRouter) SignOut(c echo.Context) error {
 	// Get token from Authorization header
 	authHeader := c.Request().Header.Get("Authorization")
 	if authHeader == "" {
-		appErr := &exception.ApplicationError{
-			Code:    exception.ErrorCodeUnauthorized,
-			Message: "Authorization header required",
-			Details: []exception.ErrorDetail{},
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Authorization header required",
+			MessageID: "authorization_header_required",
+			Details:   []exception.ErrorDetail{},
 		}
-		return c.JSON(appErr.HTTPStatus(), appErr)
 	}
 
-	signOutResp, err := r.authService.SignOut(authHeader)
+	signOutResp, err := r.authService.SignOut(c.Request().Context(), authHeader)
 	if err != nil {
 		logger.Errorw("Failed to sign out user", "error", err)
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
 	}
 
 	return c.JSON(http.StatusOK, signOutResp)
 }
+
+// RequestMagicLink godoc
+// @Summary Request a magic sign-in link
+// @Description Email a single-use, short-lived sign-in link. Unknown addresses only get a link, which creates the account, when MAGIC_LINK_ALLOW_SIGNUP is on. The response does not reveal whether the account exists.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body request.MagicLinkRequest true "Email address"
+// @Success 202 {object} response.MagicLinkResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/magic-link [post]
+func (r *AuthRouter) RequestMagicLink(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	req := request.MagicLinkRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind magic link request", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate magic link request", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	resp, err := r.authService.RequestMagicLink(c.Request().Context(), req)
+	if err != nil {
+		logger.Errorw("Failed to send magic link", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+// ConsumeMagicLink godoc
+// @Summary Sign in with a magic link
+// @Description Redeem a magic link token and return JWT tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body request.ConsumeMagicLinkRequest true "Magic link token"
+// @Success 200 {object} response.SignInResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/magic-link/consume [post]
+func (r *AuthRouter) ConsumeMagicLink(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	req := request.ConsumeMagicLinkRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind magic link token", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate magic link token", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	authResp, err := r.authService.ConsumeMagicLink(c.Request().Context(), req)
+	metrics.ObserveSignIn(metrics.SignInMethodMagicLink, err)
+	if err != nil {
+		logger.Errorw("Failed to consume magic link", "error", err)
+		if errors.Is(err, service.ErrActionTokenInvalid) || errors.Is(err, service.ErrUserNotFound) {
+			return &exception.ApplicationError{
+				Code:      exception.ErrorCodeUnauthorized,
+				Message:   "Invalid or expired link",
+				MessageID: "invalid_link",
+				Details:   []exception.ErrorDetail{},
+			}
+		}
+		if errors.Is(err, service.ErrUserInactive) {
+			return inactiveUserError()
+		}
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, authResp)
+}
+
+func inactiveUserError() *exception.ApplicationError {
+	return &exception.ApplicationError{
+		Code:      exception.ErrorCodeForbidden,
+		Message:   "User account is deactivated",
+		MessageID: "user_inactive",
+		Details:   []exception.ErrorDetail{},
+	}
+}
@@ -0,0 +1,183 @@
+package route
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/internal/storage"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+// multipartOverhead is the room left for multipart headers and boundaries
+// on top of the avatar size limit.
+const multipartOverhead = 64 * 1024
+
+type AvatarRouter struct {
+	config        runtime.ServerConfig
+	avatarConfig  runtime.AvatarConfig
+	avatarService service.AvatarService
+	blobStore     storage.BlobStore
+	jwtService    service.JWTService
+	userService   service.UserService
+}
+
+func NewAvatarRouter(config runtime.ServerConfig, avatarConfig runtime.AvatarConfig, avatarService service.AvatarService, blobStore storage.BlobStore, jwtService service.JWTService, userService service.UserService) *AvatarRouter {
+	return &AvatarRouter{
+		config:        config,
+		avatarConfig:  avatarConfig,
+		avatarService: avatarService,
+		blobStore:     blobStore,
+		jwtService:    jwtService,
+		userService:   userService,
+	}
+}
+
+func (r *AvatarRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+
+	e.PUT("/api/"+r.config.APIVersion+"/users/:id/avatar", r.UploadAvatar, jwt)
+	e.DELETE("/api/"+r.config.APIVersion+"/users/:id/avatar", r.DeleteAvatar, jwt)
+	e.GET("/media/avatars/*", r.GetAvatarFile)
+}
+
+// UploadAvatar godoc
+// @Summary Upload a profile picture
+// @Description Upload a JPEG, PNG, GIF or WebP image as the user's avatar (self or admin). It is cropped to a square and resized to the configured thumbnail sizes.
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param avatar formData file true "Image file"
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 413 {object} exception.ApplicationError
+// @Failure 415 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/avatar [put]
+func (r *AvatarRouter) UploadAvatar(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+	if !isSelfOrAdmin(c, id) {
+		return forbiddenError()
+	}
+
+	// Stop reading oversized uploads early instead of buffering them.
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, r.avatarConfig.MaxBytes+multipartOverhead)
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return toAvatarApplicationError(service.ErrAvatarTooLarge)
+		}
+		logger.Errorw("Failed to read avatar upload", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+	defer file.Close()
+
+	user, err := r.avatarService.UploadAvatar(c.Request().Context(), id, file)
+	if err != nil {
+		logger.Errorw("Failed to upload avatar", "error", err)
+		return toAvatarApplicationError(err)
+	}
+
+	c.Response().Header().Set("ETag", user.ETag())
+	return c.JSON(http.StatusOK, user)
+}
+
+// DeleteAvatar godoc
+// @Summary Remove a profile picture
+// @Description Remove the user's avatar (self or admin)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/avatar [delete]
+func (r *AvatarRouter) DeleteAvatar(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+	if !isSelfOrAdmin(c, id) {
+		return forbiddenError()
+	}
+
+	user, err := r.avatarService.DeleteAvatar(c.Request().Context(), id)
+	if err != nil {
+		logger.Errorw("Failed to delete avatar", "error", err)
+		return toAvatarApplicationError(err)
+	}
+
+	c.Response().Header().Set("ETag", user.ETag())
+	return c.JSON(http.StatusOK, user)
+}
+
+// GetAvatarFile serves avatar thumbnails from the blob store. Every upload
+// gets new keys, so the files can be cached indefinitely.
+func (r *AvatarRouter) GetAvatarFile(c echo.Context) error {
+	key := "avatars/" + c.Param("*")
+	if strings.Contains(key, "..") {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	body, contentType, err := r.blobStore.Get(c.Request().Context(), key)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		logging.LoggerFromContext(c.Request().Context()).Errorw("Failed to read avatar file", "key", key, "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+	defer body.Close()
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, contentType, io.Reader(body))
+}
+
+func toAvatarApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrAvatarTooLarge):
+		return exception.ToApplicationError(err, exception.ErrorCodePayloadTooLarge)
+	case errors.Is(err, service.ErrAvatarUnsupportedType):
+		return exception.ToApplicationError(err, exception.ErrorCodeUnsupportedMediaType)
+	case errors.Is(err, service.ErrAvatarInvalid):
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
@@ -0,0 +1,294 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type CategoryRouter struct {
+	config              runtime.ServerConfig
+	categoryService     service.CategoryService
+	organizationService service.OrganizationService
+	jwtService          service.JWTService
+	userService         service.UserService
+	validator           *validator.Validate
+}
+
+func NewCategoryRouter(config runtime.ServerConfig, categoryService service.CategoryService, organizationService service.OrganizationService, jwtService service.JWTService, userService service.UserService) *CategoryRouter {
+	return &CategoryRouter{
+		config:              config,
+		categoryService:     categoryService,
+		organizationService: organizationService,
+		jwtService:          jwtService,
+		userService:         userService,
+		validator:           i18n.Validator(),
+	}
+}
+
+func (r *CategoryRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	workspace := middleware.RequireWorkspace(r.organizationService)
+
+	e.GET("/api/"+r.config.APIVersion+"/categories", r.GetCategories, jwt, workspace)
+	e.POST("/api/"+r.config.APIVersion+"/categories", r.CreateCategory, jwt, workspace)
+	e.GET("/api/"+r.config.APIVersion+"/categories/:id", r.GetCategoryById, jwt, workspace)
+	e.PUT("/api/"+r.config.APIVersion+"/categories/:id", r.UpdateCategory, jwt, workspace)
+	e.DELETE("/api/"+r.config.APIVersion+"/categories/:id", r.DeleteCategory, jwt, workspace)
+	e.GET("/api/"+r.config.APIVersion+"/shared/categories", r.GetSharedCategories, jwt)
+}
+
+// GetCategories godoc
+// @Summary List categories
+// @Description List the categories of the active workspace, newest first
+// @Tags categories
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param cursor query string false "nextCursor or prevCursor from a previous page; replaces page"
+// @Param include_total query bool false "Count the exact total (default true for pages, false for cursors)"
+// @Success 200 {object} response.ListResponse[response.CategoryResponse]
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories [get]
+func (r *CategoryRouter) GetCategories(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	listReq := request.NewListRequest()
+	if err := c.Bind(&listReq); err != nil {
+		logger.Errorw("Failed to bind category list parameters", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(listReq); err != nil {
+		logger.Errorw("Failed to validate category list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+	if _, err := listReq.GetCursor(); err != nil {
+		logger.Errorw("Failed to validate category list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	categories, err := r.categoryService.GetCategories(c.Request().Context(), ws, listReq)
+	if err != nil {
+		logger.Errorw("Failed to get categories", "error", err)
+		return toCategoryApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Description Create a category in the active workspace
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param category body request.CategoryRequest true "Category"
+// @Success 201 {object} response.CategoryResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories [post]
+func (r *CategoryRouter) CreateCategory(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+	req := request.CategoryRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind category", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate category", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	category, err := r.categoryService.CreateCategory(c.Request().Context(), ws, req)
+	if err != nil {
+		logger.Errorw("Failed to create category", "error", err)
+		return toCategoryApplicationError(err)
+	}
+
+	return c.JSON(http.StatusCreated, category)
+}
+
+// GetCategoryById godoc
+// @Summary Get a category
+// @Description Get a category of the active workspace or one shared with you
+// @Tags categories
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Success 200 {object} response.CategoryResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories/{id} [get]
+func (r *CategoryRouter) GetCategoryById(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	category, err := r.categoryService.GetCategoryById(c.Request().Context(), ws, id)
+	if err != nil {
+		logger.Errorw("Failed to get category", "error", err)
+		return toCategoryApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Replace the name, color and description of a category in the active workspace or shared with you as editor
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param category body request.CategoryRequest true "Category"
+// @Success 200 {object} response.CategoryResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories/{id} [put]
+func (r *CategoryRouter) UpdateCategory(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.CategoryRequest{}
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind category", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate category", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	category, err := r.categoryService.UpdateCategory(c.Request().Context(), ws, id, req)
+	if err != nil {
+		logger.Errorw("Failed to update category", "error", err)
+		return toCategoryApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Delete a category of the active workspace; sharing it stops too
+// @Tags categories
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories/{id} [delete]
+func (r *CategoryRouter) DeleteCategory(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.categoryService.DeleteCategory(c.Request().Context(), ws, id); err != nil {
+		logger.Errorw("Failed to delete category", "error", err)
+		return toCategoryApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetSharedCategories godoc
+// @Summary List categories shared with me
+// @Description List the categories other users shared with you, newest first, with your access (viewer or editor)
+// @Tags sharing
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param cursor query string false "nextCursor or prevCursor from a previous page; replaces page"
+// @Param include_total query bool false "Count the exact total (default true for pages, false for cursors)"
+// @Success 200 {object} response.ListResponse[response.CategoryResponse]
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /shared/categories [get]
+func (r *CategoryRouter) GetSharedCategories(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	listReq := request.NewListRequest()
+	if err := c.Bind(&listReq); err != nil {
+		logger.Errorw("Failed to bind shared category list parameters", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(listReq); err != nil {
+		logger.Errorw("Failed to validate shared category list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+	if _, err := listReq.GetCursor(); err != nil {
+		logger.Errorw("Failed to validate shared category list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	categories, err := r.categoryService.GetSharedCategories(c.Request().Context(), userID, listReq)
+	if err != nil {
+		logger.Errorw("Failed to get shared categories", "error", err)
+		return toCategoryApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, categories)
+}
+
+func toCategoryApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrCategoryNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrCategoryForbidden):
+		return exception.ToApplicationError(err, exception.ErrorCodeForbidden)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
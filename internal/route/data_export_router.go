@@ -0,0 +1,197 @@
+package route
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type DataExportRouter struct {
+	config            runtime.ServerConfig
+	dataExportService service.DataExportService
+	jwtService        service.JWTService
+	userService       service.UserService
+}
+
+func NewDataExportRouter(config runtime.ServerConfig, dataExportService service.DataExportService, jwtService service.JWTService, userService service.UserService) *DataExportRouter {
+	return &DataExportRouter{
+		config:            config,
+		dataExportService: dataExportService,
+		jwtService:        jwtService,
+		userService:       userService,
+	}
+}
+
+func (r *DataExportRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	admin := middleware.RequireRole(repository.RoleAdmin)
+
+	e.POST("/api/"+r.config.APIVersion+"/users/me/exports", r.RequestExport, jwt)
+	e.GET("/api/"+r.config.APIVersion+"/users/me/exports", r.GetExports, jwt)
+	e.GET("/api/"+r.config.APIVersion+"/users/me/exports/:exportId", r.GetExport, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/users/:id/exports", r.RequestUserExport, jwt, admin)
+	e.GET("/api/"+r.config.APIVersion+"/exports/download", r.Download)
+}
+
+// RequestExport godoc
+// @Summary Request a personal data export
+// @Description Queue a ZIP export of everything stored about the signed-in user. A download link is emailed when it is ready. An export already in progress is returned instead of queueing another.
+// @Tags exports
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} response.DataExportResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/exports [post]
+func (r *DataExportRouter) RequestExport(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	return r.requestExport(c, userID, userID)
+}
+
+// RequestUserExport godoc
+// @Summary Request a data export for a user
+// @Description Queue a personal data export on a user's behalf (admin only). The link is emailed to the user.
+// @Tags exports
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 202 {object} response.DataExportResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/exports [post]
+func (r *DataExportRouter) RequestUserExport(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+	return r.requestExport(c, id, c.Get("userID").(uuid.UUID))
+}
+
+func (r *DataExportRouter) requestExport(c echo.Context, userID uuid.UUID, requestedBy uuid.UUID) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	export, err := r.dataExportService.RequestExport(c.Request().Context(), userID, requestedBy)
+	if err != nil {
+		logger.Errorw("Failed to request data export", "error", err)
+		return toDataExportApplicationError(err)
+	}
+
+	return c.JSON(http.StatusAccepted, export)
+}
+
+// GetExports godoc
+// @Summary List data exports
+// @Description List the signed-in user's data exports, newest first
+// @Tags exports
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} response.DataExportResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/exports [get]
+func (r *DataExportRouter) GetExports(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	exports, err := r.dataExportService.GetExports(c.Request().Context(), userID)
+	if err != nil {
+		logger.Errorw("Failed to get data exports", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, exports)
+}
+
+// GetExport godoc
+// @Summary Get a data export
+// @Description Get the status of one of the signed-in user's data exports, including the download link once it is ready
+// @Tags exports
+// @Produce json
+// @Security BearerAuth
+// @Param exportId path string true "Export ID"
+// @Success 200 {object} response.DataExportResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/exports/{exportId} [get]
+func (r *DataExportRouter) GetExport(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("exportId"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	export, err := r.dataExportService.GetExport(c.Request().Context(), userID, id)
+	if err != nil {
+		logger.Errorw("Failed to get data export", "error", err)
+		return toDataExportApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, export)
+}
+
+// Download godoc
+// @Summary Download a data export
+// @Description Download the ZIP archive using the signed link from the export email
+// @Tags exports
+// @Produce application/zip
+// @Param token query string true "Signed download token"
+// @Success 200 {file} file
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 410 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /exports/download [get]
+func (r *DataExportRouter) Download(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	filename, archive, err := r.dataExportService.Download(c.Request().Context(), c.QueryParam("token"))
+	if err != nil {
+		logger.Errorw("Failed to download data export", "error", err)
+		if errors.Is(err, service.ErrDataExportNotReady) {
+			return &exception.ApplicationError{
+				Code:      exception.ErrorCodeGone,
+				Message:   "Export is no longer available",
+				MessageID: "data_export_gone",
+				Details:   []exception.ErrorDetail{},
+			}
+		}
+		return toDataExportApplicationError(err)
+	}
+	defer archive.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Stream(http.StatusOK, "application/zip", io.Reader(archive))
+}
+
+func toDataExportApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrDataExportNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrActionTokenInvalid):
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid or expired link",
+			MessageID: "invalid_link",
+			Details:   []exception.ErrorDetail{},
+		}
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
@@ -0,0 +1,161 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type EmailChangeRouter struct {
+	config             runtime.ServerConfig
+	emailChangeService service.EmailChangeService
+	jwtService         service.JWTService
+	userService        service.UserService
+	validator          *validator.Validate
+}
+
+func NewEmailChangeRouter(config runtime.ServerConfig, emailChangeService service.EmailChangeService, jwtService service.JWTService, userService service.UserService) *EmailChangeRouter {
+	return &EmailChangeRouter{
+		config:             config,
+		emailChangeService: emailChangeService,
+		jwtService:         jwtService,
+		userService:        userService,
+		validator:          i18n.Validator(),
+	}
+}
+
+func (r *EmailChangeRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+
+	e.POST("/api/"+r.config.APIVersion+"/users/me/email", r.RequestEmailChange, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/email-change/confirm", r.ConfirmEmailChange)
+	e.POST("/api/"+r.config.APIVersion+"/email-change/revert", r.RevertEmailChange)
+}
+
+// RequestEmailChange godoc
+// @Summary Change my email address
+// @Description Email a confirmation link to the new address and a notice to the current one. The address only changes once the link is followed.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.EmailChangeRequest true "New email address"
+// @Success 202 {object} response.EmailChangeResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/me/email [post]
+func (r *EmailChangeRouter) RequestEmailChange(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+	req := request.EmailChangeRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind email change request", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate email change request", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	resp, err := r.emailChangeService.RequestEmailChange(c.Request().Context(), userID, req)
+	if err != nil {
+		logger.Errorw("Failed to request email change", "error", err)
+		return toEmailChangeApplicationError(err)
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm a new email address
+// @Description Redeem the link sent to the new address and switch the account to it. The old address receives a link to undo the change.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body request.EmailChangeTokenRequest true "Confirmation token"
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /email-change/confirm [post]
+func (r *EmailChangeRouter) ConfirmEmailChange(c echo.Context) error {
+	return r.consume(c, "confirm", r.emailChangeService.ConfirmEmailChange)
+}
+
+// RevertEmailChange godoc
+// @Summary Revert an email change
+// @Description Redeem the link sent to the previous address and switch the account back to it
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body request.EmailChangeTokenRequest true "Revert token"
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /email-change/revert [post]
+func (r *EmailChangeRouter) RevertEmailChange(c echo.Context) error {
+	return r.consume(c, "revert", r.emailChangeService.RevertEmailChange)
+}
+
+func (r *EmailChangeRouter) consume(c echo.Context, action string, redeem func(context.Context, request.EmailChangeTokenRequest) (response.NewUserResponse, error)) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	req := request.EmailChangeTokenRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind email change token", "action", action, "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate email change token", "action", action, "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	user, err := redeem(c.Request().Context(), req)
+	if err != nil {
+		logger.Errorw("Failed to redeem email change token", "action", action, "error", err)
+		return toEmailChangeApplicationError(err)
+	}
+
+	c.Response().Header().Set("ETag", user.ETag())
+	return c.JSON(http.StatusOK, user)
+}
+
+func toEmailChangeApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrActionTokenInvalid):
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid or expired link",
+			MessageID: "invalid_link",
+			Details:   []exception.ErrorDetail{},
+		}
+	case errors.Is(err, service.ErrEmailTaken):
+		return exception.ToApplicationError(err, exception.ErrorCodeConflict)
+	case errors.Is(err, service.ErrEmailUnchanged):
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	case errors.Is(err, service.ErrUserNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
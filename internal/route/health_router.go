@@ -2,23 +2,30 @@ package route
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/lamkn06/user-app-golang.git/internal/buildinfo"
+	"github.com/lamkn06/user-app-golang.git/internal/health"
 	"github.com/lamkn06/user-app-golang.git/internal/runtime"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
 )
 
 type HealthRouter struct {
 	config runtime.ServerConfig
+	checks *health.Registry
 }
 
 func (r *HealthRouter) Configure(e *echo.Echo) {
 	e.GET("/api/"+r.config.APIVersion+"/health", r.HealthCheck)
+	e.GET("/livez", r.Liveness)
+	e.GET("/readyz", r.Readiness)
 }
 
-func NewHealthRouter(config runtime.ServerConfig) *HealthRouter {
-	return &HealthRouter{config: config}
+func NewHealthRouter(config runtime.ServerConfig, checks *health.Registry) *HealthRouter {
+	return &HealthRouter{config: config, checks: checks}
 }
 
 // HealthCheck godoc
@@ -37,3 +44,66 @@ func (r *HealthRouter) HealthCheck(c echo.Context) (err error) {
 	}
 	return c.JSON(http.StatusOK, healthResp)
 }
+
+// Liveness godoc
+//
+// @Summary     Liveness probe
+// @Description Report that the process is up, without checking dependencies
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} response.LivenessResponse
+// @Router      /livez [get]
+func (r *HealthRouter) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, response.LivenessResponse{
+		Status: "ok",
+		Build:  buildInfoResponse(),
+	})
+}
+
+// Readiness godoc
+//
+// @Summary     Readiness probe
+// @Description Check the database, the schema version and the other registered dependencies
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} response.ReadinessResponse
+// @Failure     503 {object} response.ReadinessResponse
+// @Router      /readyz [get]
+func (r *HealthRouter) Readiness(c echo.Context) error {
+	results := r.checks.Run(c.Request().Context())
+
+	resp := response.ReadinessResponse{
+		Status: "ok",
+		Build:  buildInfoResponse(),
+		Checks: make(map[string]response.HealthCheckResponse, len(results)),
+	}
+	logger := logging.LoggerFromContext(c.Request().Context())
+	for _, result := range results {
+		check := response.HealthCheckResponse{
+			Status:     "ok",
+			DurationMs: float64(result.Duration.Microseconds()) / float64(time.Millisecond/time.Microsecond),
+			Detail:     result.Detail,
+		}
+		if result.Err != nil {
+			// The probe is public, so the error text stays in the log.
+			check.Status = "failed"
+			logger.Warnw("Readiness check failed", "check", result.Name, "error", result.Err)
+		}
+		resp.Checks[result.Name] = check
+	}
+
+	status := http.StatusOK
+	if !health.Healthy(results) {
+		resp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, resp)
+}
+
+func buildInfoResponse() response.BuildInfoResponse {
+	return response.BuildInfoResponse{
+		Version:   buildinfo.Version,
+		Commit:    buildinfo.Commit,
+		BuildTime: buildinfo.BuildTime,
+	}
+}
@@ -0,0 +1,199 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type InvitationRouter struct {
+	config            runtime.ServerConfig
+	invitationService service.InvitationService
+	jwtService        service.JWTService
+	userService       service.UserService
+	validator         *validator.Validate
+}
+
+func NewInvitationRouter(config runtime.ServerConfig, invitationService service.InvitationService, jwtService service.JWTService, userService service.UserService) *InvitationRouter {
+	return &InvitationRouter{
+		config:            config,
+		invitationService: invitationService,
+		jwtService:        jwtService,
+		userService:       userService,
+		validator:         i18n.Validator(),
+	}
+}
+
+func (r *InvitationRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	admin := middleware.RequireRole(repository.RoleAdmin)
+
+	e.POST("/api/"+r.config.APIVersion+"/users", r.CreateUser, jwt, admin)
+	e.POST("/api/"+r.config.APIVersion+"/users/:id/invitation", r.ResendInvitation, jwt, admin)
+	e.DELETE("/api/"+r.config.APIVersion+"/users/:id/invitation", r.RevokeInvitation, jwt, admin)
+	e.POST("/api/"+r.config.APIVersion+"/invitations/accept", r.AcceptInvitation)
+}
+
+// CreateUser godoc
+// @Summary Invite a new user
+// @Description Create a pending user with name and email and email them an invite link to set a password (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user body request.NewUserRequest true "User information"
+// @Success 201 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users [post]
+func (r *InvitationRouter) CreateUser(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	inviterID := c.Get("userID").(uuid.UUID)
+	user := request.NewUserRequest{}
+
+	if err := c.Bind(&user); err != nil {
+		logger.Errorw("Failed to bind user", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(user); err != nil {
+		logger.Errorw("Failed to validate user", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	newUser, err := r.invitationService.InviteUser(c.Request().Context(), inviterID, user)
+	if err != nil {
+		logger.Errorw("Failed to invite user", "error", err)
+		return toInvitationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusCreated, newUser)
+}
+
+// ResendInvitation godoc
+// @Summary Resend an invitation
+// @Description Email a new invite link to a pending user; earlier links stop working (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/invitation [post]
+func (r *InvitationRouter) ResendInvitation(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	user, err := r.invitationService.ResendInvitation(c.Request().Context(), id)
+	if err != nil {
+		logger.Errorw("Failed to resend invitation", "error", err)
+		return toInvitationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Delete a pending user whose invitation has not been accepted (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/invitation [delete]
+func (r *InvitationRouter) RevokeInvitation(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.invitationService.RevokeInvitation(c.Request().Context(), id); err != nil {
+		logger.Errorw("Failed to revoke invitation", "error", err)
+		return toInvitationApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Redeem an invite link, set a password and activate the account. Returns JWT tokens.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body request.AcceptInvitationRequest true "Invite token and new password"
+// @Success 200 {object} response.SignInResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /invitations/accept [post]
+func (r *InvitationRouter) AcceptInvitation(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	req := request.AcceptInvitationRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind invitation acceptance", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate invitation acceptance", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	authResp, err := r.invitationService.AcceptInvitation(c.Request().Context(), req)
+	if err != nil {
+		logger.Errorw("Failed to accept invitation", "error", err)
+		return toInvitationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, authResp)
+}
+
+func toInvitationApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrActionTokenInvalid):
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid or expired link",
+			MessageID: "invalid_link",
+			Details:   []exception.ErrorDetail{},
+		}
+	case errors.Is(err, service.ErrEmailTaken):
+		return exception.ToApplicationError(err, exception.ErrorCodeConflict)
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrInvitationNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
@@ -0,0 +1,471 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type OrganizationRouter struct {
+	config              runtime.ServerConfig
+	organizationService service.OrganizationService
+	jwtService          service.JWTService
+	userService         service.UserService
+	validator           *validator.Validate
+}
+
+func NewOrganizationRouter(config runtime.ServerConfig, organizationService service.OrganizationService, jwtService service.JWTService, userService service.UserService) *OrganizationRouter {
+	return &OrganizationRouter{
+		config:              config,
+		organizationService: organizationService,
+		jwtService:          jwtService,
+		userService:         userService,
+		validator:           i18n.Validator(),
+	}
+}
+
+func (r *OrganizationRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+
+	e.GET("/api/"+r.config.APIVersion+"/organizations", r.GetOrganizations, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/organizations", r.CreateOrganization, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/organizations/invitations/accept", r.AcceptInvitation, jwt)
+	e.GET("/api/"+r.config.APIVersion+"/organizations/:id", r.GetOrganization, jwt)
+	e.PATCH("/api/"+r.config.APIVersion+"/organizations/:id", r.UpdateOrganization, jwt)
+	e.DELETE("/api/"+r.config.APIVersion+"/organizations/:id", r.DeleteOrganization, jwt)
+	e.GET("/api/"+r.config.APIVersion+"/organizations/:id/members", r.GetMembers, jwt)
+	e.PATCH("/api/"+r.config.APIVersion+"/organizations/:id/members/:userId", r.UpdateMemberRole, jwt)
+	e.DELETE("/api/"+r.config.APIVersion+"/organizations/:id/members/:userId", r.RemoveMember, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/organizations/:id/invitations", r.InviteMember, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/auth/switch-workspace", r.SwitchWorkspace, jwt)
+}
+
+// GetOrganizations godoc
+// @Summary List my organizations
+// @Description List the organizations the current user belongs to, with the user's role in each
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} response.OrganizationResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations [get]
+func (r *OrganizationRouter) GetOrganizations(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizations, err := r.organizationService.GetOrganizations(c.Request().Context(), userID)
+	if err != nil {
+		logger.Errorw("Failed to get organizations", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, organizations)
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create an organization with the current user as its owner
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param organization body request.OrganizationRequest true "Organization name"
+// @Success 201 {object} response.OrganizationResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations [post]
+func (r *OrganizationRouter) CreateOrganization(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+	req := request.OrganizationRequest{}
+
+	if appErr := r.bind(c, &req, "organization"); appErr != nil {
+		return appErr
+	}
+
+	organization, err := r.organizationService.CreateOrganization(c.Request().Context(), userID, req)
+	if err != nil {
+		logger.Errorw("Failed to create organization", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusCreated, organization)
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Get an organization the current user belongs to
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {object} response.OrganizationResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id} [get]
+func (r *OrganizationRouter) GetOrganization(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	organization, err := r.organizationService.GetOrganization(c.Request().Context(), userID, organizationID)
+	if err != nil {
+		logger.Errorw("Failed to get organization", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, organization)
+}
+
+// UpdateOrganization godoc
+// @Summary Rename an organization
+// @Description Rename an organization (owners and admins)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param organization body request.OrganizationRequest true "Organization name"
+// @Success 200 {object} response.OrganizationResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id} [patch]
+func (r *OrganizationRouter) UpdateOrganization(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.OrganizationRequest{}
+	if appErr := r.bind(c, &req, "organization"); appErr != nil {
+		return appErr
+	}
+
+	organization, err := r.organizationService.UpdateOrganization(c.Request().Context(), userID, organizationID, req)
+	if err != nil {
+		logger.Errorw("Failed to update organization", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, organization)
+}
+
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Description Delete an organization together with its categories and todos (owners only)
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id} [delete]
+func (r *OrganizationRouter) DeleteOrganization(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.organizationService.DeleteOrganization(c.Request().Context(), userID, organizationID); err != nil {
+		logger.Errorw("Failed to delete organization", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMembers godoc
+// @Summary List organization members
+// @Description List the members of an organization the current user belongs to
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {array} response.OrganizationMemberResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id}/members [get]
+func (r *OrganizationRouter) GetMembers(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	members, err := r.organizationService.GetMembers(c.Request().Context(), userID, organizationID)
+	if err != nil {
+		logger.Errorw("Failed to get organization members", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, members)
+}
+
+// UpdateMemberRole godoc
+// @Summary Change a member's role
+// @Description Change the role of an organization member. Admins manage admins and members; only owners grant or take away the owner role. The last owner cannot be demoted.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param userId path string true "Member user ID"
+// @Param role body request.OrganizationMemberRoleRequest true "New role"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id}/members/{userId} [patch]
+func (r *OrganizationRouter) UpdateMemberRole(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, memberID, err := r.memberParams(c)
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.OrganizationMemberRoleRequest{}
+	if appErr := r.bind(c, &req, "member role"); appErr != nil {
+		return appErr
+	}
+
+	if err := r.organizationService.UpdateMemberRole(c.Request().Context(), userID, organizationID, memberID, req); err != nil {
+		logger.Errorw("Failed to update organization member role", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveMember godoc
+// @Summary Remove a member
+// @Description Remove a member from an organization, or leave it when the user ID is your own. The last owner cannot leave.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param userId path string true "Member user ID"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id}/members/{userId} [delete]
+func (r *OrganizationRouter) RemoveMember(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, memberID, err := r.memberParams(c)
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.organizationService.RemoveMember(c.Request().Context(), userID, organizationID, memberID); err != nil {
+		logger.Errorw("Failed to remove organization member", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// InviteMember godoc
+// @Summary Invite a member
+// @Description Email an invite link to join the organization as admin or member (owners and admins)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param invitation body request.OrganizationInviteRequest true "Email and role"
+// @Success 202 {object} response.OrganizationInvitationResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/{id}/invitations [post]
+func (r *OrganizationRouter) InviteMember(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.OrganizationInviteRequest{}
+	if appErr := r.bind(c, &req, "organization invitation"); appErr != nil {
+		return appErr
+	}
+
+	invitation, err := r.organizationService.InviteMember(c.Request().Context(), userID, organizationID, req)
+	if err != nil {
+		logger.Errorw("Failed to invite organization member", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusAccepted, invitation)
+}
+
+// AcceptInvitation godoc
+// @Summary Join an organization
+// @Description Redeem an organization invite link. The link only works for the account whose email it was sent to.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.OrganizationInvitationTokenRequest true "Invite token"
+// @Success 200 {object} response.OrganizationResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 409 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /organizations/invitations/accept [post]
+func (r *OrganizationRouter) AcceptInvitation(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+	req := request.OrganizationInvitationTokenRequest{}
+
+	if appErr := r.bind(c, &req, "organization invitation acceptance"); appErr != nil {
+		return appErr
+	}
+
+	organization, err := r.organizationService.AcceptInvitation(c.Request().Context(), userID, req)
+	if err != nil {
+		logger.Errorw("Failed to accept organization invitation", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, organization)
+}
+
+// SwitchWorkspace godoc
+// @Summary Switch the active workspace
+// @Description Issue new tokens scoped to an organization the user belongs to, or to the personal workspace when organization_id is omitted
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.SwitchWorkspaceRequest true "Organization to switch to"
+// @Success 200 {object} response.SignInResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/switch-workspace [post]
+func (r *OrganizationRouter) SwitchWorkspace(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+	req := request.SwitchWorkspaceRequest{}
+
+	if appErr := r.bind(c, &req, "workspace switch"); appErr != nil {
+		return appErr
+	}
+
+	var organizationID *uuid.UUID
+	if req.OrganizationID != nil {
+		id, err := uuid.Parse(*req.OrganizationID)
+		if err != nil {
+			return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+		}
+		organizationID = &id
+	}
+
+	authResp, err := r.organizationService.SwitchWorkspace(c.Request().Context(), userID, organizationID)
+	if err != nil {
+		logger.Errorw("Failed to switch workspace", "error", err)
+		return toOrganizationApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, authResp)
+}
+
+// bind binds and validates the request body.
+func (r *OrganizationRouter) bind(c echo.Context, req any, name string) *exception.ApplicationError {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	if err := c.Bind(req); err != nil {
+		logger.Errorw("Failed to bind "+name, "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate "+name, "error", err)
+		return middleware.ParseValidationError(err)
+	}
+	return nil
+}
+
+func (r *OrganizationRouter) memberParams(c echo.Context) (organizationID, memberID uuid.UUID, err error) {
+	organizationID, err = uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	memberID, err = uuid.Parse(c.Param("userId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return organizationID, memberID, nil
+}
+
+func toOrganizationApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrActionTokenInvalid):
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid or expired link",
+			MessageID: "invalid_link",
+			Details:   []exception.ErrorDetail{},
+		}
+	case errors.Is(err, service.ErrOrganizationNotFound), errors.Is(err, service.ErrOrganizationMemberNotFound), errors.Is(err, service.ErrUserNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrOrganizationForbidden):
+		return exception.ToApplicationError(err, exception.ErrorCodeForbidden)
+	case errors.Is(err, service.ErrLastOrganizationOwner), errors.Is(err, service.ErrAlreadyOrganizationMember):
+		return exception.ToApplicationError(err, exception.ErrorCodeConflict)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
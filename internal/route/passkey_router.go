@@ -0,0 +1,263 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/metrics"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type PasskeyRouter struct {
+	config         runtime.ServerConfig
+	passkeyService service.PasskeyService
+	jwtService     service.JWTService
+	userService    service.UserService
+	rateLimit      echo.MiddlewareFunc
+	validator      *validator.Validate
+}
+
+// NewPasskeyRouter returns the passkey routes. rateLimit guards the sign-in
+// routes, which need no access token.
+func NewPasskeyRouter(config runtime.ServerConfig, passkeyService service.PasskeyService, jwtService service.JWTService, userService service.UserService, rateLimit echo.MiddlewareFunc) *PasskeyRouter {
+	return &PasskeyRouter{
+		config:         config,
+		passkeyService: passkeyService,
+		jwtService:     jwtService,
+		userService:    userService,
+		rateLimit:      rateLimit,
+		validator:      i18n.Validator(),
+	}
+}
+
+func (r *PasskeyRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+
+	e.POST("/api/"+r.config.APIVersion+"/auth/passkeys/register/begin", r.BeginRegistration, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/auth/passkeys/register/finish", r.FinishRegistration, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/auth/passkeys/login/begin", r.BeginLogin, r.rateLimit)
+	e.POST("/api/"+r.config.APIVersion+"/auth/passkeys/login/finish", r.FinishLogin, r.rateLimit)
+	e.GET("/api/"+r.config.APIVersion+"/auth/passkeys", r.GetPasskeys, jwt)
+	e.DELETE("/api/"+r.config.APIVersion+"/auth/passkeys/:id", r.DeletePasskey, jwt)
+}
+
+// BeginRegistration godoc
+// @Summary Start passkey registration
+// @Description Create WebAuthn credential creation options for the signed-in user
+// @Tags passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.PasskeyOptionsResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/passkeys/register/begin [post]
+func (r *PasskeyRouter) BeginRegistration(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	options, err := r.passkeyService.BeginRegistration(c.Request().Context(), userID)
+	if err != nil {
+		logger.Errorw("Failed to begin passkey registration", "error", err)
+		return toPasskeyApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, options)
+}
+
+// FinishRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verify the authenticator attestation and store the new passkey
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param credential body request.FinishPasskeyRegistrationRequest true "Attestation response"
+// @Success 200 {object} response.PasskeyResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/passkeys/register/finish [post]
+func (r *PasskeyRouter) FinishRegistration(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+	req := request.FinishPasskeyRegistrationRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind passkey registration request", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate passkey registration request", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	passkey, err := r.passkeyService.FinishRegistration(c.Request().Context(), userID, req)
+	if err != nil {
+		logger.Errorw("Failed to finish passkey registration", "error", err)
+		return toPasskeyApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, passkey)
+}
+
+// BeginLogin godoc
+// @Summary Start passkey sign-in
+// @Description Create WebAuthn assertion options. Omit the email for a discoverable (usernameless) sign-in. Addresses without passkeys get decoy options.
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param user body request.BeginPasskeyLoginRequest false "Account to sign in to"
+// @Success 200 {object} response.PasskeyOptionsResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/passkeys/login/begin [post]
+func (r *PasskeyRouter) BeginLogin(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	req := request.BeginPasskeyLoginRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind passkey login request", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate passkey login request", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	options, err := r.passkeyService.BeginLogin(c.Request().Context(), req)
+	if err != nil {
+		logger.Errorw("Failed to begin passkey login", "error", err)
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid credentials",
+			MessageID: "invalid_credentials",
+			Details:   []exception.ErrorDetail{},
+		}
+	}
+
+	return c.JSON(http.StatusOK, options)
+}
+
+// FinishLogin godoc
+// @Summary Finish passkey sign-in
+// @Description Verify the authenticator assertion and return JWT tokens
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param credential body request.FinishPasskeyLoginRequest true "Assertion response"
+// @Success 200 {object} response.SignInResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/passkeys/login/finish [post]
+func (r *PasskeyRouter) FinishLogin(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	req := request.FinishPasskeyLoginRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind passkey login request", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate passkey login request", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	authResp, err := r.passkeyService.FinishLogin(c.Request().Context(), req)
+	metrics.ObserveSignIn(metrics.SignInMethodPasskey, err)
+	if err != nil {
+		logger.Errorw("Failed to finish passkey login", "error", err)
+		if errors.Is(err, service.ErrUserInactive) {
+			return inactiveUserError()
+		}
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodeUnauthorized,
+			Message:   "Invalid credentials",
+			MessageID: "invalid_credentials",
+			Details:   []exception.ErrorDetail{},
+		}
+	}
+
+	return c.JSON(http.StatusOK, authResp)
+}
+
+// GetPasskeys godoc
+// @Summary List passkeys
+// @Description List the passkeys registered by the signed-in user
+// @Tags passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} response.PasskeyResponse
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/passkeys [get]
+func (r *PasskeyRouter) GetPasskeys(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	passkeys, err := r.passkeyService.GetPasskeys(c.Request().Context(), userID)
+	if err != nil {
+		logger.Errorw("Failed to get passkeys", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, passkeys)
+}
+
+// DeletePasskey godoc
+// @Summary Remove a passkey
+// @Description Remove one of the signed-in user's passkeys
+// @Tags passkeys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Passkey ID"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /auth/passkeys/{id} [delete]
+func (r *PasskeyRouter) DeletePasskey(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.passkeyService.DeletePasskey(c.Request().Context(), userID, id); err != nil {
+		logger.Errorw("Failed to delete passkey", "error", err)
+		return toPasskeyApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func toPasskeyApplicationError(err error) *exception.ApplicationError {
+	var protocolErr *protocol.Error
+
+	switch {
+	case errors.Is(err, service.ErrPasskeyNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrPasskeySessionInvalid), errors.As(err, &protocolErr):
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
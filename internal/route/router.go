@@ -2,11 +2,19 @@ package route
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/health"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/ratelimit"
 	"github.com/lamkn06/user-app-golang.git/internal/repository"
 	"github.com/lamkn06/user-app-golang.git/internal/runtime"
 	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/internal/storage"
+	"github.com/lamkn06/user-app-golang.git/internal/worker"
 	"github.com/uptrace/bun"
 )
 
@@ -14,16 +22,137 @@ type Router interface {
 	Configure(e *echo.Echo)
 }
 
-func Routers(ctx context.Context, config runtime.ServerConfig, db *bun.DB, jwtConfig runtime.JWTConfig) (routers []Router, err error) {
-	userRepository := repository.NewUserRepository(db, ctx)
-	userService := service.NewUserService(userRepository)
+// Routers wires the services and returns the HTTP routers together with the
+// background workers that share those services. It registers the readiness
+// checks of the dependencies it connects to with checks.
+func Routers(ctx context.Context, config runtime.ServerConfig, db *bun.DB, jwtConfig runtime.JWTConfig, webAuthnConfig runtime.WebAuthnConfig, mailerConfig runtime.MailerConfig, magicLinkConfig runtime.MagicLinkConfig, dataExportConfig runtime.DataExportConfig, accountDeletionConfig runtime.AccountDeletionConfig, blobStoreConfig runtime.BlobStoreConfig, avatarConfig runtime.AvatarConfig, emailChangeConfig runtime.EmailChangeConfig, invitationConfig runtime.InvitationConfig, userImportConfig runtime.UserImportConfig, rateLimitConfig runtime.RateLimitConfig, timeoutConfig runtime.TimeoutConfig, checks *health.Registry) (routers []Router, middlewares []echo.MiddlewareFunc, workers []worker.Worker, err error) {
+	userRepository := repository.NewUserRepository(db)
+
+	blobStore, err := storage.NewBlobStore(ctx, blobStoreConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	avatarService := service.NewAvatarService(avatarConfig, userRepository, blobStore)
+
+	userService := service.NewUserService(userRepository, avatarService)
 
 	jwtService := service.NewJWTService(jwtConfig)
-	authService := service.NewAuthService(userRepository, jwtService)
 
-	return []Router{
-		NewHealthRouter(config),
+	var rateLimitStore ratelimit.Store
+	if rateLimitConfig.Enabled {
+		if rateLimitStore, err = ratelimit.NewStore(rateLimitConfig, db); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	defaultLimit, err := ratelimit.ParseLimit(rateLimitConfig.Default)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	authLimit, err := ratelimit.ParseLimit(rateLimitConfig.Auth)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	authRateLimit := middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+		Name:  "auth",
+		Limit: authLimit,
+		Key:   middleware.KeyByIP,
+	})
+
+	mail, err := mailer.NewMailer(mailerConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	actionTokenRepository := repository.NewActionTokenRepository(db)
+	actionTokenService := service.NewActionTokenService(actionTokenRepository, jwtService)
+
+	categoryRepository := repository.NewCategoryRepository(db)
+	todoRepository := repository.NewTodoRepository(db)
+	passkeyRepository := repository.NewPasskeyRepository(db)
+
+	dataExportService := service.NewDataExportService(
+		config,
+		dataExportConfig,
+		repository.NewDataExportRepository(db),
+		userRepository,
+		categoryRepository,
+		todoRepository,
+		passkeyRepository,
+		actionTokenRepository,
+		jwtService,
+		avatarService,
+		blobStore,
+		mail,
+	)
+
+	accountDeletionRepository := repository.NewAccountDeletionRepository(db)
+	organizationRepository := repository.NewOrganizationRepository(db)
+	accountDeletionService := service.NewAccountDeletionService(accountDeletionConfig, accountDeletionRepository, userRepository, organizationRepository, avatarService, dataExportService, mail)
+
+	authService := service.NewAuthService(userRepository, jwtService, actionTokenService, accountDeletionService, avatarService, mail, magicLinkConfig)
+
+	emailChangeService := service.NewEmailChangeService(emailChangeConfig, userRepository, actionTokenRepository, actionTokenService, avatarService, mail)
+
+	invitationService := service.NewInvitationService(invitationConfig, userRepository, actionTokenRepository, actionTokenService, jwtService, accountDeletionService, avatarService, mail)
+	userImportService := service.NewUserImportService(userImportConfig, userRepository, invitationService)
+
+	organizationService := service.NewOrganizationService(invitationConfig, organizationRepository, userRepository, actionTokenService, jwtService, avatarService, mail)
+
+	categoryService := service.NewCategoryService(categoryRepository)
+
+	todoService := service.NewTodoService(todoRepository, categoryRepository)
+
+	shareService := service.NewShareService(repository.NewResourceGrantRepository(db), categoryRepository, todoRepository, userRepository)
+
+	passkeyService, err := service.NewPasskeyService(webAuthnConfig, userRepository, passkeyRepository, jwtService, accountDeletionService, avatarService)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	checks.Register("database", health.Database(db))
+	checks.Register("migrations", health.Migrations(db))
+	checks.Register("blob_store", func(ctx context.Context) (string, error) {
+		return blobStoreConfig.Driver, blobStore.Ping(ctx)
+	})
+
+	routers = []Router{
+		NewHealthRouter(config, checks),
 		NewUserRouter(config, userService, jwtService),
-		NewAuthRouter(config, authService),
-	}, err
+		NewAuthRouter(config, authService, authRateLimit),
+		NewInvitationRouter(config, invitationService, jwtService, userService),
+		NewUserImportRouter(config, userImportConfig, userImportService, jwtService, userService),
+		NewPasskeyRouter(config, passkeyService, jwtService, userService, authRateLimit),
+		NewDataExportRouter(config, dataExportService, jwtService, userService),
+		NewAccountDeletionRouter(config, accountDeletionService, jwtService, userService),
+		NewEmailChangeRouter(config, emailChangeService, jwtService, userService),
+		NewAvatarRouter(config, avatarConfig, avatarService, blobStore, jwtService, userService),
+		NewOrganizationRouter(config, organizationService, jwtService, userService),
+		NewCategoryRouter(config, categoryService, organizationService, jwtService, userService),
+		NewTodoRouter(config, todoService, organizationService, jwtService, userService),
+		NewShareRouter(config, shareService, organizationService, jwtService, userService),
+	}
+	apiPrefix := "/api/" + config.APIVersion
+	middlewares = []echo.MiddlewareFunc{
+		middleware.RequestTimeout(timeoutConfig.Request, map[string]time.Duration{
+			"POST " + apiPrefix + "/users/import":    timeoutConfig.Transfer,
+			"PUT " + apiPrefix + "/users/:id/avatar": timeoutConfig.Transfer,
+			"GET " + apiPrefix + "/exports/download": timeoutConfig.Transfer,
+		}),
+		middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Name:  "default",
+			Limit: defaultLimit,
+			Key:   middleware.KeyByUser(jwtService),
+			Skip: func(c echo.Context) bool {
+				return !strings.HasPrefix(c.Request().URL.Path, "/api/")
+			},
+		}),
+	}
+	workers = []worker.Worker{
+		worker.NewPollingWorker("data_export_worker", dataExportConfig.PollInterval, dataExportService.ProcessPending),
+		worker.NewPollingWorker("account_purge_worker", accountDeletionConfig.PurgeInterval, accountDeletionService.PurgeDue),
+	}
+	if rateLimitStore != nil {
+		workers = append(workers, worker.NewPollingWorker("rate_limit_cleanup_worker", rateLimitConfig.CleanupInterval, rateLimitStore.Cleanup))
+	}
+	return routers, middlewares, workers, nil
 }
@@ -0,0 +1,253 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type ShareRouter struct {
+	config              runtime.ServerConfig
+	shareService        service.ShareService
+	organizationService service.OrganizationService
+	jwtService          service.JWTService
+	userService         service.UserService
+	validator           *validator.Validate
+}
+
+func NewShareRouter(config runtime.ServerConfig, shareService service.ShareService, organizationService service.OrganizationService, jwtService service.JWTService, userService service.UserService) *ShareRouter {
+	return &ShareRouter{
+		config:              config,
+		shareService:        shareService,
+		organizationService: organizationService,
+		jwtService:          jwtService,
+		userService:         userService,
+		validator:           i18n.Validator(),
+	}
+}
+
+func (r *ShareRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	workspace := middleware.RequireWorkspace(r.organizationService)
+
+	e.GET("/api/"+r.config.APIVersion+"/categories/:id/shares", r.GetCategoryShares, jwt, workspace)
+	e.POST("/api/"+r.config.APIVersion+"/categories/:id/shares", r.ShareCategory, jwt, workspace)
+	e.DELETE("/api/"+r.config.APIVersion+"/categories/:id/shares", r.UnshareCategory, jwt, workspace)
+	e.GET("/api/"+r.config.APIVersion+"/todos/:id/shares", r.GetTodoShares, jwt, workspace)
+	e.POST("/api/"+r.config.APIVersion+"/todos/:id/shares", r.ShareTodo, jwt, workspace)
+	e.DELETE("/api/"+r.config.APIVersion+"/todos/:id/shares", r.UnshareTodo, jwt, workspace)
+}
+
+// GetCategoryShares godoc
+// @Summary List who a category is shared with
+// @Description List the users a category of the active workspace is shared with (owner only)
+// @Tags sharing
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Success 200 {array} response.ShareResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories/{id}/shares [get]
+func (r *ShareRouter) GetCategoryShares(c echo.Context) error {
+	return r.getShares(c, repository.ResourceTypeCategory)
+}
+
+// ShareCategory godoc
+// @Summary Share a category
+// @Description Share a category of the active workspace, and every todo in it, with a user by email. Sharing again changes the role. (owner only)
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param share body request.ShareRequest true "Email and role"
+// @Success 200 {object} response.ShareResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories/{id}/shares [post]
+func (r *ShareRouter) ShareCategory(c echo.Context) error {
+	return r.share(c, repository.ResourceTypeCategory)
+}
+
+// UnshareCategory godoc
+// @Summary Stop sharing a category
+// @Description Stop sharing a category of the active workspace with a user (owner only)
+// @Tags sharing
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param email query string true "Email of the user to stop sharing with"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /categories/{id}/shares [delete]
+func (r *ShareRouter) UnshareCategory(c echo.Context) error {
+	return r.unshare(c, repository.ResourceTypeCategory)
+}
+
+// GetTodoShares godoc
+// @Summary List who a todo is shared with
+// @Description List the users a todo of the active workspace is shared with directly (owner only)
+// @Tags sharing
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Todo ID"
+// @Success 200 {array} response.ShareResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos/{id}/shares [get]
+func (r *ShareRouter) GetTodoShares(c echo.Context) error {
+	return r.getShares(c, repository.ResourceTypeTodo)
+}
+
+// ShareTodo godoc
+// @Summary Share a todo
+// @Description Share a todo of the active workspace with a user by email. Sharing again changes the role. (owner only)
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Todo ID"
+// @Param share body request.ShareRequest true "Email and role"
+// @Success 200 {object} response.ShareResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos/{id}/shares [post]
+func (r *ShareRouter) ShareTodo(c echo.Context) error {
+	return r.share(c, repository.ResourceTypeTodo)
+}
+
+// UnshareTodo godoc
+// @Summary Stop sharing a todo
+// @Description Stop sharing a todo of the active workspace with a user (owner only)
+// @Tags sharing
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Todo ID"
+// @Param email query string true "Email of the user to stop sharing with"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos/{id}/shares [delete]
+func (r *ShareRouter) UnshareTodo(c echo.Context) error {
+	return r.unshare(c, repository.ResourceTypeTodo)
+}
+
+func (r *ShareRouter) getShares(c echo.Context, resourceType string) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	shares, err := r.shareService.GetShares(c.Request().Context(), ws, repository.Resource{Type: resourceType, Id: id})
+	if err != nil {
+		logger.Errorw("Failed to get shares", "resource", resourceType, "error", err)
+		return toShareApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, shares)
+}
+
+func (r *ShareRouter) share(c echo.Context, resourceType string) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.ShareRequest{}
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind share", "resource", resourceType, "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate share", "resource", resourceType, "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	share, err := r.shareService.Share(c.Request().Context(), ws, repository.Resource{Type: resourceType, Id: id}, req)
+	if err != nil {
+		logger.Errorw("Failed to share", "resource", resourceType, "error", err)
+		return toShareApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, share)
+}
+
+func (r *ShareRouter) unshare(c echo.Context, resourceType string) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.UnshareRequest{}
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind unshare", "resource", resourceType, "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate unshare", "resource", resourceType, "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	if err := r.shareService.Unshare(c.Request().Context(), ws, repository.Resource{Type: resourceType, Id: id}, req.Email); err != nil {
+		logger.Errorw("Failed to unshare", "resource", resourceType, "error", err)
+		return toShareApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func toShareApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrCategoryNotFound), errors.Is(err, service.ErrTodoNotFound),
+		errors.Is(err, service.ErrShareUserNotFound), errors.Is(err, service.ErrShareNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrShareForbidden):
+		return exception.ToApplicationError(err, exception.ErrorCodeForbidden)
+	case errors.Is(err, service.ErrShareWithOwner):
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
@@ -0,0 +1,300 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type TodoRouter struct {
+	config              runtime.ServerConfig
+	todoService         service.TodoService
+	organizationService service.OrganizationService
+	jwtService          service.JWTService
+	userService         service.UserService
+	validator           *validator.Validate
+}
+
+func NewTodoRouter(config runtime.ServerConfig, todoService service.TodoService, organizationService service.OrganizationService, jwtService service.JWTService, userService service.UserService) *TodoRouter {
+	return &TodoRouter{
+		config:              config,
+		todoService:         todoService,
+		organizationService: organizationService,
+		jwtService:          jwtService,
+		userService:         userService,
+		validator:           i18n.Validator(),
+	}
+}
+
+func (r *TodoRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	workspace := middleware.RequireWorkspace(r.organizationService)
+
+	e.GET("/api/"+r.config.APIVersion+"/todos", r.GetTodos, jwt, workspace)
+	e.POST("/api/"+r.config.APIVersion+"/todos", r.CreateTodo, jwt, workspace)
+	e.GET("/api/"+r.config.APIVersion+"/todos/:id", r.GetTodoById, jwt, workspace)
+	e.PUT("/api/"+r.config.APIVersion+"/todos/:id", r.UpdateTodo, jwt, workspace)
+	e.DELETE("/api/"+r.config.APIVersion+"/todos/:id", r.DeleteTodo, jwt, workspace)
+	e.GET("/api/"+r.config.APIVersion+"/shared/todos", r.GetSharedTodos, jwt)
+}
+
+// GetTodos godoc
+// @Summary List todos
+// @Description List the todos of the active workspace, newest first
+// @Tags todos
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param cursor query string false "nextCursor or prevCursor from a previous page; replaces page"
+// @Param include_total query bool false "Count the exact total (default true for pages, false for cursors)"
+// @Param status query string false "Only todos with this status: pending, in_progress, completed or cancelled"
+// @Param category_id query string false "Only todos in this category"
+// @Success 200 {object} response.ListResponse[response.TodoResponse]
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos [get]
+func (r *TodoRouter) GetTodos(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	listReq := request.NewTodoListRequest()
+	if err := c.Bind(&listReq); err != nil {
+		logger.Errorw("Failed to bind todo list parameters", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(listReq); err != nil {
+		logger.Errorw("Failed to validate todo list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+	if _, err := listReq.GetCursor(); err != nil {
+		logger.Errorw("Failed to validate todo list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	todos, err := r.todoService.GetTodos(c.Request().Context(), ws, listReq)
+	if err != nil {
+		logger.Errorw("Failed to get todos", "error", err)
+		return toTodoApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+// CreateTodo godoc
+// @Summary Create a todo
+// @Description Create a todo in the active workspace. A todo put in a category shared with you as editor belongs to the category's owner.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param todo body request.TodoRequest true "Todo"
+// @Success 201 {object} response.TodoResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos [post]
+func (r *TodoRouter) CreateTodo(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+	req := request.TodoRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind todo", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate todo", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	todo, err := r.todoService.CreateTodo(c.Request().Context(), ws, req)
+	if err != nil {
+		logger.Errorw("Failed to create todo", "error", err)
+		return toTodoApplicationError(err)
+	}
+
+	return c.JSON(http.StatusCreated, todo)
+}
+
+// GetTodoById godoc
+// @Summary Get a todo
+// @Description Get a todo of the active workspace or one shared with you, directly or through its category
+// @Tags todos
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Todo ID"
+// @Success 200 {object} response.TodoResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos/{id} [get]
+func (r *TodoRouter) GetTodoById(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	todo, err := r.todoService.GetTodoById(c.Request().Context(), ws, id)
+	if err != nil {
+		logger.Errorw("Failed to get todo", "error", err)
+		return toTodoApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, todo)
+}
+
+// UpdateTodo godoc
+// @Summary Update a todo
+// @Description Replace the fields of a todo in the active workspace or shared with you as editor. The category must belong to the todo's owner. Setting the status to completed records completed_at; any other status clears it.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Todo ID"
+// @Param todo body request.TodoRequest true "Todo"
+// @Success 200 {object} response.TodoResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos/{id} [put]
+func (r *TodoRouter) UpdateTodo(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	req := request.TodoRequest{}
+	if err := c.Bind(&req); err != nil {
+		logger.Errorw("Failed to bind todo", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(req); err != nil {
+		logger.Errorw("Failed to validate todo", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	todo, err := r.todoService.UpdateTodo(c.Request().Context(), ws, id, req)
+	if err != nil {
+		logger.Errorw("Failed to update todo", "error", err)
+		return toTodoApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, todo)
+}
+
+// DeleteTodo godoc
+// @Summary Delete a todo
+// @Description Delete a todo of the active workspace, or one in a category shared with you as editor
+// @Tags todos
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Todo ID"
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /todos/{id} [delete]
+func (r *TodoRouter) DeleteTodo(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	ws := c.Get("workspace").(repository.Workspace)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.todoService.DeleteTodo(c.Request().Context(), ws, id); err != nil {
+		logger.Errorw("Failed to delete todo", "error", err)
+		return toTodoApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetSharedTodos godoc
+// @Summary List todos shared with me
+// @Description List the todos other users shared with you, directly or through a shared category, newest first, with your access (viewer or editor)
+// @Tags sharing
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param cursor query string false "nextCursor or prevCursor from a previous page; replaces page"
+// @Param include_total query bool false "Count the exact total (default true for pages, false for cursors)"
+// @Param status query string false "Only todos with this status: pending, in_progress, completed or cancelled"
+// @Param category_id query string false "Only todos in this category"
+// @Success 200 {object} response.ListResponse[response.TodoResponse]
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /shared/todos [get]
+func (r *TodoRouter) GetSharedTodos(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	userID := c.Get("userID").(uuid.UUID)
+
+	listReq := request.NewTodoListRequest()
+	if err := c.Bind(&listReq); err != nil {
+		logger.Errorw("Failed to bind shared todo list parameters", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(listReq); err != nil {
+		logger.Errorw("Failed to validate shared todo list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+	if _, err := listReq.GetCursor(); err != nil {
+		logger.Errorw("Failed to validate shared todo list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	todos, err := r.todoService.GetSharedTodos(c.Request().Context(), userID, listReq)
+	if err != nil {
+		logger.Errorw("Failed to get shared todos", "error", err)
+		return toTodoApplicationError(err)
+	}
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+func toTodoApplicationError(err error) *exception.ApplicationError {
+	switch {
+	case errors.Is(err, service.ErrTodoNotFound):
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	case errors.Is(err, service.ErrTodoForbidden):
+		return exception.ToApplicationError(err, exception.ErrorCodeForbidden)
+	case errors.Is(err, service.ErrTodoCategoryInvalid):
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
@@ -0,0 +1,128 @@
+package route
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/service"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+type UserImportRouter struct {
+	config            runtime.ServerConfig
+	importConfig      runtime.UserImportConfig
+	userImportService service.UserImportService
+	jwtService        service.JWTService
+	userService       service.UserService
+}
+
+func NewUserImportRouter(config runtime.ServerConfig, importConfig runtime.UserImportConfig, userImportService service.UserImportService, jwtService service.JWTService, userService service.UserService) *UserImportRouter {
+	return &UserImportRouter{
+		config:            config,
+		importConfig:      importConfig,
+		userImportService: userImportService,
+		jwtService:        jwtService,
+		userService:       userService,
+	}
+}
+
+func (r *UserImportRouter) Configure(e *echo.Echo) {
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	admin := middleware.RequireRole(repository.RoleAdmin)
+
+	e.POST("/api/"+r.config.APIVersion+"/users/import", r.ImportUsers, jwt, admin)
+}
+
+// ImportUsers godoc
+// @Summary Import users from CSV
+// @Description Create pending users from a CSV file whose header row names a name and an email column. Every row is validated like POST /users; rows repeating an earlier email or matching an existing user are rejected. Accepted rows are created together in one transaction and the report lists the outcome of each row. (admin only)
+// @Tags users
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file false "CSV file, when sent as multipart/form-data"
+// @Param dry_run query bool false "Only validate and report, create nobody"
+// @Param send_invitations query bool false "Email every created user an invite link"
+// @Success 200 {object} response.UserImportResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 413 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/import [post]
+func (r *UserImportRouter) ImportUsers(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+	inviterID := c.Get("userID").(uuid.UUID)
+
+	req := request.UserImportRequest{}
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &req); err != nil {
+		logger.Errorw("Failed to bind user import parameters", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	// Stop reading oversized files early instead of buffering them.
+	httpReq := c.Request()
+	httpReq.Body = http.MaxBytesReader(c.Response(), httpReq.Body, r.importConfig.MaxBytes+multipartOverhead)
+
+	var file io.Reader = httpReq.Body
+	if strings.HasPrefix(httpReq.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			logger.Errorw("Failed to read user import upload", "error", err)
+			return toUserImportApplicationError(err)
+		}
+		upload, err := header.Open()
+		if err != nil {
+			return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+		}
+		defer upload.Close()
+		file = upload
+	}
+
+	report, err := r.userImportService.ImportUsers(c.Request().Context(), &inviterID, file, req)
+	if err != nil {
+		logger.Errorw("Failed to import users", "error", err)
+		return toUserImportApplicationError(err)
+	}
+
+	logger.Infow("Imported users", "dry_run", report.DryRun, "total", report.Total, "created", report.Created, "failed", report.Failed)
+
+	// Row errors are reported in the client's language, like error responses
+	trans := i18n.Translator(c.Request().Header.Get("Accept-Language"))
+	for i := range report.Rows {
+		report.Rows[i].Errors = exception.LocalizeDetails(report.Rows[i].Errors, trans)
+	}
+	c.Response().Header().Set("Content-Language", trans.Locale())
+	return c.JSON(http.StatusOK, report)
+}
+
+func toUserImportApplicationError(err error) *exception.ApplicationError {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodePayloadTooLarge,
+			Message:   "The file is too large",
+			MessageID: "file_too_large",
+			Details:   []exception.ErrorDetail{},
+		}
+	case errors.Is(err, service.ErrImportTooManyRows):
+		return exception.ToApplicationError(err, exception.ErrorCodePayloadTooLarge)
+	case errors.Is(err, service.ErrImportEmpty), errors.Is(err, service.ErrImportMissingColumns),
+		errors.Is(err, service.ErrImportMalformed), errors.Is(err, http.ErrMissingFile):
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	default:
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+	}
+}
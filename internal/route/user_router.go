@@ -1,16 +1,24 @@
 package route
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/lamkn06/user-app-golang.git/internal/middleware"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
 	"github.com/lamkn06/user-app-golang.git/internal/runtime"
 	"github.com/lamkn06/user-app-golang.git/internal/service"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
 	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
 	"github.com/lamkn06/user-app-golang.git/pkg/logging"
 )
 
@@ -22,115 +30,386 @@ type UserRouter struct {
 }
 
 func NewUserRouter(config runtime.ServerConfig, userService service.UserService, jwtService service.JWTService) *UserRouter {
-	return &UserRouter{config: config, userService: userService, jwtService: jwtService, validator: validator.New()}
+	return &UserRouter{config: config, userService: userService, jwtService: jwtService, validator: i18n.Validator()}
 }
 
 func (r *UserRouter) Configure(e *echo.Echo) {
-	e.GET("/api/"+r.config.APIVersion+"/users", r.GetUsers)
-	e.POST("/api/"+r.config.APIVersion+"/users", r.CreateUser)
-	e.GET("/api/"+r.config.APIVersion+"/users/:id", r.GetUserById, middleware.JWTMiddleware(r.jwtService))
+	jwt := middleware.JWTMiddleware(r.jwtService, r.userService)
+	admin := middleware.RequireRole(repository.RoleAdmin)
+
+	e.GET("/api/"+r.config.APIVersion+"/users", r.GetUsers, jwt, admin)
+
+	e.GET("/api/"+r.config.APIVersion+"/users/:id", r.GetUserById, jwt)
+	e.PATCH("/api/"+r.config.APIVersion+"/users/:id", r.UpdateUser, jwt)
+	e.DELETE("/api/"+r.config.APIVersion+"/users/:id", r.DeleteUser, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/users/:id/deactivate", r.DeactivateUser, jwt)
+	e.POST("/api/"+r.config.APIVersion+"/users/:id/reactivate", r.ReactivateUser, jwt, admin)
+	e.POST("/api/"+r.config.APIVersion+"/users/:id/restore", r.RestoreUser, jwt, admin)
 }
 
 // GetUsers godoc
-// @Summary Get all users with pagination
-// @Description Get all users from the database with pagination support
+// @Summary Search users
+// @Description Search, filter and sort users with pagination support (admin only)
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param cursor query string false "nextCursor or prevCursor from a previous page; replaces page"
+// @Param include_total query bool false "Count the exact total (default true for pages, false for cursors)"
+// @Param q query string false "Substring of the name or email"
+// @Param active query bool false "Only active (true) or deactivated (false) users"
+// @Param verified query bool false "Only users with (true) or without (false) a verified email"
+// @Param deleted query string false "Soft-deleted users: exclude, include or only" default(exclude)
+// @Param created_after query string false "Created at or after (RFC 3339)"
+// @Param created_before query string false "Created before (RFC 3339)"
+// @Param sort query string false "Comma-separated fields, prefix with - for descending: name, email, created_at, updated_at" default(-created_at)
 // @Success 200 {object} response.ListResponse[response.NewUserResponse]
 // @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
 // @Failure 500 {object} exception.ApplicationError
 // @Router /users [get]
 func (r *UserRouter) GetUsers(c echo.Context) error {
 	logger := logging.LoggerFromContext(c.Request().Context())
 
-	// Parse pagination parameters with defaults
-	listReq := request.NewListRequest()
+	// Parse query parameters with defaults
+	listReq := request.NewUserListRequest()
 	if err := c.Bind(&listReq); err != nil {
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
-		logger.Errorw("Failed to bind pagination parameters", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		logger.Errorw("Failed to bind user list parameters", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 	}
 
-	// Validate pagination parameters
+	// Validate query parameters
 	if err := r.validator.Struct(listReq); err != nil {
-		appErr := middleware.ParseValidationError(err)
-		logger.Errorw("Failed to validate pagination parameters", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		logger.Errorw("Failed to validate user list parameters", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+	if err := listReq.Validate(); err != nil {
+		logger.Errorw("Failed to validate user list parameters", "error", err)
+		return middleware.ParseValidationError(err)
 	}
 
 	// Get users with pagination
-	users, err := r.userService.GetUsers(listReq)
+	users, err := r.userService.GetUsers(c.Request().Context(), listReq)
 	if err != nil {
 		logger.Errorw("Failed to get users", "error", err)
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
 	}
 
 	return c.JSON(http.StatusOK, users)
 }
 
-// CreateUser godoc
-// @Summary Create a new user
-// @Description Create a new user with name and email
+// GetUserById godoc
+// @Summary Get user by ID
+// @Description Get a specific user by their ID
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param user body request.NewUserRequest true "User information"
+// @Param id path string true "User ID"
+// @Security BearerAuth
 // @Success 200 {object} response.NewUserResponse
 // @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
 // @Failure 500 {object} exception.ApplicationError
-// @Router /users [post]
-func (r *UserRouter) CreateUser(c echo.Context) error {
+// @Router /users/{id} [get]
+func (r *UserRouter) GetUserById(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	user, err := r.userService.GetUserById(c.Request().Context(), id)
+	if err != nil {
+		return toUserApplicationError(err)
+	}
+	c.Response().Header().Set("ETag", user.ETag())
+	return c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser godoc
+// @Summary Partially update a user
+// @Description Apply a JSON Merge Patch (RFC 7396) to a user. The If-Match header must carry the ETag returned by a previous read. The email cannot be changed here; use POST /users/me/email.
+// @Tags users
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param If-Match header string true "ETag of the version being modified"
+// @Param user body request.NewUserRequest true "Fields to change; null removes a field"
+// @Security BearerAuth
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 412 {object} exception.ApplicationError
+// @Failure 415 {object} exception.ApplicationError
+// @Failure 428 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id} [patch]
+func (r *UserRouter) UpdateUser(c echo.Context) error {
 	logger := logging.LoggerFromContext(c.Request().Context())
-	user := request.NewUserRequest{}
 
-	if err := c.Bind(&user); err != nil {
-		appErr := exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
-		logger.Errorw("Failed to bind user", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if !isSelfOrAdmin(c, id) {
+		return forbiddenError()
 	}
 
-	if err := r.validator.Struct(user); err != nil {
-		appErr := middleware.ParseValidationError(err)
-		logger.Errorw("Failed to validate user", "error", err)
-		return c.JSON(appErr.HTTPStatus(), appErr)
+	if contentType := c.Request().Header.Get(echo.HeaderContentType); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != echo.MIMEApplicationJSON) {
+			return &exception.ApplicationError{
+				Code:      exception.ErrorCodeUnsupportedMediaType,
+				Message:   "Content-Type must be application/merge-patch+json or application/json",
+				MessageID: "merge_patch_content_type",
+				Details:   []exception.ErrorDetail{},
+			}
+		}
+	}
+
+	ifMatch := c.Request().Header.Get("If-Match")
+	if ifMatch == "" {
+		return &exception.ApplicationError{
+			Code:      exception.ErrorCodePreconditionRequired,
+			Message:   "If-Match header required",
+			MessageID: "if_match_required",
+			Details:   []exception.ErrorDetail{},
+		}
 	}
 
-	newUser, err := r.userService.NewUser(user)
+	current, err := r.userService.GetUserById(c.Request().Context(), id)
+	if err != nil {
+		logger.Errorw("Failed to get user", "error", err)
+		code := exception.ErrorCodeInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			code = exception.ErrorCodeNotFound
+		}
+		return exception.ToApplicationError(err, code)
+	}
+
+	if !etagMatches(ifMatch, current.ETag()) {
+		return staleUserError()
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		logger.Errorw("Failed to read user patch", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	document, err := json.Marshal(request.NewUserRequest{Name: current.Name, Email: current.Email})
 	if err != nil {
-		logger.Errorw("Failed to create user", "error", err)
 		return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, newUser)
+	patched, err := request.ApplyMergePatch(document, patch)
+	if err != nil {
+		logger.Errorw("Failed to apply user patch", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	user := request.NewUserRequest{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&user); err != nil {
+		logger.Errorw("Failed to bind user patch", "error", err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if err := r.validator.Struct(user); err != nil {
+		logger.Errorw("Failed to validate user patch", "error", err)
+		return middleware.ParseValidationError(err)
+	}
+
+	updated, err := r.userService.UpdateUser(c.Request().Context(), id, current.UpdatedAt, user)
+	if err != nil {
+		logger.Errorw("Failed to update user", "error", err)
+		switch {
+		case errors.Is(err, service.ErrUserVersionMismatch):
+			return staleUserError()
+		case errors.Is(err, service.ErrUserNotFound):
+			return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+		case errors.Is(err, service.ErrEmailChangeNotAllowed):
+			return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+		default:
+			return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+		}
+	}
+
+	c.Response().Header().Set("ETag", updated.ETag())
+	return c.JSON(http.StatusOK, updated)
 }
 
-// GetUserById godoc
-// @Summary Get user by ID
-// @Description Get a specific user by their ID
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description Soft-delete a user. Users can delete themselves; admins can delete anyone.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id} [delete]
+func (r *UserRouter) DeleteUser(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if !isSelfOrAdmin(c, id) {
+		return forbiddenError()
+	}
+
+	if err := r.userService.DeleteUser(c.Request().Context(), id); err != nil {
+		logger.Errorw("Failed to delete user", "error", err)
+		return toUserApplicationError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeactivateUser godoc
+// @Summary Deactivate a user
+// @Description Block a user from signing in. Users can deactivate themselves; admins can deactivate anyone.
 // @Tags users
-// @Accept json
 // @Produce json
 // @Param id path string true "User ID"
 // @Security BearerAuth
 // @Success 200 {object} response.NewUserResponse
 // @Failure 400 {object} exception.ApplicationError
 // @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
 // @Failure 500 {object} exception.ApplicationError
-// @Router /users/{id} [get]
-func (r *UserRouter) GetUserById(c echo.Context) error {
-	id := c.Param("id")
-	idUUID, err := uuid.Parse(id)
+// @Router /users/{id}/deactivate [post]
+func (r *UserRouter) DeactivateUser(c echo.Context) error {
+	return r.setUserActive(c, false)
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate a user
+// @Description Allow a deactivated user to sign in again. Admin only.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/reactivate [post]
+func (r *UserRouter) ReactivateUser(c echo.Context) error {
+	return r.setUserActive(c, true)
+}
+
+func (r *UserRouter) setUserActive(c echo.Context, active bool) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
+	}
+
+	if !isSelfOrAdmin(c, id) {
+		return forbiddenError()
+	}
+
+	user, err := r.userService.SetUserActive(c.Request().Context(), id, active)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err)
+		logger.Errorw("Failed to change user active state", "error", err, "active", active)
+		return toUserApplicationError(err)
 	}
 
-	user, err := r.userService.GetUserById(idUUID)
+	return c.JSON(http.StatusOK, user)
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Undo a soft delete. Admin only.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} response.NewUserResponse
+// @Failure 400 {object} exception.ApplicationError
+// @Failure 401 {object} exception.ApplicationError
+// @Failure 403 {object} exception.ApplicationError
+// @Failure 404 {object} exception.ApplicationError
+// @Failure 500 {object} exception.ApplicationError
+// @Router /users/{id}/restore [post]
+func (r *UserRouter) RestoreUser(c echo.Context) error {
+	logger := logging.LoggerFromContext(c.Request().Context())
+
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return exception.ToApplicationError(err, exception.ErrorCodeBadRequest)
 	}
+
+	user, err := r.userService.RestoreUser(c.Request().Context(), id)
+	if err != nil {
+		logger.Errorw("Failed to restore user", "error", err)
+		return toUserApplicationError(err)
+	}
+
 	return c.JSON(http.StatusOK, user)
 }
+
+// isSelfOrAdmin reports whether the authenticated user is the user with the
+// given id or an admin.
+func isSelfOrAdmin(c echo.Context, id uuid.UUID) bool {
+	userID, _ := c.Get("userID").(uuid.UUID)
+	role, _ := c.Get("userRole").(string)
+	return userID == id || role == repository.RoleAdmin
+}
+
+func forbiddenError() *exception.ApplicationError {
+	return &exception.ApplicationError{
+		Code:      exception.ErrorCodeForbidden,
+		Message:   "Insufficient permissions",
+		MessageID: "insufficient_permissions",
+		Details:   []exception.ErrorDetail{},
+	}
+}
+
+func toUserApplicationError(err error) *exception.ApplicationError {
+	if errors.Is(err, service.ErrUserNotFound) {
+		return exception.ToApplicationError(err, exception.ErrorCodeNotFound)
+	}
+	return exception.ToApplicationError(err, exception.ErrorCodeInternalServerError)
+}
+
+func staleUserError() *exception.ApplicationError {
+	return &exception.ApplicationError{
+		Code:      exception.ErrorCodePreconditionFailed,
+		Message:   "User has been modified since it was read",
+		MessageID: "user_version_mismatch",
+		Details:   []exception.ErrorDetail{},
+	}
+}
+
+// etagMatches reports whether an If-Match header matches etag using the strong
+// comparison required by RFC 9110; weak validators never match.
+func etagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package runtime
+
+import "time"
+
+type AccountDeletionConfig struct {
+	// GracePeriod is how long a deletion request can be cancelled by signing in.
+	GracePeriod   time.Duration `env:"ACCOUNT_DELETION_GRACE_PERIOD" envDefault:"720h"`
+	PurgeInterval time.Duration `env:"ACCOUNT_PURGE_INTERVAL" envDefault:"1m"`
+	// PurgeBatchSize is how many rows are deleted per statement while purging.
+	PurgeBatchSize int `env:"ACCOUNT_PURGE_BATCH_SIZE" envDefault:"500"`
+	// StaleAfter is how long a purge may go without progress before another worker resumes it.
+	StaleAfter time.Duration `env:"ACCOUNT_PURGE_STALE_AFTER" envDefault:"15m"`
+}
@@ -0,0 +1,7 @@
+package runtime
+
+type AvatarConfig struct {
+	MaxBytes int64 `env:"AVATAR_MAX_BYTES" envDefault:"5242880"`
+	// Sizes are the square thumbnail edges, in pixels, generated for every upload.
+	Sizes []int `env:"AVATAR_SIZES" envDefault:"64,128,256" envSeparator:","`
+}
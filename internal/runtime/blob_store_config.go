@@ -0,0 +1,17 @@
+package runtime
+
+type BlobStoreConfig struct {
+	Driver string `env:"BLOB_STORE_DRIVER" envDefault:"local"`
+	// PublicURL is the base URL blobs are served from. The API serves them
+	// itself under /media, which works with every driver.
+	PublicURL string `env:"BLOB_PUBLIC_URL" envDefault:"http://localhost:8080/media"`
+
+	LocalDir string `env:"BLOB_LOCAL_DIR" envDefault:"./data/blobs"`
+
+	S3Endpoint  string `env:"S3_ENDPOINT" envDefault:"localhost:9000"`
+	S3Region    string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket    string `env:"S3_BUCKET" envDefault:"user-app"`
+	S3AccessKey string `env:"S3_ACCESS_KEY"`
+	S3SecretKey string `env:"S3_SECRET_KEY"`
+	S3UseSSL    bool   `env:"S3_USE_SSL" envDefault:"false"`
+}
@@ -0,0 +1,11 @@
+package runtime
+
+import "time"
+
+type DataExportConfig struct {
+	// TTL is how long a finished export can be downloaded before it is deleted.
+	TTL          time.Duration `env:"DATA_EXPORT_TTL" envDefault:"72h"`
+	PollInterval time.Duration `env:"DATA_EXPORT_POLL_INTERVAL" envDefault:"10s"`
+	// StaleAfter is how long an export may stay in processing before another worker retries it.
+	StaleAfter time.Duration `env:"DATA_EXPORT_STALE_AFTER" envDefault:"15m"`
+}
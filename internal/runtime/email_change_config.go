@@ -0,0 +1,13 @@
+package runtime
+
+import "time"
+
+type EmailChangeConfig struct {
+	// ConfirmURL is the front-end page opened from the link sent to the new address.
+	ConfirmURL string        `env:"EMAIL_CHANGE_CONFIRM_URL" envDefault:"http://localhost:3000/account/email/confirm"`
+	TTL        time.Duration `env:"EMAIL_CHANGE_TTL" envDefault:"24h"`
+	// RevertURL is the front-end page opened from the link sent to the old address.
+	RevertURL string `env:"EMAIL_CHANGE_REVERT_URL" envDefault:"http://localhost:3000/account/email/revert"`
+	// RevertWindow is how long the old address can undo a completed change.
+	RevertWindow time.Duration `env:"EMAIL_CHANGE_REVERT_WINDOW" envDefault:"168h"`
+}
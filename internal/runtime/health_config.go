@@ -0,0 +1,8 @@
+package runtime
+
+import "time"
+
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check, such as the database ping.
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+}
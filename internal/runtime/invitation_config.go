@@ -0,0 +1,11 @@
+package runtime
+
+import "time"
+
+type InvitationConfig struct {
+	// URL is the front-end page where an invited user sets a password.
+	URL string        `env:"INVITATION_URL" envDefault:"http://localhost:3000/invitations/accept"`
+	TTL time.Duration `env:"INVITATION_TTL" envDefault:"168h"`
+	// OrganizationURL is the front-end page where a signed-in user joins an organization.
+	OrganizationURL string `env:"ORGANIZATION_INVITATION_URL" envDefault:"http://localhost:3000/organizations/join"`
+}
@@ -0,0 +1,19 @@
+package runtime
+
+type LoggingConfig struct {
+	// Level is the minimum level logged; empty means debug in development and
+	// info elsewhere.
+	Level string `env:"LOG_LEVEL"`
+	// Outside development, after SamplingInitial identical entries in a second
+	// only every SamplingThereafter-th is logged. Zero disables sampling.
+	SamplingInitial    int `env:"LOG_SAMPLING_INITIAL" envDefault:"100"`
+	SamplingThereafter int `env:"LOG_SAMPLING_THEREAFTER" envDefault:"100"`
+	// Redact masks passwords, tokens and connection strings in log output.
+	Redact bool `env:"LOG_REDACT" envDefault:"true"`
+	// File, when set, also writes JSON logs to this path, rotated by size.
+	File           string `env:"LOG_FILE"`
+	FileMaxSizeMB  int    `env:"LOG_FILE_MAX_SIZE_MB" envDefault:"100"`
+	FileMaxBackups int    `env:"LOG_FILE_MAX_BACKUPS" envDefault:"10"`
+	FileMaxAgeDays int    `env:"LOG_FILE_MAX_AGE_DAYS" envDefault:"30"`
+	FileCompress   bool   `env:"LOG_FILE_COMPRESS" envDefault:"true"`
+}
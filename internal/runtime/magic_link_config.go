@@ -0,0 +1,11 @@
+package runtime
+
+import "time"
+
+type MagicLinkConfig struct {
+	// URL is the front-end page that receives the token and calls the consume endpoint.
+	URL string        `env:"MAGIC_LINK_URL" envDefault:"http://localhost:3000/auth/magic-link"`
+	TTL time.Duration `env:"MAGIC_LINK_TTL" envDefault:"15m"`
+	// AllowSignUp lets a magic link for an unknown address create an account.
+	AllowSignUp bool `env:"MAGIC_LINK_ALLOW_SIGNUP" envDefault:"false"`
+}
@@ -0,0 +1,10 @@
+package runtime
+
+type MailerConfig struct {
+	Driver       string `env:"MAILER_DRIVER" envDefault:"log"` // log, smtp
+	From         string `env:"MAIL_FROM" envDefault:"no-reply@localhost"`
+	SMTPHost     string `env:"SMTP_HOST" envDefault:"localhost"`
+	SMTPPort     int    `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME" envDefault:""`
+	SMTPPassword string `env:"SMTP_PASSWORD" envDefault:""`
+}
@@ -0,0 +1,9 @@
+package runtime
+
+type MetricsConfig struct {
+	Enabled bool   `env:"METRICS_ENABLED" envDefault:"true"`
+	Path    string `env:"METRICS_PATH" envDefault:"/metrics"`
+	// Port is the admin port the metrics are served on. They are never
+	// served on the public API port.
+	Port string `env:"METRICS_PORT" envDefault:"9090"`
+}
@@ -0,0 +1,17 @@
+package runtime
+
+import "time"
+
+type RateLimitConfig struct {
+	Enabled bool `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	// Backend keeps the buckets: "memory" per replica, or "postgres" shared by
+	// all replicas.
+	Backend string `env:"RATE_LIMIT_BACKEND" envDefault:"memory"`
+	// Limits are written as <requests>/<period>. Default applies to every API
+	// request per user, or per client IP before signing in; Auth applies per
+	// client IP to sign-up, sign-in and magic links.
+	Default string `env:"RATE_LIMIT_DEFAULT" envDefault:"300/1m"`
+	Auth    string `env:"RATE_LIMIT_AUTH" envDefault:"10/1m"`
+	// CleanupInterval is how often buckets that have refilled are dropped.
+	CleanupInterval time.Duration `env:"RATE_LIMIT_CLEANUP_INTERVAL" envDefault:"5m"`
+}
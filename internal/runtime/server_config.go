@@ -4,4 +4,6 @@ type ServerConfig struct {
 	Port        string `env:"PORT" envDefault:"8080"`
 	Environment string `env:"ENVIRONMENT" envDefault:"development"`
 	APIVersion  string `env:"API_VERSION" envDefault:"v1"`
+	// PublicURL is the externally reachable base URL, used to build links in emails.
+	PublicURL string `env:"PUBLIC_URL" envDefault:"http://localhost:8080"`
 }
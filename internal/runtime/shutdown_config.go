@@ -0,0 +1,11 @@
+package runtime
+
+import "time"
+
+type ShutdownConfig struct {
+	// Timeout bounds the whole shutdown, including the drain delay.
+	Timeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	// DrainDelay is how long readiness fails before the server stops
+	// accepting connections, so load balancers stop sending new requests.
+	DrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
+}
@@ -0,0 +1,12 @@
+package runtime
+
+import "time"
+
+type TimeoutConfig struct {
+	// Request is the deadline of every API request. Queries run in the
+	// request context, so Postgres gets the time left as statement_timeout.
+	Request time.Duration `env:"REQUEST_TIMEOUT" envDefault:"15s"`
+	// Transfer replaces Request for the routes that upload or download files:
+	// avatar uploads, user imports and export downloads.
+	Transfer time.Duration `env:"REQUEST_TIMEOUT_TRANSFER" envDefault:"2m"`
+}
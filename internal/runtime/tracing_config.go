@@ -0,0 +1,14 @@
+package runtime
+
+type TracingConfig struct {
+	// Exporter sends finished spans to "otlp", prints them with "stdout", or
+	// drops them with "none". Incoming trace context is propagated either way.
+	Exporter    string `env:"TRACING_EXPORTER" envDefault:"none"`
+	ServiceName string `env:"TRACING_SERVICE_NAME" envDefault:"user-app"`
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector.
+	OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT" envDefault:"localhost:4318"`
+	OTLPInsecure bool   `env:"TRACING_OTLP_INSECURE" envDefault:"true"`
+	// SampleRatio is the share of new traces recorded; traces started
+	// upstream follow the caller's sampling decision.
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+}
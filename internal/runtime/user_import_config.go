@@ -0,0 +1,10 @@
+package runtime
+
+type UserImportConfig struct {
+	// MaxBytes limits the size of an uploaded CSV file.
+	MaxBytes int64 `env:"USER_IMPORT_MAX_BYTES" envDefault:"5242880"`
+	// MaxRows limits the number of users in one import.
+	MaxRows int `env:"USER_IMPORT_MAX_ROWS" envDefault:"5000"`
+	// BatchSize is the number of users written per INSERT statement.
+	BatchSize int `env:"USER_IMPORT_BATCH_SIZE" envDefault:"500"`
+}
@@ -0,0 +1,13 @@
+package runtime
+
+import "time"
+
+type WebAuthnConfig struct {
+	RPID           string        `env:"WEBAUTHN_RP_ID" envDefault:"localhost"`
+	RPDisplayName  string        `env:"WEBAUTHN_RP_DISPLAY_NAME" envDefault:"User App"`
+	RPOrigins      []string      `env:"WEBAUTHN_RP_ORIGINS" envDefault:"http://localhost:8080" envSeparator:","`
+	SessionTimeout time.Duration `env:"WEBAUTHN_SESSION_TIMEOUT" envDefault:"5m"`
+	// DecoySecret keys the decoy credentials offered to addresses without
+	// passkeys. Instances behind one load balancer must share it.
+	DecoySecret string `env:"WEBAUTHN_DECOY_SECRET"`
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+var (
+	ErrAccountDeletionNotFound = exception.NewError("account_deletion_not_found", "account deletion not found")
+	ErrSoleOrganizationOwner   = exception.NewError("sole_organization_owner", "transfer ownership of the organizations you alone own, or delete them, before deleting your account")
+)
+
+// accountDeletionFailedReason is what the deletion record shows of a failed
+// purge attempt; the cause is only logged, since it can name internal paths
+// and hosts.
+const accountDeletionFailedReason = "The last purge attempt failed and will be retried."
+
+// AccountDeletionService schedules accounts for permanent erasure. A deletion
+// can be cancelled until its grace period ends; after that PurgeDue removes
+// the user and everything they own.
+type AccountDeletionService interface {
+	RequestDeletion(ctx context.Context, userID uuid.UUID) (response.AccountDeletionResponse, error)
+	GetDeletion(ctx context.Context, userID uuid.UUID) (response.AccountDeletionResponse, error)
+	GetDeletionById(ctx context.Context, id uuid.UUID) (response.AccountDeletionResponse, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+	CancelOnSignIn(ctx context.Context, userID uuid.UUID) error
+	PurgeDue(ctx context.Context) error
+}
+
+type DefaultAccountDeletionService struct {
+	config                    runtime.AccountDeletionConfig
+	accountDeletionRepository repository.AccountDeletionRepository
+	userRepository            repository.UserRepository
+	organizationRepository    repository.OrganizationRepository
+	avatarService             AvatarService
+	dataExportService         DataExportService
+	mailer                    mailer.Mailer
+}
+
+func NewAccountDeletionService(config runtime.AccountDeletionConfig, accountDeletionRepository repository.AccountDeletionRepository, userRepository repository.UserRepository, organizationRepository repository.OrganizationRepository, avatarService AvatarService, dataExportService DataExportService, mailer mailer.Mailer) AccountDeletionService {
+	return &DefaultAccountDeletionService{
+		config:                    config,
+		accountDeletionRepository: accountDeletionRepository,
+		userRepository:            userRepository,
+		organizationRepository:    organizationRepository,
+		avatarService:             avatarService,
+		dataExportService:         dataExportService,
+		mailer:                    mailer,
+	}
+}
+
+// RequestDeletion schedules the user's account for deletion after the grace
+// period. If a deletion is already open it is returned unchanged. Users who
+// are the only owner of an organization must hand it over or delete it first.
+func (s *DefaultAccountDeletionService) RequestDeletion(ctx context.Context, userID uuid.UUID) (resp response.AccountDeletionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.RequestDeletion")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return response.AccountDeletionResponse{}, ErrUserNotFound
+		}
+		return response.AccountDeletionResponse{}, err
+	}
+
+	open, err := s.accountDeletionRepository.GetOpenDeletionByUserId(ctx, userID)
+	if err == nil {
+		return toAccountDeletionResponse(open), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return response.AccountDeletionResponse{}, err
+	}
+
+	owned, err := s.organizationRepository.GetSoleOwnedOrganizations(ctx, userID)
+	if err != nil {
+		return response.AccountDeletionResponse{}, err
+	}
+	if len(owned) > 0 {
+		return response.AccountDeletionResponse{}, ErrSoleOrganizationOwner
+	}
+
+	now := time.Now()
+	deletion, err := s.accountDeletionRepository.InsertDeletion(ctx, repository.AccountDeletionEntity{
+		Id:           uuid.New(),
+		UserId:       userID,
+		Status:       repository.AccountDeletionStatusScheduled,
+		ScheduledFor: now.Add(s.config.GracePeriod),
+		RequestedAt:  now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		return response.AccountDeletionResponse{}, err
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: "Your account and all of its data will be permanently deleted on " +
+			deletion.ScheduledFor.UTC().Format(time.RFC1123) + ".\n\n" +
+			"Changed your mind? Sign in before then and the deletion is cancelled.\n",
+	})
+	if err != nil {
+		logging.NewSugaredLogger("account_deletion").Errorw("Failed to send deletion email", "deletion_id", deletion.Id, "error", err)
+	}
+
+	return toAccountDeletionResponse(deletion), nil
+}
+
+func (s *DefaultAccountDeletionService) GetDeletion(ctx context.Context, userID uuid.UUID) (resp response.AccountDeletionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.GetDeletion")
+	defer func() { tracing.End(span, err) }()
+
+	deletion, err := s.accountDeletionRepository.GetOpenDeletionByUserId(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return response.AccountDeletionResponse{}, ErrAccountDeletionNotFound
+		}
+		return response.AccountDeletionResponse{}, err
+	}
+	return toAccountDeletionResponse(deletion), nil
+}
+
+func (s *DefaultAccountDeletionService) GetDeletionById(ctx context.Context, id uuid.UUID) (resp response.AccountDeletionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.GetDeletionById")
+	defer func() { tracing.End(span, err) }()
+
+	deletion, err := s.accountDeletionRepository.GetDeletionById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return response.AccountDeletionResponse{}, ErrAccountDeletionNotFound
+		}
+		return response.AccountDeletionResponse{}, err
+	}
+	return toAccountDeletionResponse(deletion), nil
+}
+
+func (s *DefaultAccountDeletionService) CancelDeletion(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.CancelDeletion")
+	defer func() { tracing.End(span, err) }()
+
+	cancelled, err := s.accountDeletionRepository.CancelScheduledDeletion(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrAccountDeletionNotFound
+	}
+	return nil
+}
+
+// CancelOnSignIn cancels a scheduled deletion when the user signs in. Once the
+// purge has started the account is already partly gone, so the sign-in is
+// refused instead.
+func (s *DefaultAccountDeletionService) CancelOnSignIn(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.CancelOnSignIn")
+	defer func() { tracing.End(span, err) }()
+
+	cancelled, err := s.accountDeletionRepository.CancelScheduledDeletion(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if cancelled {
+		logging.NewSugaredLogger("account_deletion").Infow("Account deletion cancelled by sign-in", "user_id", userID)
+		return nil
+	}
+
+	open, err := s.accountDeletionRepository.GetOpenDeletionByUserId(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if open.Status == repository.AccountDeletionStatusPurging {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// PurgeDue erases every account whose grace period has ended. It stops
+// between batches once ctx is cancelled; the deletion it was working on is
+// resumed by a later run once it goes stale.
+func (s *DefaultAccountDeletionService) PurgeDue(ctx context.Context) error {
+	logger := logging.NewSugaredLogger("account_deletion")
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		deletion, err := s.accountDeletionRepository.ClaimDueDeletion(ctx, s.config.StaleAfter)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		logger.Infow("Purging account", "deletion_id", deletion.Id, "user_id", deletion.UserId)
+		if err := s.purge(ctx, deletion); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// The deletion stays in purging and is resumed once it goes stale.
+			logger.Errorw("Failed to purge account", "deletion_id", deletion.Id, "error", err)
+			if err := s.accountDeletionRepository.RecordDeletionError(ctx, deletion.Id, accountDeletionFailedReason); err != nil {
+				return err
+			}
+			continue
+		}
+		logger.Infow("Purged account", "deletion_id", deletion.Id, "user_id", deletion.UserId)
+	}
+}
+
+func (s *DefaultAccountDeletionService) purge(ctx context.Context, deletion repository.AccountDeletionEntity) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountDeletionService.purge")
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.NewSugaredLogger("account_deletion")
+
+	if deletion.TotalRows == 0 {
+		total, err := s.accountDeletionRepository.CountUserRows(ctx, deletion.UserId)
+		if err != nil {
+			return err
+		}
+		if err := s.accountDeletionRepository.SetDeletionTotal(ctx, deletion.Id, total); err != nil {
+			return err
+		}
+		deletion.TotalRows = total
+	}
+
+	if err := s.handOverOrganizations(ctx, deletion.UserId); err != nil {
+		return err
+	}
+
+	// Export archives live in the blob store and are only reachable through
+	// the export rows, so they go first. A failure leaves the rows for the
+	// next attempt.
+	if err := s.dataExportService.DeleteArchives(ctx, deletion.UserId); err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		deleted, err := s.accountDeletionRepository.PurgeUserRowsBatch(ctx, deletion.Id, deletion.UserId, s.config.PurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			break
+		}
+		deletion.DeletedRows += deleted
+		logger.Debugw("Purge progress", "deletion_id", deletion.Id, "deleted_rows", deletion.DeletedRows, "total_rows", deletion.TotalRows)
+	}
+
+	// Files outside the database go before the user row that points at them.
+	user, err := s.userRepository.GetUserById(ctx, deletion.UserId, repository.IncludeDeleted())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	s.avatarService.DeleteAvatarFiles(ctx, user.AvatarKey)
+
+	return s.accountDeletionRepository.CompleteDeletion(ctx, deletion)
+}
+
+// handOverOrganizations keeps the organizations the user alone owns from
+// losing their last owner when the membership goes with the user. Scheduling
+// refuses sole owners, but another owner may have left since. The next admin
+// or member becomes owner; an organization nobody else belongs to is deleted.
+func (s *DefaultAccountDeletionService) handOverOrganizations(ctx context.Context, userID uuid.UUID) error {
+	logger := logging.NewSugaredLogger("account_deletion")
+
+	owned, err := s.organizationRepository.GetSoleOwnedOrganizations(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, organization := range owned {
+		promoted, err := s.organizationRepository.PromoteSuccessor(ctx, organization.Id, userID)
+		if err != nil {
+			return err
+		}
+		if promoted {
+			logger.Infow("Handed over organization of deleted account", "organization_id", organization.Id, "user_id", userID)
+			continue
+		}
+		if _, err := s.organizationRepository.DeleteOrganization(ctx, organization.Id); err != nil {
+			return err
+		}
+		logger.Infow("Deleted organization of deleted account", "organization_id", organization.Id, "user_id", userID)
+	}
+	return nil
+}
+
+func toAccountDeletionResponse(deletion repository.AccountDeletionEntity) response.AccountDeletionResponse {
+	progress := 0.0
+	switch {
+	case deletion.Status == repository.AccountDeletionStatusCompleted:
+		progress = 100
+	case deletion.TotalRows > 0:
+		progress = min(float64(deletion.DeletedRows)*100/float64(deletion.TotalRows), 100)
+	}
+
+	// Rows written before the cause was kept out of the record may still
+	// hold it, so only whether the last attempt failed is shown.
+	var reason string
+	if deletion.Error != "" {
+		reason = accountDeletionFailedReason
+	}
+
+	return response.AccountDeletionResponse{
+		ID:           deletion.Id.String(),
+		UserID:       deletion.UserId.String(),
+		Status:       deletion.Status,
+		ScheduledFor: deletion.ScheduledFor,
+		TotalRows:    deletion.TotalRows,
+		DeletedRows:  deletion.DeletedRows,
+		Progress:     progress,
+		Error:        reason,
+		RequestedAt:  deletion.RequestedAt,
+		CancelledAt:  deletion.CancelledAt,
+		StartedAt:    deletion.StartedAt,
+		CompletedAt:  deletion.CompletedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var ErrActionTokenInvalid = exception.NewError("action_token_invalid", "token is invalid, expired or already used")
+
+// ActionTokenService issues and redeems the single-use signed tokens embedded
+// in emailed links.
+type ActionTokenService interface {
+	Issue(ctx context.Context, purpose string, userID *uuid.UUID, email string, payload map[string]string, ttl time.Duration) (string, error)
+	Inspect(ctx context.Context, token string, purpose string) (repository.ActionTokenEntity, error)
+	Consume(ctx context.Context, token string, purpose string) (repository.ActionTokenEntity, error)
+}
+
+type DefaultActionTokenService struct {
+	actionTokenRepository repository.ActionTokenRepository
+	jwtService            JWTService
+}
+
+func NewActionTokenService(actionTokenRepository repository.ActionTokenRepository, jwtService JWTService) ActionTokenService {
+	return &DefaultActionTokenService{
+		actionTokenRepository: actionTokenRepository,
+		jwtService:            jwtService,
+	}
+}
+
+func (s *DefaultActionTokenService) Issue(ctx context.Context, purpose string, userID *uuid.UUID, email string, payload map[string]string, ttl time.Duration) (token string, err error) {
+	ctx, span := tracing.Start(ctx, "ActionTokenService.Issue")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.actionTokenRepository.DeleteExpiredTokens(ctx); err != nil {
+		return "", err
+	}
+
+	entity, err := s.actionTokenRepository.InsertToken(ctx, repository.ActionTokenEntity{
+		Id:        uuid.New(),
+		UserId:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		Payload:   payload,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.jwtService.GenerateActionToken(entity.Id, purpose, entity.ExpiresAt)
+}
+
+// Inspect returns the token if it could still be consumed, without using it
+// up. It lets callers check who a link is meant for before redeeming it.
+func (s *DefaultActionTokenService) Inspect(ctx context.Context, token string, purpose string) (out repository.ActionTokenEntity, err error) {
+	ctx, span := tracing.Start(ctx, "ActionTokenService.Inspect")
+	defer func() { tracing.End(span, err) }()
+
+	id, err := s.jwtService.ValidateActionToken(token, purpose)
+	if err != nil {
+		return repository.ActionTokenEntity{}, ErrActionTokenInvalid
+	}
+
+	entity, err := s.actionTokenRepository.GetUsableToken(ctx, id, purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ActionTokenEntity{}, ErrActionTokenInvalid
+	}
+	if err != nil {
+		return repository.ActionTokenEntity{}, err
+	}
+	return entity, nil
+}
+
+func (s *DefaultActionTokenService) Consume(ctx context.Context, token string, purpose string) (out repository.ActionTokenEntity, err error) {
+	ctx, span := tracing.Start(ctx, "ActionTokenService.Consume")
+	defer func() { tracing.End(span, err) }()
+
+	id, err := s.jwtService.ValidateActionToken(token, purpose)
+	if err != nil {
+		return repository.ActionTokenEntity{}, ErrActionTokenInvalid
+	}
+
+	entity, err := s.actionTokenRepository.ConsumeToken(ctx, id, purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ActionTokenEntity{}, ErrActionTokenInvalid
+	}
+	if err != nil {
+		return repository.ActionTokenEntity{}, err
+	}
+	return entity, nil
+}
@@ -1,38 +1,65 @@
 package service
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
 	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService interface {
-	SignUp(req request.SignUpRequest) (response.SignUpResponse, error)
-	SignIn(req request.SignInRequest) (response.SignInResponse, error)
-	SignOut(token string) (response.SignOutResponse, error)
+	SignUp(ctx context.Context, req request.SignUpRequest) (response.SignUpResponse, error)
+	SignIn(ctx context.Context, req request.SignInRequest) (response.SignInResponse, error)
+	SignOut(ctx context.Context, token string) (response.SignOutResponse, error)
+	RequestMagicLink(ctx context.Context, req request.MagicLinkRequest) (response.MagicLinkResponse, error)
+	ConsumeMagicLink(ctx context.Context, req request.ConsumeMagicLinkRequest) (response.SignInResponse, error)
 }
 
 type DefaultAuthService struct {
-	userRepository repository.UserRepository
-	jwtService     JWTService
+	userRepository         repository.UserRepository
+	jwtService             JWTService
+	actionTokenService     ActionTokenService
+	accountDeletionService AccountDeletionService
+	avatars                AvatarURLResolver
+	mailer                 mailer.Mailer
+	magicLinkConfig        runtime.MagicLinkConfig
 }
 
-func NewAuthService(userRepository repository.UserRepository, jwtService JWTService) AuthService {
+func NewAuthService(userRepository repository.UserRepository, jwtService JWTService, actionTokenService ActionTokenService, accountDeletionService AccountDeletionService, avatars AvatarURLResolver, mailer mailer.Mailer, magicLinkConfig runtime.MagicLinkConfig) AuthService {
 	return &DefaultAuthService{
-		userRepository: userRepository,
-		jwtService:     jwtService,
+		userRepository:         userRepository,
+		jwtService:             jwtService,
+		actionTokenService:     actionTokenService,
+		accountDeletionService: accountDeletionService,
+		avatars:                avatars,
+		mailer:                 mailer,
+		magicLinkConfig:        magicLinkConfig,
 	}
 }
 
-func (s *DefaultAuthService) SignUp(req request.SignUpRequest) (response.SignUpResponse, error) {
-	// Check if user already exists
-	_, err := s.userRepository.GetUserByEmail(req.Email)
+func (s *DefaultAuthService) SignUp(ctx context.Context, req request.SignUpRequest) (resp response.SignUpResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SignUp")
+	defer func() { tracing.End(span, err) }()
+
+	// Check if user already exists, including soft-deleted accounts that still hold the address
+	_, err = s.userRepository.GetUserByEmail(ctx, req.Email, repository.IncludeDeleted())
 	if err == nil {
-		return response.SignUpResponse{}, errors.New("user already exists")
+		return response.SignUpResponse{}, ErrEmailTaken
+	}
+	if !errors.Is(err, exception.ErrNotFound) {
+		return response.SignUpResponse{}, err
 	}
 
 	// Hash password
@@ -46,9 +73,14 @@ func (s *DefaultAuthService) SignUp(req request.SignUpRequest) (response.SignUpR
 		Id:       uuid.New(),
 		Email:    req.Email,
 		Password: string(hashedPassword),
+		IsActive: true,
 	}
 
-	createdUser, err := s.userRepository.InsertUser(user)
+	// A concurrent sign-up may have taken the address since the check
+	createdUser, err := s.userRepository.InsertUser(ctx, user)
+	if errors.Is(err, exception.ErrConflict) {
+		return response.SignUpResponse{}, ErrEmailTaken
+	}
 	if err != nil {
 		return response.SignUpResponse{}, err
 	}
@@ -59,41 +91,164 @@ func (s *DefaultAuthService) SignUp(req request.SignUpRequest) (response.SignUpR
 	}, nil
 }
 
-func (s *DefaultAuthService) SignIn(req request.SignInRequest) (response.SignInResponse, error) {
+func (s *DefaultAuthService) SignIn(ctx context.Context, req request.SignInRequest) (resp response.SignInResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SignIn")
+	defer func() { tracing.End(span, err) }()
+
 	// Get user by email
-	user, err := s.userRepository.GetUserByEmail(req.Email)
+	user, err := s.userRepository.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		return response.SignInResponse{}, errors.New("invalid credentials")
 	}
 
-	// Check password
+	// Check password; passwordless accounts can only use magic links or passkeys
+	if user.Password == "" {
+		return response.SignInResponse{}, errors.New("invalid credentials")
+	}
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
 		return response.SignInResponse{}, errors.New("invalid credentials")
 	}
 
-	// Generate tokens
-	token, err := s.jwtService.GenerateToken(user.Id, user.Email)
+	return newSignInResponse(ctx, s.jwtService, s.accountDeletionService, s.avatars, user)
+}
+
+// RequestMagicLink emails a single-use sign-in link. The response is the same
+// whether or not an account exists for the address. Unknown addresses only
+// get a link when magic link sign-up is allowed; the account is then created
+// when the link is consumed.
+func (s *DefaultAuthService) RequestMagicLink(ctx context.Context, req request.MagicLinkRequest) (resp response.MagicLinkResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RequestMagicLink")
+	defer func() { tracing.End(span, err) }()
+
+	resp = response.MagicLinkResponse{
+		Message: "If the address can receive email, a sign-in link has been sent",
+	}
+
+	var userID *uuid.UUID
+	user, err := s.userRepository.GetUserByEmail(ctx, req.Email, repository.IncludeDeleted())
+	if err == nil {
+		if !user.DeletedAt.IsZero() {
+			return resp, nil
+		}
+		userID = &user.Id
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return response.MagicLinkResponse{}, err
+	} else if !s.magicLinkConfig.AllowSignUp {
+		return resp, nil
+	}
+
+	token, err := s.actionTokenService.Issue(ctx, repository.ActionTokenPurposeMagicLink, userID, req.Email, nil, s.magicLinkConfig.TTL)
+	if err != nil {
+		return response.MagicLinkResponse{}, err
+	}
+
+	link := s.magicLinkConfig.URL + "?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      req.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Use the link below to sign in. It expires in %s and can only be used once.\n\n%s\n\n"+
+			"If you did not request this email you can ignore it.", s.magicLinkConfig.TTL, link),
+	})
+	if err != nil {
+		return response.MagicLinkResponse{}, err
+	}
+
+	return resp, nil
+}
+
+func (s *DefaultAuthService) ConsumeMagicLink(ctx context.Context, req request.ConsumeMagicLinkRequest) (resp response.SignInResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ConsumeMagicLink")
+	defer func() { tracing.End(span, err) }()
+
+	token, err := s.actionTokenService.Consume(ctx, req.Token, repository.ActionTokenPurposeMagicLink)
 	if err != nil {
 		return response.SignInResponse{}, err
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user.Id)
+	var user repository.UserEntity
+	if token.UserId != nil {
+		// The link was sent to an existing account; it is only valid while
+		// that account still owns the address.
+		user, err = s.userRepository.GetUserById(ctx, *token.UserId)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && user.Email != token.Email) {
+			return response.SignInResponse{}, ErrActionTokenInvalid
+		}
+	} else {
+		user, err = s.userRepository.GetUserByEmail(ctx, token.Email)
+		if errors.Is(err, sql.ErrNoRows) {
+			if !s.magicLinkConfig.AllowSignUp {
+				// Issued while sign-up was allowed
+				return response.SignInResponse{}, ErrActionTokenInvalid
+			}
+			user, err = s.userRepository.InsertUser(ctx, repository.UserEntity{
+				Id:       uuid.New(),
+				Email:    token.Email,
+				IsActive: true,
+			})
+			if errors.Is(err, exception.ErrConflict) {
+				// Created by a concurrent sign-up
+				user, err = s.userRepository.GetUserByEmail(ctx, token.Email)
+			}
+		}
+	}
 	if err != nil {
 		return response.SignInResponse{}, err
 	}
 
-	return response.SignInResponse{
+	// Following the link proves the user controls the address.
+	if user.EmailVerifiedAt == nil {
+		if err := s.userRepository.MarkEmailVerified(ctx, user.Id); err != nil {
+			return response.SignInResponse{}, err
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	return newSignInResponse(ctx, s.jwtService, s.accountDeletionService, s.avatars, user)
+}
+
+// newSignInResponse issues the access and refresh tokens for an authenticated
+// user. Every sign-in method returns this same response, and every sign-in
+// cancels a pending account deletion. Sessions start in the personal workspace.
+func newSignInResponse(ctx context.Context, jwtService JWTService, accountDeletionService AccountDeletionService, avatars AvatarURLResolver, user repository.UserEntity) (response.SignInResponse, error) {
+	if !user.IsActive {
+		return response.SignInResponse{}, ErrUserInactive
+	}
+
+	if err := accountDeletionService.CancelOnSignIn(ctx, user.Id); err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	return newTokenResponse(jwtService, avatars, user, nil)
+}
+
+// newTokenResponse issues tokens whose active workspace is organizationID, or
+// the personal workspace when it is nil.
+func newTokenResponse(jwtService JWTService, avatars AvatarURLResolver, user repository.UserEntity, organizationID *uuid.UUID) (response.SignInResponse, error) {
+	token, err := jwtService.GenerateToken(user.Id, user.Email, organizationID)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	refreshToken, err := jwtService.GenerateRefreshToken(user.Id)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	resp := response.SignInResponse{
 		Token:        token,
 		RefreshToken: refreshToken,
-		User: response.NewUserResponse{
-			ID:    user.Id.String(),
-			Email: user.Email,
-		},
-	}, nil
+		User:         toUserResponse(user, avatars),
+	}
+	if organizationID != nil {
+		id := organizationID.String()
+		resp.OrganizationID = &id
+	}
+	return resp, nil
 }
 
-func (s *DefaultAuthService) SignOut(token string) (response.SignOutResponse, error) {
+func (s *DefaultAuthService) SignOut(ctx context.Context, token string) (response.SignOutResponse, error) {
 	// In a real application, you would blacklist the token
 	// For now, we just return success
 	return response.SignOutResponse{
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/storage"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// maxAvatarPixels caps the decoded size of an upload so a small, highly
+// compressed file cannot exhaust memory.
+const maxAvatarPixels = 40_000_000
+
+var (
+	ErrAvatarTooLarge        = exception.NewError("avatar_too_large", "avatar file is too large")
+	ErrAvatarUnsupportedType = exception.NewError("avatar_unsupported_type", "avatar must be a JPEG, PNG, GIF or WebP image")
+	ErrAvatarInvalid         = exception.NewError("avatar_invalid", "avatar is not a valid image")
+)
+
+var avatarContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// AvatarURLResolver turns a stored avatar key into thumbnail URLs keyed by
+// size in pixels.
+type AvatarURLResolver interface {
+	AvatarURLs(key string) map[string]string
+}
+
+type AvatarService interface {
+	AvatarURLResolver
+	UploadAvatar(ctx context.Context, userID uuid.UUID, file io.Reader) (response.NewUserResponse, error)
+	DeleteAvatar(ctx context.Context, userID uuid.UUID) (response.NewUserResponse, error)
+	DeleteAvatarFiles(ctx context.Context, key string)
+}
+
+type DefaultAvatarService struct {
+	config         runtime.AvatarConfig
+	userRepository repository.UserRepository
+	blobStore      storage.BlobStore
+}
+
+func NewAvatarService(config runtime.AvatarConfig, userRepository repository.UserRepository, blobStore storage.BlobStore) AvatarService {
+	return &DefaultAvatarService{config: config, userRepository: userRepository, blobStore: blobStore}
+}
+
+// UploadAvatar validates the image, stores a square JPEG thumbnail for every
+// configured size and points the user at them. Each upload gets a new key so
+// the URLs can be cached forever.
+func (s *DefaultAvatarService) UploadAvatar(ctx context.Context, userID uuid.UUID, file io.Reader) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AvatarService.UploadAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.config.MaxBytes+1))
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	if int64(len(data)) > s.config.MaxBytes {
+		return response.NewUserResponse{}, ErrAvatarTooLarge
+	}
+
+	// Trust the bytes, not the client's Content-Type or file name.
+	if !slices.Contains(avatarContentTypes, http.DetectContentType(data)) {
+		return response.NewUserResponse{}, ErrAvatarUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return response.NewUserResponse{}, ErrAvatarInvalid
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxAvatarPixels {
+		return response.NewUserResponse{}, ErrAvatarInvalid
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return response.NewUserResponse{}, ErrAvatarInvalid
+	}
+
+	key := "avatars/" + userID.String() + "/" + uuid.NewString()
+	for _, size := range s.config.Sizes {
+		thumbnail, err := encodeThumbnail(src, size)
+		if err != nil {
+			return response.NewUserResponse{}, err
+		}
+		if err := s.blobStore.Put(ctx, avatarBlobKey(key, size), bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg"); err != nil {
+			return response.NewUserResponse{}, err
+		}
+	}
+
+	updated, err := s.userRepository.UpdateAvatarKey(ctx, userID, key)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	if !updated {
+		s.DeleteAvatarFiles(ctx, key)
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	s.DeleteAvatarFiles(ctx, user.AvatarKey)
+
+	return s.reload(ctx, userID)
+}
+
+func (s *DefaultAvatarService) DeleteAvatar(ctx context.Context, userID uuid.UUID) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AvatarService.DeleteAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	if user.AvatarKey != "" {
+		if _, err := s.userRepository.UpdateAvatarKey(ctx, userID, ""); err != nil {
+			return response.NewUserResponse{}, err
+		}
+		s.DeleteAvatarFiles(ctx, user.AvatarKey)
+	}
+
+	return s.reload(ctx, userID)
+}
+
+func (s *DefaultAvatarService) AvatarURLs(key string) map[string]string {
+	if key == "" {
+		return nil
+	}
+	urls := make(map[string]string, len(s.config.Sizes))
+	for _, size := range s.config.Sizes {
+		urls[strconv.Itoa(size)] = s.blobStore.URL(avatarBlobKey(key, size))
+	}
+	return urls
+}
+
+func (s *DefaultAvatarService) reload(ctx context.Context, userID uuid.UUID) (response.NewUserResponse, error) {
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	return toUserResponse(user, s), nil
+}
+
+// DeleteAvatarFiles removes the thumbnails stored under key. Failures only
+// leave unreferenced files behind, so they are logged rather than returned.
+func (s *DefaultAvatarService) DeleteAvatarFiles(ctx context.Context, key string) {
+	ctx, span := tracing.Start(ctx, "AvatarService.DeleteAvatarFiles")
+	defer span.End()
+
+	if key == "" {
+		return
+	}
+	for _, size := range s.config.Sizes {
+		if err := s.blobStore.Delete(ctx, avatarBlobKey(key, size)); err != nil {
+			logging.NewSugaredLogger("avatar").Errorw("Failed to delete avatar thumbnail", "key", key, "size", size, "error", err)
+		}
+	}
+}
+
+func avatarBlobKey(key string, size int) string {
+	return key + "/" + strconv.Itoa(size) + ".jpg"
+}
+
+// encodeThumbnail center-crops src to a square, scales it to size pixels and
+// encodes it as JPEG. Transparent areas become white.
+func encodeThumbnail(src image.Image, size int) ([]byte, error) {
+	b := src.Bounds()
+	edge := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, edge, edge).Add(image.Pt(b.Min.X+(b.Dx()-edge)/2, b.Min.Y+(b.Dy()-edge)/2))
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/storage"
+)
+
+func TestUploadAvatarSniffsContent(t *testing.T) {
+	ctx := context.Background()
+	env := newAvatarTestEnv(t, 1<<20)
+	src := solidImage(80, 80, color.RGBA{R: 255, A: 255})
+
+	var pngData, gifData, jpegData bytes.Buffer
+	mustEncode(t, png.Encode(&pngData, src))
+	mustEncode(t, gif.Encode(&gifData, src, nil))
+	mustEncode(t, jpeg.Encode(&jpegData, src, nil))
+
+	// The bytes decide the type; uploads carry no name or Content-Type here.
+	for name, data := range map[string][]byte{"png": pngData.Bytes(), "gif": gifData.Bytes(), "jpeg": jpegData.Bytes()} {
+		if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(data)); err != nil {
+			t.Errorf("%s upload: %v", name, err)
+		}
+	}
+}
+
+func TestUploadAvatarRejectsOtherTypes(t *testing.T) {
+	ctx := context.Background()
+	env := newAvatarTestEnv(t, 1<<20)
+
+	for name, data := range map[string]string{
+		"svg":  `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><script>alert(1)</script></svg>`,
+		"html": `<html><body><img src="x" onerror="alert(1)"></body></html>`,
+		"pdf":  "%PDF-1.4\n1 0 obj\n<<>>\nendobj\n",
+		"bmp":  "BM" + string(make([]byte, 64)),
+		"text": "just some text pretending to be avatar.png",
+	} {
+		_, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader([]byte(data)))
+		if !errors.Is(err, ErrAvatarUnsupportedType) {
+			t.Errorf("%s upload: got %v, want ErrAvatarUnsupportedType", name, err)
+		}
+	}
+	if len(env.stored()) != 0 {
+		t.Fatalf("rejected uploads stored %d blobs", len(env.stored()))
+	}
+}
+
+func TestUploadAvatarRejectsCorruptImage(t *testing.T) {
+	ctx := context.Background()
+	env := newAvatarTestEnv(t, 1<<20)
+
+	var data bytes.Buffer
+	mustEncode(t, png.Encode(&data, solidImage(80, 80, color.White)))
+	truncated := data.Bytes()[:data.Len()/2]
+
+	if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(truncated)); !errors.Is(err, ErrAvatarInvalid) {
+		t.Fatalf("got %v, want ErrAvatarInvalid", err)
+	}
+}
+
+func TestUploadAvatarEnforcesSizeLimits(t *testing.T) {
+	ctx := context.Background()
+
+	var data bytes.Buffer
+	mustEncode(t, png.Encode(&data, solidImage(64, 64, color.White)))
+
+	// Over the byte limit
+	env := newAvatarTestEnv(t, int64(data.Len()-1))
+	if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(data.Bytes())); !errors.Is(err, ErrAvatarTooLarge) {
+		t.Fatalf("file over the limit: got %v, want ErrAvatarTooLarge", err)
+	}
+
+	// Exactly at the byte limit
+	env = newAvatarTestEnv(t, int64(data.Len()))
+	if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(data.Bytes())); err != nil {
+		t.Fatalf("file at the limit: %v", err)
+	}
+
+	// A small file that decodes to more pixels than allowed
+	var small bytes.Buffer
+	mustEncode(t, png.Encode(&small, solidImage(1, 1, color.White)))
+	bomb := withPNGSize(t, small.Bytes(), 10_000, 10_000)
+	if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(bomb)); !errors.Is(err, ErrAvatarInvalid) {
+		t.Fatalf("decompression bomb: got %v, want ErrAvatarInvalid", err)
+	}
+}
+
+func TestUploadAvatarStoresSquareThumbnails(t *testing.T) {
+	ctx := context.Background()
+	env := newAvatarTestEnv(t, 1<<20)
+
+	// Red on the left and right thirds, blue in the middle square
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for x := range 300 {
+		for y := range 100 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 100 && x < 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var data bytes.Buffer
+	mustEncode(t, png.Encode(&data, src))
+
+	resp, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("UploadAvatar: %v", err)
+	}
+	if len(resp.AvatarURLs) != len(env.config.Sizes) {
+		t.Fatalf("got avatar URLs %v, want one per size %v", resp.AvatarURLs, env.config.Sizes)
+	}
+
+	key := env.users.users[env.user.Id].AvatarKey
+	for _, size := range env.config.Sizes {
+		thumbnail := env.read(t, avatarBlobKey(key, size))
+		bounds := thumbnail.Bounds()
+		if bounds.Dx() != size || bounds.Dy() != size {
+			t.Fatalf("thumbnail %d is %dx%d", size, bounds.Dx(), bounds.Dy())
+		}
+		// The center crop keeps only the blue middle, corners included.
+		for _, p := range []image.Point{{1, 1}, {size / 2, size / 2}, {size - 2, size - 2}} {
+			r, _, b, _ := thumbnail.At(p.X, p.Y).RGBA()
+			if b>>8 < 200 || r>>8 > 60 {
+				t.Fatalf("thumbnail %d pixel %v is %v, want blue", size, p, thumbnail.At(p.X, p.Y))
+			}
+		}
+	}
+}
+
+func TestUploadAvatarReplacesPreviousFiles(t *testing.T) {
+	ctx := context.Background()
+	env := newAvatarTestEnv(t, 1<<20)
+
+	var data bytes.Buffer
+	mustEncode(t, png.Encode(&data, solidImage(80, 80, color.White)))
+
+	if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(data.Bytes())); err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+	first := env.users.users[env.user.Id].AvatarKey
+	if _, err := env.service.UploadAvatar(ctx, env.user.Id, bytes.NewReader(data.Bytes())); err != nil {
+		t.Fatalf("second upload: %v", err)
+	}
+	second := env.users.users[env.user.Id].AvatarKey
+
+	if first == second {
+		t.Fatal("second upload reused the first key")
+	}
+	for _, size := range env.config.Sizes {
+		if _, _, err := env.blobs.Get(ctx, avatarBlobKey(first, size)); !errors.Is(err, storage.ErrBlobNotFound) {
+			t.Fatalf("old thumbnail %d: got %v, want ErrBlobNotFound", size, err)
+		}
+	}
+	if len(env.stored()) != len(env.config.Sizes) {
+		t.Fatalf("%d blobs stored, want %d", len(env.stored()), len(env.config.Sizes))
+	}
+}
+
+type avatarTestEnv struct {
+	service AvatarService
+	config  runtime.AvatarConfig
+	users   *fakeUserRepository
+	blobs   *storage.LocalBlobStore
+	user    repository.UserEntity
+}
+
+func newAvatarTestEnv(t *testing.T, maxBytes int64) *avatarTestEnv {
+	t.Helper()
+
+	dir := t.TempDir()
+	blobs, err := storage.NewLocalBlobStore(runtime.BlobStoreConfig{LocalDir: dir, PublicURL: "http://localhost:8080/media"})
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore: %v", err)
+	}
+
+	user := repository.UserEntity{Id: uuid.New(), Email: "alice@example.com", IsActive: true}
+	users := &fakeUserRepository{users: map[uuid.UUID]repository.UserEntity{user.Id: user}}
+	config := runtime.AvatarConfig{MaxBytes: maxBytes, Sizes: []int{32, 64}}
+
+	return &avatarTestEnv{
+		service: NewAvatarService(config, users, blobs),
+		config:  config,
+		users:   users,
+		blobs:   blobs,
+		user:    user,
+	}
+}
+
+func (e *avatarTestEnv) read(t *testing.T, key string) image.Image {
+	t.Helper()
+
+	r, contentType, err := e.blobs.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", key, err)
+	}
+	defer r.Close()
+	if contentType != "image/jpeg" {
+		t.Fatalf("%s stored as %q, want image/jpeg", key, contentType)
+	}
+	img, err := jpeg.Decode(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", key, err)
+	}
+	return img
+}
+
+// stored lists the thumbnails of every avatar the user has had.
+func (e *avatarTestEnv) stored() []string {
+	var keys []string
+	for key, user := range e.users.avatarKeys {
+		if user != e.user.Id {
+			continue
+		}
+		for _, size := range e.config.Sizes {
+			if r, _, err := e.blobs.Get(context.Background(), avatarBlobKey(key, size)); err == nil {
+				r.Close()
+				keys = append(keys, avatarBlobKey(key, size))
+			}
+		}
+	}
+	return keys
+}
+
+func (r *fakeUserRepository) UpdateAvatarKey(_ context.Context, id uuid.UUID, key string) (bool, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return false, nil
+	}
+	user.AvatarKey = key
+	r.users[id] = user
+	if key != "" {
+		if r.avatarKeys == nil {
+			r.avatarKeys = map[string]uuid.UUID{}
+		}
+		r.avatarKeys[key] = id
+	}
+	return true, nil
+}
+
+func solidImage(width, height int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withPNGSize rewrites the dimensions in the IHDR chunk of a PNG, keeping its
+// checksum valid, so the header claims more pixels than the data holds.
+func withPNGSize(t *testing.T, data []byte, width, height uint32) []byte {
+	t.Helper()
+
+	out := bytes.Clone(data)
+	// Signature (8), then the IHDR length (4) and type (4)
+	const ihdr = 8 + 4
+	if string(out[ihdr:ihdr+4]) != "IHDR" {
+		t.Fatal("not a PNG")
+	}
+	binary.BigEndian.PutUint32(out[ihdr+4:], width)
+	binary.BigEndian.PutUint32(out[ihdr+8:], height)
+	binary.BigEndian.PutUint32(out[ihdr+4+13:], crc32.ChecksumIEEE(out[ihdr:ihdr+4+13]))
+	return out
+}
+
+func mustEncode(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var (
+	ErrCategoryNotFound  = exception.NewError("category_not_found", "category not found")
+	ErrCategoryForbidden = exception.NewError("category_forbidden", "you do not have permission to change this category")
+)
+
+// CategoryService manages the categories of the caller's active workspace and
+// the categories other users shared with the caller.
+type CategoryService interface {
+	GetCategories(ctx context.Context, workspace repository.Workspace, listReq request.ListRequest) (response.ListResponse[response.CategoryResponse], error)
+	GetCategoryById(ctx context.Context, workspace repository.Workspace, id uuid.UUID) (response.CategoryResponse, error)
+	CreateCategory(ctx context.Context, workspace repository.Workspace, req request.CategoryRequest) (response.CategoryResponse, error)
+	UpdateCategory(ctx context.Context, workspace repository.Workspace, id uuid.UUID, req request.CategoryRequest) (response.CategoryResponse, error)
+	DeleteCategory(ctx context.Context, workspace repository.Workspace, id uuid.UUID) error
+	GetSharedCategories(ctx context.Context, userID uuid.UUID, listReq request.ListRequest) (response.ListResponse[response.CategoryResponse], error)
+}
+
+type DefaultCategoryService struct {
+	categoryRepository repository.CategoryRepository
+}
+
+func NewCategoryService(categoryRepository repository.CategoryRepository) CategoryService {
+	return &DefaultCategoryService{categoryRepository: categoryRepository}
+}
+
+func (s *DefaultCategoryService) GetCategories(ctx context.Context, workspace repository.Workspace, listReq request.ListRequest) (resp response.ListResponse[response.CategoryResponse], err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetCategories")
+	defer func() { tracing.End(span, err) }()
+
+	page, err := toPage(listReq)
+	if err != nil {
+		return response.ListResponse[response.CategoryResponse]{}, err
+	}
+
+	categories, info, err := s.categoryRepository.GetCategories(ctx, workspace, page)
+	if err != nil {
+		return response.ListResponse[response.CategoryResponse]{}, err
+	}
+
+	responses := make([]response.CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		responses = append(responses, toCategoryResponse(category))
+	}
+
+	resp = newPageResponse(responses, listReq, info)
+	if listReq.WantsTotal() {
+		total, err := s.categoryRepository.GetCategoriesCount(ctx, workspace)
+		if err != nil {
+			return response.ListResponse[response.CategoryResponse]{}, err
+		}
+		resp = resp.WithTotal(total)
+	}
+	return resp, nil
+}
+
+func (s *DefaultCategoryService) GetCategoryById(ctx context.Context, workspace repository.Workspace, id uuid.UUID) (resp response.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetCategoryById")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := s.categoryRepository.GetCategoryById(ctx, workspace, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.CategoryResponse{}, ErrCategoryNotFound
+	}
+	if err != nil {
+		return response.CategoryResponse{}, err
+	}
+	return toCategoryResponse(category), nil
+}
+
+func (s *DefaultCategoryService) CreateCategory(ctx context.Context, workspace repository.Workspace, req request.CategoryRequest) (resp response.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.CreateCategory")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := s.categoryRepository.InsertCategory(ctx, workspace, repository.CategoryEntity{
+		Id:          uuid.New(),
+		CreatedBy:   &workspace.UserId,
+		Name:        req.Name,
+		Color:       req.Color,
+		Description: req.Description,
+	})
+	if err != nil {
+		return response.CategoryResponse{}, err
+	}
+	return toCategoryResponse(category), nil
+}
+
+func (s *DefaultCategoryService) UpdateCategory(ctx context.Context, workspace repository.Workspace, id uuid.UUID, req request.CategoryRequest) (resp response.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.UpdateCategory")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := s.categoryRepository.UpdateCategory(ctx, workspace, repository.CategoryEntity{
+		Id:          id,
+		Name:        req.Name,
+		Color:       req.Color,
+		Description: req.Description,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.CategoryResponse{}, s.explainDenied(ctx, workspace, id)
+	}
+	if err != nil {
+		return response.CategoryResponse{}, err
+	}
+	return toCategoryResponse(category), nil
+}
+
+// DeleteCategory deletes a category of the workspace. Users it is shared with
+// cannot delete it, not even editors.
+func (s *DefaultCategoryService) DeleteCategory(ctx context.Context, workspace repository.Workspace, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.DeleteCategory")
+	defer func() { tracing.End(span, err) }()
+
+	deleted, err := s.categoryRepository.DeleteCategory(ctx, workspace, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return s.explainDenied(ctx, workspace, id)
+	}
+	return nil
+}
+
+func (s *DefaultCategoryService) GetSharedCategories(ctx context.Context, userID uuid.UUID, listReq request.ListRequest) (resp response.ListResponse[response.CategoryResponse], err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetSharedCategories")
+	defer func() { tracing.End(span, err) }()
+
+	page, err := toPage(listReq)
+	if err != nil {
+		return response.ListResponse[response.CategoryResponse]{}, err
+	}
+
+	categories, info, err := s.categoryRepository.GetSharedCategories(ctx, userID, page)
+	if err != nil {
+		return response.ListResponse[response.CategoryResponse]{}, err
+	}
+
+	responses := make([]response.CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		responses = append(responses, toCategoryResponse(category))
+	}
+
+	resp = newPageResponse(responses, listReq, info)
+	if listReq.WantsTotal() {
+		total, err := s.categoryRepository.GetSharedCategoriesCount(ctx, userID)
+		if err != nil {
+			return response.ListResponse[response.CategoryResponse]{}, err
+		}
+		resp = resp.WithTotal(total)
+	}
+	return resp, nil
+}
+
+// explainDenied tells why a change to a category matched no row: it is
+// either invisible to the workspace or only shared with a lesser role.
+func (s *DefaultCategoryService) explainDenied(ctx context.Context, workspace repository.Workspace, id uuid.UUID) error {
+	_, err := s.categoryRepository.GetCategoryById(ctx, workspace, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrCategoryForbidden
+}
+
+func toCategoryResponse(category repository.CategoryEntity) response.CategoryResponse {
+	return response.CategoryResponse{
+		ID:             category.Id.String(),
+		UserID:         optionalUUID(category.UserId),
+		OrganizationID: optionalUUID(category.OrganizationId),
+		Name:           category.Name,
+		Color:          category.Color,
+		Description:    category.Description,
+		Access:         category.Access,
+		CreatedAt:      category.CreatedAt,
+		UpdatedAt:      category.UpdatedAt,
+	}
+}
+
+func optionalUUID(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/storage"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+const dataExportTokenPurpose = "data_export"
+
+// dataExportFailedReason is what users see of a failed export; the cause is
+// only logged, since it can name internal paths and hosts.
+const dataExportFailedReason = "The export could not be built. Request a new one."
+
+var (
+	ErrDataExportNotFound = exception.NewError("data_export_not_found", "data export not found")
+	ErrDataExportNotReady = exception.NewError("data_export_not_ready", "data export is not available for download")
+)
+
+// DataExportService builds ZIP archives of everything stored about a user.
+// Requests are queued and built in the background by ProcessPending; the
+// archives are kept in the blob store until their link expires.
+type DataExportService interface {
+	RequestExport(ctx context.Context, userID uuid.UUID, requestedBy uuid.UUID) (response.DataExportResponse, error)
+	GetExports(ctx context.Context, userID uuid.UUID) ([]response.DataExportResponse, error)
+	GetExport(ctx context.Context, userID uuid.UUID, id uuid.UUID) (response.DataExportResponse, error)
+	Download(ctx context.Context, token string) (filename string, archive io.ReadCloser, err error)
+	ProcessPending(ctx context.Context) error
+	DeleteArchives(ctx context.Context, userID uuid.UUID) error
+}
+
+type DefaultDataExportService struct {
+	config                runtime.ServerConfig
+	exportConfig          runtime.DataExportConfig
+	dataExportRepository  repository.DataExportRepository
+	userRepository        repository.UserRepository
+	categoryRepository    repository.CategoryRepository
+	todoRepository        repository.TodoRepository
+	passkeyRepository     repository.PasskeyRepository
+	actionTokenRepository repository.ActionTokenRepository
+	jwtService            JWTService
+	avatars               AvatarURLResolver
+	blobStore             storage.BlobStore
+	mailer                mailer.Mailer
+}
+
+func NewDataExportService(
+	config runtime.ServerConfig,
+	exportConfig runtime.DataExportConfig,
+	dataExportRepository repository.DataExportRepository,
+	userRepository repository.UserRepository,
+	categoryRepository repository.CategoryRepository,
+	todoRepository repository.TodoRepository,
+	passkeyRepository repository.PasskeyRepository,
+	actionTokenRepository repository.ActionTokenRepository,
+	jwtService JWTService,
+	avatars AvatarURLResolver,
+	blobStore storage.BlobStore,
+	mailer mailer.Mailer,
+) DataExportService {
+	return &DefaultDataExportService{
+		config:                config,
+		exportConfig:          exportConfig,
+		dataExportRepository:  dataExportRepository,
+		userRepository:        userRepository,
+		categoryRepository:    categoryRepository,
+		todoRepository:        todoRepository,
+		passkeyRepository:     passkeyRepository,
+		actionTokenRepository: actionTokenRepository,
+		jwtService:            jwtService,
+		avatars:               avatars,
+		blobStore:             blobStore,
+		mailer:                mailer,
+	}
+}
+
+// RequestExport queues an export for the user. If one is already queued or
+// being built it is returned instead of queueing another.
+func (s *DefaultDataExportService) RequestExport(ctx context.Context, userID uuid.UUID, requestedBy uuid.UUID) (resp response.DataExportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "DataExportService.RequestExport")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.userRepository.GetUserById(ctx, userID, repository.IncludeDeleted()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return response.DataExportResponse{}, ErrUserNotFound
+		}
+		return response.DataExportResponse{}, err
+	}
+
+	open, err := s.dataExportRepository.GetOpenExportByUserId(ctx, userID)
+	if err == nil {
+		return s.toDataExportResponse(open), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return response.DataExportResponse{}, err
+	}
+
+	export, err := s.dataExportRepository.InsertExport(ctx, repository.DataExportEntity{
+		Id:          uuid.New(),
+		UserId:      userID,
+		RequestedBy: &requestedBy,
+		Status:      repository.DataExportStatusPending,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return response.DataExportResponse{}, err
+	}
+	return s.toDataExportResponse(export), nil
+}
+
+func (s *DefaultDataExportService) GetExports(ctx context.Context, userID uuid.UUID) (resp []response.DataExportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "DataExportService.GetExports")
+	defer func() { tracing.End(span, err) }()
+
+	exports, err := s.dataExportRepository.GetExportsByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]response.DataExportResponse, 0, len(exports))
+	for _, export := range exports {
+		responses = append(responses, s.toDataExportResponse(export))
+	}
+	return responses, nil
+}
+
+func (s *DefaultDataExportService) GetExport(ctx context.Context, userID uuid.UUID, id uuid.UUID) (resp response.DataExportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "DataExportService.GetExport")
+	defer func() { tracing.End(span, err) }()
+
+	export, err := s.dataExportRepository.GetExportById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return response.DataExportResponse{}, ErrDataExportNotFound
+		}
+		return response.DataExportResponse{}, err
+	}
+	if export.UserId != userID {
+		return response.DataExportResponse{}, ErrDataExportNotFound
+	}
+	return s.toDataExportResponse(export), nil
+}
+
+// Download opens the archive for a signed download token. The token is
+// stateless; the export row decides whether the archive is still available.
+// The caller closes the archive.
+func (s *DefaultDataExportService) Download(ctx context.Context, token string) (filename string, archive io.ReadCloser, err error) {
+	ctx, span := tracing.Start(ctx, "DataExportService.Download")
+	defer func() { tracing.End(span, err) }()
+
+	id, err := s.jwtService.ValidateActionToken(token, dataExportTokenPurpose)
+	if err != nil {
+		return "", nil, ErrActionTokenInvalid
+	}
+
+	export, err := s.dataExportRepository.GetExportById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, ErrDataExportNotFound
+		}
+		return "", nil, err
+	}
+	if export.Status != repository.DataExportStatusCompleted || export.ArchiveKey == "" {
+		return "", nil, ErrDataExportNotReady
+	}
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now()) {
+		return "", nil, ErrDataExportNotReady
+	}
+
+	archive, _, err = s.blobStore.Get(ctx, export.ArchiveKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return "", nil, ErrDataExportNotReady
+		}
+		return "", nil, err
+	}
+	return exportFilename(export), archive, nil
+}
+
+// ProcessPending expires old archives and then builds queued exports until
+// none are left or ctx is cancelled. An export interrupted by the
+// cancellation is claimed again once it goes stale.
+func (s *DefaultDataExportService) ProcessPending(ctx context.Context) error {
+	logger := logging.NewSugaredLogger("data_export")
+
+	expired, err := s.dataExportRepository.ExpireExports(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range expired {
+		if err := s.blobStore.Delete(ctx, key); err != nil {
+			logger.Errorw("Failed to delete data export archive", "key", key, "error", err)
+		}
+	}
+	if len(expired) > 0 {
+		logger.Infow("Expired data exports", "count", len(expired))
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		export, err := s.dataExportRepository.ClaimNextExport(ctx, s.exportConfig.StaleAfter)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		if err := s.process(ctx, export); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Errorw("Failed to build data export", "export_id", export.Id, "error", err)
+			if err := s.dataExportRepository.FailExport(ctx, export.Id, dataExportFailedReason); err != nil {
+				return err
+			}
+			continue
+		}
+		logger.Infow("Built data export", "export_id", export.Id, "user_id", export.UserId)
+	}
+}
+
+func (s *DefaultDataExportService) process(ctx context.Context, export repository.DataExportEntity) (err error) {
+	ctx, span := tracing.Start(ctx, "DataExportService.process")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, export.UserId, repository.IncludeDeleted())
+	if err != nil {
+		return err
+	}
+
+	archive, err := s.buildArchive(ctx, export, user)
+	if err != nil {
+		return err
+	}
+
+	key := exportBlobKey(export)
+	if err := s.blobStore.Put(ctx, key, bytes.NewReader(archive), int64(len(archive)), "application/zip"); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.exportConfig.TTL)
+	if err := s.dataExportRepository.CompleteExport(ctx, export.Id, key, int64(len(archive)), expiresAt); err != nil {
+		if err := s.blobStore.Delete(context.WithoutCancel(ctx), key); err != nil {
+			logging.NewSugaredLogger("data_export").Errorw("Failed to delete data export archive", "key", key, "error", err)
+		}
+		return err
+	}
+	export.ExpiresAt = &expiresAt
+
+	// The archive is ready either way; a failed email only means the user has
+	// to fetch the link from the API.
+	if err := s.sendReadyEmail(ctx, user, export); err != nil {
+		logging.NewSugaredLogger("data_export").Errorw("Failed to send data export email", "export_id", export.Id, "error", err)
+	}
+	return nil
+}
+
+// DeleteArchives removes the user's archives from the blob store. The account
+// purge calls it before the export rows that point at them are deleted.
+func (s *DefaultDataExportService) DeleteArchives(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "DataExportService.DeleteArchives")
+	defer func() { tracing.End(span, err) }()
+
+	exports, err := s.dataExportRepository.GetExportsByUserId(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.ArchiveKey == "" {
+			continue
+		}
+		if err := s.blobStore.Delete(ctx, export.ArchiveKey); err != nil {
+			return fmt.Errorf("delete archive of export %s: %w", export.Id, err)
+		}
+	}
+	return nil
+}
+
+// exportSection is one JSON file in the archive.
+type exportSection struct {
+	name    string
+	collect func(ctx context.Context, user repository.UserEntity) (any, error)
+}
+
+func (s *DefaultDataExportService) sections() []exportSection {
+	return []exportSection{
+		{name: "profile.json", collect: s.collectProfile},
+		{name: "categories.json", collect: s.collectCategories},
+		{name: "todos.json", collect: s.collectTodos},
+		{name: "authored_categories.json", collect: s.collectAuthoredCategories},
+		{name: "authored_todos.json", collect: s.collectAuthoredTodos},
+		{name: "passkeys.json", collect: s.collectPasskeys},
+		{name: "email_links.json", collect: s.collectEmailLinks},
+		{name: "data_exports.json", collect: s.collectDataExports},
+	}
+}
+
+func (s *DefaultDataExportService) buildArchive(ctx context.Context, export repository.DataExportEntity, user repository.UserEntity) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	sections := s.sections()
+	files := make([]string, 0, len(sections))
+	for _, section := range sections {
+		data, err := section.collect(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("collect %s: %w", section.name, err)
+		}
+		if err := writeJSONFile(zw, section.name, data); err != nil {
+			return nil, err
+		}
+		files = append(files, section.name)
+	}
+
+	manifest := map[string]any{
+		"export_id":    export.Id,
+		"user_id":      user.Id,
+		"generated_at": time.Now().UTC(),
+		"files":        files,
+	}
+	if err := writeJSONFile(zw, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONFile(zw *zip.Writer, name string, data any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+func (s *DefaultDataExportService) collectProfile(_ context.Context, user repository.UserEntity) (any, error) {
+	profile := toUserResponse(user, s.avatars)
+	return struct {
+		response.NewUserResponse
+		HasPassword bool `json:"has_password"`
+	}{profile, user.Password != ""}, nil
+}
+
+type exportedCategory struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	Name           string     `json:"name"`
+	Color          string     `json:"color,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (s *DefaultDataExportService) collectCategories(ctx context.Context, user repository.UserEntity) (any, error) {
+	categories, err := s.categoryRepository.GetAllCategories(ctx, repository.PersonalWorkspace(user.Id), repository.IncludeDeleted())
+	if err != nil {
+		return nil, err
+	}
+	return toExportedCategories(categories), nil
+}
+
+// collectAuthoredCategories lists the categories the user created in
+// organization workspaces.
+func (s *DefaultDataExportService) collectAuthoredCategories(ctx context.Context, user repository.UserEntity) (any, error) {
+	categories, err := s.categoryRepository.GetAuthoredCategories(ctx, user.Id, repository.IncludeDeleted())
+	if err != nil {
+		return nil, err
+	}
+	return toExportedCategories(categories), nil
+}
+
+func toExportedCategories(categories []repository.CategoryEntity) []exportedCategory {
+	out := make([]exportedCategory, 0, len(categories))
+	for _, c := range categories {
+		out = append(out, exportedCategory{
+			ID:             c.Id,
+			OrganizationID: c.OrganizationId,
+			Name:           c.Name,
+			Color:          c.Color,
+			Description:    c.Description,
+			CreatedAt:      c.CreatedAt,
+			UpdatedAt:      c.UpdatedAt,
+			DeletedAt:      optionalTime(c.DeletedAt),
+		})
+	}
+	return out
+}
+
+type exportedTodo struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	CategoryID     *uuid.UUID `json:"category_id,omitempty"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description,omitempty"`
+	Priority       string     `json:"priority"`
+	Status         string     `json:"status"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (s *DefaultDataExportService) collectTodos(ctx context.Context, user repository.UserEntity) (any, error) {
+	todos, err := s.todoRepository.GetAllTodos(ctx, repository.PersonalWorkspace(user.Id), repository.IncludeDeleted())
+	if err != nil {
+		return nil, err
+	}
+	return toExportedTodos(todos), nil
+}
+
+// collectAuthoredTodos lists the todos the user created outside their
+// personal workspace: in organizations and in categories shared with them.
+func (s *DefaultDataExportService) collectAuthoredTodos(ctx context.Context, user repository.UserEntity) (any, error) {
+	todos, err := s.todoRepository.GetAuthoredTodos(ctx, user.Id, repository.IncludeDeleted())
+	if err != nil {
+		return nil, err
+	}
+	return toExportedTodos(todos), nil
+}
+
+func toExportedTodos(todos []repository.TodoEntity) []exportedTodo {
+	out := make([]exportedTodo, 0, len(todos))
+	for _, t := range todos {
+		out = append(out, exportedTodo{
+			ID:             t.Id,
+			OrganizationID: t.OrganizationId,
+			CategoryID:     t.CategoryId,
+			Title:          t.Title,
+			Description:    t.Description,
+			Priority:       t.Priority,
+			Status:         t.Status,
+			DueDate:        t.DueDate,
+			CompletedAt:    t.CompletedAt,
+			CreatedAt:      t.CreatedAt,
+			UpdatedAt:      t.UpdatedAt,
+			DeletedAt:      optionalTime(t.DeletedAt),
+		})
+	}
+	return out
+}
+
+// collectPasskeys lists registered passkeys. Key material is left out; it is
+// meaningless outside this service.
+func (s *DefaultDataExportService) collectPasskeys(ctx context.Context, user repository.UserEntity) (any, error) {
+	credentials, err := s.passkeyRepository.GetCredentialsByUserId(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]response.PasskeyResponse, 0, len(credentials))
+	for _, c := range credentials {
+		out = append(out, toPasskeyResponse(c))
+	}
+	return out, nil
+}
+
+type exportedEmailLink struct {
+	Purpose    string     `json:"purpose"`
+	Email      string     `json:"email"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+}
+
+// collectEmailLinks lists the sign-in and action links emailed to the user.
+func (s *DefaultDataExportService) collectEmailLinks(ctx context.Context, user repository.UserEntity) (any, error) {
+	tokens, err := s.actionTokenRepository.GetTokensByUserId(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]exportedEmailLink, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, exportedEmailLink{
+			Purpose:    t.Purpose,
+			Email:      t.Email,
+			CreatedAt:  t.CreatedAt,
+			ExpiresAt:  t.ExpiresAt,
+			ConsumedAt: t.ConsumedAt,
+		})
+	}
+	return out, nil
+}
+
+type exportedDataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	RequestedBy *uuid.UUID `json:"requested_by,omitempty"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// collectDataExports records earlier access requests, including who made them.
+func (s *DefaultDataExportService) collectDataExports(ctx context.Context, user repository.UserEntity) (any, error) {
+	exports, err := s.dataExportRepository.GetExportsByUserId(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]exportedDataExport, 0, len(exports))
+	for _, e := range exports {
+		out = append(out, exportedDataExport{
+			ID:          e.Id,
+			RequestedBy: e.RequestedBy,
+			Status:      e.Status,
+			CreatedAt:   e.CreatedAt,
+			CompletedAt: e.CompletedAt,
+		})
+	}
+	return out, nil
+}
+
+func (s *DefaultDataExportService) sendReadyEmail(ctx context.Context, user repository.UserEntity, export repository.DataExportEntity) error {
+	link, err := s.downloadURL(export)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: "Hi " + user.Name + ",\n\n" +
+			"The export of your personal data is ready. Download it here:\n\n" +
+			link + "\n\n" +
+			"The link expires on " + export.ExpiresAt.UTC().Format(time.RFC1123) + ".\n",
+	})
+}
+
+func (s *DefaultDataExportService) downloadURL(export repository.DataExportEntity) (string, error) {
+	token, err := s.jwtService.GenerateActionToken(export.Id, dataExportTokenPurpose, *export.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	return s.config.PublicURL + "/api/" + s.config.APIVersion + "/exports/download?token=" + token, nil
+}
+
+func (s *DefaultDataExportService) toDataExportResponse(export repository.DataExportEntity) response.DataExportResponse {
+	resp := response.DataExportResponse{
+		ID:          export.Id.String(),
+		UserID:      export.UserId.String(),
+		Status:      export.Status,
+		SizeBytes:   export.SizeBytes,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+
+	// Rows written before the cause was kept out of the record may still
+	// hold it, so failed exports always get the generic reason.
+	if export.Status == repository.DataExportStatusFailed {
+		resp.Error = dataExportFailedReason
+	}
+	if export.Status == repository.DataExportStatusCompleted && export.ExpiresAt != nil {
+		if link, err := s.downloadURL(export); err == nil {
+			resp.DownloadURL = link
+		}
+	}
+	return resp
+}
+
+func exportBlobKey(export repository.DataExportEntity) string {
+	return "exports/" + export.UserId.String() + "/" + export.Id.String() + ".zip"
+}
+
+func exportFilename(export repository.DataExportEntity) string {
+	return "data-export-" + export.Id.String() + ".zip"
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+var ErrEmailUnchanged = exception.NewError("email_unchanged", "new email is the same as the current one")
+
+// EmailChangeService moves a user to a new email address once the new
+// address is confirmed. The old address is told about the request and can
+// undo a completed change for a while afterwards.
+type EmailChangeService interface {
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, req request.EmailChangeRequest) (response.EmailChangeResponse, error)
+	ConfirmEmailChange(ctx context.Context, req request.EmailChangeTokenRequest) (response.NewUserResponse, error)
+	RevertEmailChange(ctx context.Context, req request.EmailChangeTokenRequest) (response.NewUserResponse, error)
+}
+
+type DefaultEmailChangeService struct {
+	config                runtime.EmailChangeConfig
+	userRepository        repository.UserRepository
+	actionTokenRepository repository.ActionTokenRepository
+	actionTokenService    ActionTokenService
+	avatars               AvatarURLResolver
+	mailer                mailer.Mailer
+}
+
+func NewEmailChangeService(config runtime.EmailChangeConfig, userRepository repository.UserRepository, actionTokenRepository repository.ActionTokenRepository, actionTokenService ActionTokenService, avatars AvatarURLResolver, mailer mailer.Mailer) EmailChangeService {
+	return &DefaultEmailChangeService{
+		config:                config,
+		userRepository:        userRepository,
+		actionTokenRepository: actionTokenRepository,
+		actionTokenService:    actionTokenService,
+		avatars:               avatars,
+		mailer:                mailer,
+	}
+}
+
+// RequestEmailChange sends a confirmation link to the new address and a
+// notice to the current one. Only the latest request can be confirmed.
+func (s *DefaultEmailChangeService) RequestEmailChange(ctx context.Context, userID uuid.UUID, req request.EmailChangeRequest) (resp response.EmailChangeResponse, err error) {
+	ctx, span := tracing.Start(ctx, "EmailChangeService.RequestEmailChange")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.EmailChangeResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.EmailChangeResponse{}, err
+	}
+	if req.NewEmail == user.Email {
+		return response.EmailChangeResponse{}, ErrEmailUnchanged
+	}
+	if err := s.checkEmailAvailable(ctx, userID, req.NewEmail); err != nil {
+		return response.EmailChangeResponse{}, err
+	}
+
+	if err := s.actionTokenRepository.RevokeTokens(ctx, userID, repository.ActionTokenPurposeEmailChange); err != nil {
+		return response.EmailChangeResponse{}, err
+	}
+	token, err := s.actionTokenService.Issue(ctx, repository.ActionTokenPurposeEmailChange, &user.Id, req.NewEmail,
+		map[string]string{"old_email": user.Email}, s.config.TTL)
+	if err != nil {
+		return response.EmailChangeResponse{}, err
+	}
+
+	link := s.config.ConfirmURL + "?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      req.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Use the link below to make this the email address of your account. It expires in %s.\n\n%s\n\n"+
+			"If you did not request this change you can ignore this email.", s.config.TTL, link),
+	})
+	if err != nil {
+		return response.EmailChangeResponse{}, err
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the email address of your account to %s. "+
+			"Nothing changes until the new address is confirmed.\n\n"+
+			"If this was not you, sign in and secure your account.", req.NewEmail),
+	})
+	if err != nil {
+		return response.EmailChangeResponse{}, err
+	}
+
+	return response.EmailChangeResponse{
+		Message:   "A confirmation link has been sent to the new address",
+		NewEmail:  req.NewEmail,
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}, nil
+}
+
+// ConfirmEmailChange swaps the address once the new one is confirmed and
+// sends the old address a link that reverts the change.
+func (s *DefaultEmailChangeService) ConfirmEmailChange(ctx context.Context, req request.EmailChangeTokenRequest) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "EmailChangeService.ConfirmEmailChange")
+	defer func() { tracing.End(span, err) }()
+
+	token, err := s.actionTokenService.Consume(ctx, req.Token, repository.ActionTokenPurposeEmailChange)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	user, err := s.tokenUser(ctx, token)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	// The link is stale once the account has moved to another address.
+	oldEmail := token.Payload["old_email"]
+	if user.Email != oldEmail {
+		return response.NewUserResponse{}, ErrActionTokenInvalid
+	}
+	if err := s.changeEmail(ctx, user.Id, oldEmail, token.Email); err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	revertToken, err := s.actionTokenService.Issue(ctx, repository.ActionTokenPurposeEmailChangeRevert, &user.Id, oldEmail,
+		map[string]string{"new_email": token.Email}, s.config.RevertWindow)
+	if err == nil {
+		link := s.config.RevertURL + "?token=" + url.QueryEscape(revertToken)
+		err = s.mailer.Send(ctx, mailer.Message{
+			To:      oldEmail,
+			Subject: "Your email address was changed",
+			Body: fmt.Sprintf("The email address of your account is now %s.\n\n"+
+				"If this was not you, use the link below within %s to switch back to this address.\n\n%s",
+				token.Email, s.config.RevertWindow, link),
+		})
+	}
+	if err != nil {
+		// The change itself succeeded; only the undo link is missing.
+		logging.NewSugaredLogger("email_change").Errorw("Failed to send email change revert link", "user_id", user.Id, "error", err)
+	}
+
+	return s.reload(ctx, user.Id)
+}
+
+// RevertEmailChange moves the account back to the address that received the
+// revert link, whatever it was changed to since, and cancels every other
+// pending change or revert.
+func (s *DefaultEmailChangeService) RevertEmailChange(ctx context.Context, req request.EmailChangeTokenRequest) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "EmailChangeService.RevertEmailChange")
+	defer func() { tracing.End(span, err) }()
+
+	token, err := s.actionTokenService.Consume(ctx, req.Token, repository.ActionTokenPurposeEmailChangeRevert)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	user, err := s.tokenUser(ctx, token)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	if user.Email != token.Email {
+		if err := s.changeEmail(ctx, user.Id, user.Email, token.Email); err != nil {
+			return response.NewUserResponse{}, err
+		}
+	}
+	err = s.actionTokenRepository.RevokeTokens(ctx, user.Id, repository.ActionTokenPurposeEmailChange, repository.ActionTokenPurposeEmailChangeRevert)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	return s.reload(ctx, user.Id)
+}
+
+func (s *DefaultEmailChangeService) tokenUser(ctx context.Context, token repository.ActionTokenEntity) (repository.UserEntity, error) {
+	if token.UserId == nil {
+		return repository.UserEntity{}, ErrActionTokenInvalid
+	}
+	user, err := s.userRepository.GetUserById(ctx, *token.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.UserEntity{}, ErrActionTokenInvalid
+	}
+	return user, err
+}
+
+// changeEmail re-checks that the address is free at the moment of the swap;
+// it may have been taken since the link was sent.
+func (s *DefaultEmailChangeService) changeEmail(ctx context.Context, userID uuid.UUID, oldEmail, newEmail string) error {
+	if err := s.checkEmailAvailable(ctx, userID, newEmail); err != nil {
+		return err
+	}
+	changed, err := s.userRepository.ChangeEmail(ctx, userID, oldEmail, newEmail)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		if err := s.checkEmailAvailable(ctx, userID, newEmail); err != nil {
+			return err
+		}
+		return ErrActionTokenInvalid
+	}
+	return nil
+}
+
+// checkEmailAvailable reports ErrEmailTaken if another account holds the
+// address. Soft-deleted accounts count because they can be restored.
+func (s *DefaultEmailChangeService) checkEmailAvailable(ctx context.Context, userID uuid.UUID, email string) error {
+	existing, err := s.userRepository.GetUserByEmail(ctx, email, repository.IncludeDeleted())
+	if err == nil && existing.Id != userID {
+		return ErrEmailTaken
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+func (s *DefaultEmailChangeService) reload(ctx context.Context, userID uuid.UUID) (response.NewUserResponse, error) {
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	return toUserResponse(user, s.avatars), nil
+}
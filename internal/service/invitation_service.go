@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvitationNotFound = exception.NewError("invitation_not_found", "user has no pending invitation")
+
+// InvitationService onboards users created by an admin. The account stays
+// inactive until the user follows the emailed link and sets a password.
+type InvitationService interface {
+	InviteUser(ctx context.Context, inviterID uuid.UUID, req request.NewUserRequest) (response.NewUserResponse, error)
+	ResendInvitation(ctx context.Context, userID uuid.UUID) (response.NewUserResponse, error)
+	RevokeInvitation(ctx context.Context, userID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, req request.AcceptInvitationRequest) (response.SignInResponse, error)
+}
+
+type DefaultInvitationService struct {
+	config                 runtime.InvitationConfig
+	userRepository         repository.UserRepository
+	actionTokenRepository  repository.ActionTokenRepository
+	actionTokenService     ActionTokenService
+	jwtService             JWTService
+	accountDeletionService AccountDeletionService
+	avatars                AvatarURLResolver
+	mailer                 mailer.Mailer
+}
+
+func NewInvitationService(config runtime.InvitationConfig, userRepository repository.UserRepository, actionTokenRepository repository.ActionTokenRepository, actionTokenService ActionTokenService, jwtService JWTService, accountDeletionService AccountDeletionService, avatars AvatarURLResolver, mailer mailer.Mailer) InvitationService {
+	return &DefaultInvitationService{
+		config:                 config,
+		userRepository:         userRepository,
+		actionTokenRepository:  actionTokenRepository,
+		actionTokenService:     actionTokenService,
+		jwtService:             jwtService,
+		accountDeletionService: accountDeletionService,
+		avatars:                avatars,
+		mailer:                 mailer,
+	}
+}
+
+// InviteUser creates the user as a pending, inactive account and emails them
+// an invite link.
+func (s *DefaultInvitationService) InviteUser(ctx context.Context, inviterID uuid.UUID, req request.NewUserRequest) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.InviteUser")
+	defer func() { tracing.End(span, err) }()
+
+	// Soft-deleted accounts still hold their address because they can be restored
+	_, err = s.userRepository.GetUserByEmail(ctx, req.Email, repository.IncludeDeleted())
+	if err == nil {
+		return response.NewUserResponse{}, ErrEmailTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, err
+	}
+
+	now := time.Now()
+	user, err := s.userRepository.InsertUser(ctx, repository.UserEntity{
+		Id:        uuid.New(),
+		Name:      req.Name,
+		Email:     req.Email,
+		IsActive:  false,
+		InvitedAt: &now,
+		InvitedBy: &inviterID,
+	})
+	if errors.Is(err, exception.ErrConflict) {
+		return response.NewUserResponse{}, ErrEmailTaken
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	if err := s.sendInvitation(ctx, user); err != nil {
+		return response.NewUserResponse{}, err
+	}
+	return s.reload(ctx, user.Id)
+}
+
+// ResendInvitation emails a fresh invite link; links sent earlier stop working.
+func (s *DefaultInvitationService) ResendInvitation(ctx context.Context, userID uuid.UUID) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.ResendInvitation")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.pendingUser(ctx, userID)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+
+	renewed, err := s.userRepository.RenewInvitation(ctx, userID)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	if !renewed {
+		return response.NewUserResponse{}, ErrInvitationNotFound
+	}
+
+	if err := s.sendInvitation(ctx, user); err != nil {
+		return response.NewUserResponse{}, err
+	}
+	return s.reload(ctx, user.Id)
+}
+
+// RevokeInvitation deletes the pending user together with its invite links.
+func (s *DefaultInvitationService) RevokeInvitation(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.RevokeInvitation")
+	defer func() { tracing.End(span, err) }()
+
+	deleted, err := s.userRepository.DeleteInvitedUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation sets the invited user's password, activates the account
+// and signs the user in.
+func (s *DefaultInvitationService) AcceptInvitation(ctx context.Context, req request.AcceptInvitationRequest) (resp response.SignInResponse, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.AcceptInvitation")
+	defer func() { tracing.End(span, err) }()
+
+	token, err := s.actionTokenService.Consume(ctx, req.Token, repository.ActionTokenPurposeInvitation)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+	if token.UserId == nil {
+		return response.SignInResponse{}, ErrActionTokenInvalid
+	}
+
+	user, err := s.pendingUser(ctx, *token.UserId)
+	if errors.Is(err, ErrInvitationNotFound) || errors.Is(err, ErrUserNotFound) || (err == nil && user.Email != token.Email) {
+		return response.SignInResponse{}, ErrActionTokenInvalid
+	}
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+	accepted, err := s.userRepository.AcceptInvitation(ctx, user.Id, req.Name, string(hashedPassword))
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+	if !accepted {
+		return response.SignInResponse{}, ErrActionTokenInvalid
+	}
+
+	user, err = s.userRepository.GetUserById(ctx, user.Id)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+	return newSignInResponse(ctx, s.jwtService, s.accountDeletionService, s.avatars, user)
+}
+
+func (s *DefaultInvitationService) sendInvitation(ctx context.Context, user repository.UserEntity) error {
+	if err := s.actionTokenRepository.RevokeTokens(ctx, user.Id, repository.ActionTokenPurposeInvitation); err != nil {
+		return err
+	}
+	token, err := s.actionTokenService.Issue(ctx, repository.ActionTokenPurposeInvitation, &user.Id, user.Email, nil, s.config.TTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.config.URL + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf("An account has been created for you. Use the link below to choose a password and activate it. "+
+			"The link expires in %s and can only be used once.\n\n%s", s.config.TTL, link),
+	})
+}
+
+func (s *DefaultInvitationService) pendingUser(ctx context.Context, userID uuid.UUID) (repository.UserEntity, error) {
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.UserEntity{}, ErrUserNotFound
+	}
+	if err != nil {
+		return repository.UserEntity{}, err
+	}
+	if user.InvitedAt == nil {
+		return repository.UserEntity{}, ErrInvitationNotFound
+	}
+	return user, nil
+}
+
+func (s *DefaultInvitationService) reload(ctx context.Context, userID uuid.UUID) (response.NewUserResponse, error) {
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	return toUserResponse(user, s.avatars), nil
+}
@@ -10,10 +10,13 @@ import (
 )
 
 type JWTService interface {
-	GenerateToken(userID uuid.UUID, email string) (string, error)
+	GenerateToken(userID uuid.UUID, email string, organizationID *uuid.UUID) (string, error)
 	GenerateRefreshToken(userID uuid.UUID) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	ExtractUserID(token *jwt.Token) (uuid.UUID, error)
+	ExtractOrganizationID(token *jwt.Token) *uuid.UUID
+	GenerateActionToken(id uuid.UUID, purpose string, expiresAt time.Time) (string, error)
+	ValidateActionToken(tokenString string, purpose string) (uuid.UUID, error)
 }
 
 type DefaultJWTService struct {
@@ -27,13 +30,17 @@ func NewJWTService(config runtime.JWTConfig) JWTService {
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	// OrganizationID is the active organization; without it the token works
+	// in the user's personal workspace.
+	OrganizationID *uuid.UUID `json:"org_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (s *DefaultJWTService) GenerateToken(userID uuid.UUID, email string) (string, error) {
+func (s *DefaultJWTService) GenerateToken(userID uuid.UUID, email string, organizationID *uuid.UUID) (string, error) {
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:         userID,
+		Email:          email,
+		OrganizationID: organizationID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.config.Expiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -77,3 +84,52 @@ func (s *DefaultJWTService) ExtractUserID(token *jwt.Token) (uuid.UUID, error) {
 	}
 	return claims.UserID, nil
 }
+
+// ExtractOrganizationID returns the active organization of a validated
+// access token, or nil for the personal workspace.
+func (s *DefaultJWTService) ExtractOrganizationID(token *jwt.Token) *uuid.UUID {
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil
+	}
+	return claims.OrganizationID
+}
+
+// GenerateActionToken signs a token for an emailed link such as a magic link.
+// Action tokens are signed with a key derived from the purpose, so they are
+// never accepted as access tokens or for a different purpose.
+func (s *DefaultJWTService) GenerateActionToken(id uuid.UUID, purpose string, expiresAt time.Time) (string, error) {
+	claims := &jwt.RegisteredClaims{
+		ID:        id.String(),
+		Subject:   purpose,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    "user-app",
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.actionKey(purpose))
+}
+
+// ValidateActionToken verifies an action token for the given purpose and
+// returns the id it was issued for.
+func (s *DefaultJWTService) ValidateActionToken(tokenString string, purpose string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return s.actionKey(purpose), nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !token.Valid || claims.Subject != purpose {
+		return uuid.Nil, errors.New("invalid token")
+	}
+	return uuid.Parse(claims.ID)
+}
+
+func (s *DefaultJWTService) actionKey(purpose string) []byte {
+	return []byte(s.config.SecretKey + ":" + purpose)
+}
@@ -0,0 +1,428 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/mailer"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var (
+	ErrOrganizationNotFound       = exception.NewError("organization_not_found", "organization not found")
+	ErrOrganizationForbidden      = exception.NewError("organization_forbidden", "your role in the organization does not allow this")
+	ErrOrganizationMemberNotFound = exception.NewError("organization_member_not_found", "organization member not found")
+	ErrLastOrganizationOwner      = exception.NewError("last_organization_owner", "an organization needs at least one owner")
+	ErrAlreadyOrganizationMember  = exception.NewError("already_organization_member", "user is already a member of the organization")
+)
+
+// OrganizationService manages organizations, their members and the workspace
+// a session works in. Organizations are reported as not found to users who
+// are not members, so their existence is not revealed.
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, userID uuid.UUID, req request.OrganizationRequest) (response.OrganizationResponse, error)
+	GetOrganizations(ctx context.Context, userID uuid.UUID) ([]response.OrganizationResponse, error)
+	GetOrganization(ctx context.Context, userID, organizationID uuid.UUID) (response.OrganizationResponse, error)
+	UpdateOrganization(ctx context.Context, userID, organizationID uuid.UUID, req request.OrganizationRequest) (response.OrganizationResponse, error)
+	DeleteOrganization(ctx context.Context, userID, organizationID uuid.UUID) error
+	GetMembers(ctx context.Context, userID, organizationID uuid.UUID) ([]response.OrganizationMemberResponse, error)
+	UpdateMemberRole(ctx context.Context, userID, organizationID, memberID uuid.UUID, req request.OrganizationMemberRoleRequest) error
+	RemoveMember(ctx context.Context, userID, organizationID, memberID uuid.UUID) error
+	InviteMember(ctx context.Context, userID, organizationID uuid.UUID, req request.OrganizationInviteRequest) (response.OrganizationInvitationResponse, error)
+	AcceptInvitation(ctx context.Context, userID uuid.UUID, req request.OrganizationInvitationTokenRequest) (response.OrganizationResponse, error)
+	SwitchWorkspace(ctx context.Context, userID uuid.UUID, organizationID *uuid.UUID) (response.SignInResponse, error)
+	ResolveWorkspace(ctx context.Context, userID uuid.UUID, organizationID *uuid.UUID) (repository.Workspace, error)
+}
+
+type DefaultOrganizationService struct {
+	config                 runtime.InvitationConfig
+	organizationRepository repository.OrganizationRepository
+	userRepository         repository.UserRepository
+	actionTokenService     ActionTokenService
+	jwtService             JWTService
+	avatars                AvatarURLResolver
+	mailer                 mailer.Mailer
+}
+
+func NewOrganizationService(config runtime.InvitationConfig, organizationRepository repository.OrganizationRepository, userRepository repository.UserRepository, actionTokenService ActionTokenService, jwtService JWTService, avatars AvatarURLResolver, mailer mailer.Mailer) OrganizationService {
+	return &DefaultOrganizationService{
+		config:                 config,
+		organizationRepository: organizationRepository,
+		userRepository:         userRepository,
+		actionTokenService:     actionTokenService,
+		jwtService:             jwtService,
+		avatars:                avatars,
+		mailer:                 mailer,
+	}
+}
+
+func (s *DefaultOrganizationService) CreateOrganization(ctx context.Context, userID uuid.UUID, req request.OrganizationRequest) (resp response.OrganizationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.CreateOrganization")
+	defer func() { tracing.End(span, err) }()
+
+	organization, err := s.organizationRepository.CreateOrganization(ctx, repository.OrganizationEntity{
+		Id:   uuid.New(),
+		Name: req.Name,
+	}, userID)
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	return toOrganizationResponse(organization, repository.OrganizationRoleOwner), nil
+}
+
+func (s *DefaultOrganizationService) GetOrganizations(ctx context.Context, userID uuid.UUID) (resp []response.OrganizationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganizations")
+	defer func() { tracing.End(span, err) }()
+
+	organizations, err := s.organizationRepository.GetOrganizationsByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]response.OrganizationResponse, 0, len(organizations))
+	for _, organization := range organizations {
+		responses = append(responses, toOrganizationResponse(organization.OrganizationEntity, organization.Role))
+	}
+	return responses, nil
+}
+
+func (s *DefaultOrganizationService) GetOrganization(ctx context.Context, userID, organizationID uuid.UUID) (resp response.OrganizationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganization")
+	defer func() { tracing.End(span, err) }()
+
+	member, err := s.member(ctx, organizationID, userID)
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	organization, err := s.organizationRepository.GetOrganizationById(ctx, organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.OrganizationResponse{}, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	return toOrganizationResponse(organization, member.Role), nil
+}
+
+func (s *DefaultOrganizationService) UpdateOrganization(ctx context.Context, userID, organizationID uuid.UUID, req request.OrganizationRequest) (resp response.OrganizationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateOrganization")
+	defer func() { tracing.End(span, err) }()
+
+	member, err := s.member(ctx, organizationID, userID, repository.OrganizationRoleOwner, repository.OrganizationRoleAdmin)
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	organization, err := s.organizationRepository.UpdateOrganization(ctx, organizationID, req.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.OrganizationResponse{}, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	return toOrganizationResponse(organization, member.Role), nil
+}
+
+// DeleteOrganization deletes the organization with all of its categories and
+// todos. Only owners can do this.
+func (s *DefaultOrganizationService) DeleteOrganization(ctx context.Context, userID, organizationID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.DeleteOrganization")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.member(ctx, organizationID, userID, repository.OrganizationRoleOwner); err != nil {
+		return err
+	}
+	deleted, err := s.organizationRepository.DeleteOrganization(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+func (s *DefaultOrganizationService) GetMembers(ctx context.Context, userID, organizationID uuid.UUID) (resp []response.OrganizationMemberResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetMembers")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.member(ctx, organizationID, userID); err != nil {
+		return nil, err
+	}
+	members, err := s.organizationRepository.GetMembers(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]response.OrganizationMemberResponse, 0, len(members))
+	for _, member := range members {
+		resp := response.OrganizationMemberResponse{
+			UserID:   member.UserId.String(),
+			Role:     member.Role,
+			JoinedAt: member.CreatedAt,
+		}
+		// Soft-deleted users keep their membership but not their profile
+		if member.User != nil && member.User.Id != uuid.Nil {
+			resp.Name = member.User.Name
+			resp.Email = member.User.Email
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+// UpdateMemberRole changes a member's role. Admins manage admins and members;
+// only owners can make someone an owner or change an owner's role.
+func (s *DefaultOrganizationService) UpdateMemberRole(ctx context.Context, userID, organizationID, memberID uuid.UUID, req request.OrganizationMemberRoleRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateMemberRole")
+	defer func() { tracing.End(span, err) }()
+
+	actor, err := s.member(ctx, organizationID, userID, repository.OrganizationRoleOwner, repository.OrganizationRoleAdmin)
+	if err != nil {
+		return err
+	}
+	target, err := s.targetMember(ctx, organizationID, memberID)
+	if err != nil {
+		return err
+	}
+	if (req.Role == repository.OrganizationRoleOwner || target.Role == repository.OrganizationRoleOwner) &&
+		actor.Role != repository.OrganizationRoleOwner {
+		return ErrOrganizationForbidden
+	}
+
+	updated, err := s.organizationRepository.UpdateMemberRole(ctx, organizationID, memberID, req.Role)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return s.explainUnchangedMember(ctx, organizationID, memberID)
+	}
+	return nil
+}
+
+// RemoveMember removes a member from the organization. Any member can leave;
+// removing someone else follows the same rules as changing their role.
+func (s *DefaultOrganizationService) RemoveMember(ctx context.Context, userID, organizationID, memberID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RemoveMember")
+	defer func() { tracing.End(span, err) }()
+
+	if userID != memberID {
+		actor, err := s.member(ctx, organizationID, userID, repository.OrganizationRoleOwner, repository.OrganizationRoleAdmin)
+		if err != nil {
+			return err
+		}
+		target, err := s.targetMember(ctx, organizationID, memberID)
+		if err != nil {
+			return err
+		}
+		if target.Role == repository.OrganizationRoleOwner && actor.Role != repository.OrganizationRoleOwner {
+			return ErrOrganizationForbidden
+		}
+	} else if _, err := s.member(ctx, organizationID, userID); err != nil {
+		return err
+	}
+
+	removed, err := s.organizationRepository.RemoveMember(ctx, organizationID, memberID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return s.explainUnchangedMember(ctx, organizationID, memberID)
+	}
+	return nil
+}
+
+// InviteMember emails a link to join the organization. It works for addresses
+// without an account too; the recipient signs up first and then accepts.
+func (s *DefaultOrganizationService) InviteMember(ctx context.Context, userID, organizationID uuid.UUID, req request.OrganizationInviteRequest) (resp response.OrganizationInvitationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.InviteMember")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.member(ctx, organizationID, userID, repository.OrganizationRoleOwner, repository.OrganizationRoleAdmin); err != nil {
+		return response.OrganizationInvitationResponse{}, err
+	}
+	organization, err := s.organizationRepository.GetOrganizationById(ctx, organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.OrganizationInvitationResponse{}, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return response.OrganizationInvitationResponse{}, err
+	}
+
+	invitee, err := s.userRepository.GetUserByEmail(ctx, req.Email)
+	if err == nil {
+		if _, err := s.organizationRepository.GetMember(ctx, organizationID, invitee.Id); err == nil {
+			return response.OrganizationInvitationResponse{}, ErrAlreadyOrganizationMember
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return response.OrganizationInvitationResponse{}, err
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return response.OrganizationInvitationResponse{}, err
+	}
+
+	token, err := s.actionTokenService.Issue(ctx, repository.ActionTokenPurposeOrganizationInvite, nil, req.Email,
+		map[string]string{"organization_id": organizationID.String(), "role": req.Role}, s.config.TTL)
+	if err != nil {
+		return response.OrganizationInvitationResponse{}, err
+	}
+
+	link := s.config.OrganizationURL + "?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      req.Email,
+		Subject: "Join " + organization.Name,
+		Body: fmt.Sprintf("You have been invited to join %s as %s. Sign in with this email address and use the link below to accept. "+
+			"It expires in %s.\n\n%s", organization.Name, req.Role, s.config.TTL, link),
+	})
+	if err != nil {
+		return response.OrganizationInvitationResponse{}, err
+	}
+
+	return response.OrganizationInvitationResponse{
+		Message:   "An invitation has been sent",
+		Email:     req.Email,
+		Role:      req.Role,
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}, nil
+}
+
+// AcceptInvitation adds the signed-in user to the organization. The link is
+// only checked, not used up, when it was sent to a different address.
+func (s *DefaultOrganizationService) AcceptInvitation(ctx context.Context, userID uuid.UUID, req request.OrganizationInvitationTokenRequest) (resp response.OrganizationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.AcceptInvitation")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.OrganizationResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+
+	token, err := s.actionTokenService.Inspect(ctx, req.Token, repository.ActionTokenPurposeOrganizationInvite)
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	if token.Email != user.Email {
+		return response.OrganizationResponse{}, ErrActionTokenInvalid
+	}
+	token, err = s.actionTokenService.Consume(ctx, req.Token, repository.ActionTokenPurposeOrganizationInvite)
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+
+	organizationID, err := uuid.Parse(token.Payload["organization_id"])
+	if err != nil {
+		return response.OrganizationResponse{}, ErrActionTokenInvalid
+	}
+	organization, err := s.organizationRepository.GetOrganizationById(ctx, organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.OrganizationResponse{}, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+
+	role := token.Payload["role"]
+	added, err := s.organizationRepository.AddMember(ctx, repository.OrganizationMemberEntity{
+		OrganizationId: organizationID,
+		UserId:         userID,
+		Role:           role,
+	})
+	if err != nil {
+		return response.OrganizationResponse{}, err
+	}
+	if !added {
+		return response.OrganizationResponse{}, ErrAlreadyOrganizationMember
+	}
+	return toOrganizationResponse(organization, role), nil
+}
+
+// SwitchWorkspace issues new tokens whose active workspace is the given
+// organization, or the personal workspace when it is nil.
+func (s *DefaultOrganizationService) SwitchWorkspace(ctx context.Context, userID uuid.UUID, organizationID *uuid.UUID) (resp response.SignInResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.SwitchWorkspace")
+	defer func() { tracing.End(span, err) }()
+
+	if organizationID != nil {
+		if _, err := s.member(ctx, *organizationID, userID); err != nil {
+			return response.SignInResponse{}, err
+		}
+	}
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.SignInResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+	return newTokenResponse(s.jwtService, s.avatars, user, organizationID)
+}
+
+// ResolveWorkspace returns the workspace for the organization of an access
+// token, checking that the user still belongs to it.
+func (s *DefaultOrganizationService) ResolveWorkspace(ctx context.Context, userID uuid.UUID, organizationID *uuid.UUID) (workspace repository.Workspace, err error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ResolveWorkspace")
+	defer func() { tracing.End(span, err) }()
+
+	if organizationID == nil {
+		return repository.PersonalWorkspace(userID), nil
+	}
+	if _, err := s.member(ctx, *organizationID, userID); err != nil {
+		return repository.Workspace{}, err
+	}
+	return repository.Workspace{UserId: userID, OrganizationId: organizationID}, nil
+}
+
+// member returns the user's membership, requiring one of roles if any are
+// given. Non-members get ErrOrganizationNotFound.
+func (s *DefaultOrganizationService) member(ctx context.Context, organizationID, userID uuid.UUID, roles ...string) (repository.OrganizationMemberEntity, error) {
+	member, err := s.organizationRepository.GetMember(ctx, organizationID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.OrganizationMemberEntity{}, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return repository.OrganizationMemberEntity{}, err
+	}
+	if len(roles) > 0 && !slices.Contains(roles, member.Role) {
+		return repository.OrganizationMemberEntity{}, ErrOrganizationForbidden
+	}
+	return member, nil
+}
+
+func (s *DefaultOrganizationService) targetMember(ctx context.Context, organizationID, memberID uuid.UUID) (repository.OrganizationMemberEntity, error) {
+	member, err := s.organizationRepository.GetMember(ctx, organizationID, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.OrganizationMemberEntity{}, ErrOrganizationMemberNotFound
+	}
+	return member, err
+}
+
+// explainUnchangedMember tells why a membership was not updated or removed:
+// either it is gone or it belongs to the last owner.
+func (s *DefaultOrganizationService) explainUnchangedMember(ctx context.Context, organizationID, memberID uuid.UUID) error {
+	if _, err := s.targetMember(ctx, organizationID, memberID); err != nil {
+		return err
+	}
+	return ErrLastOrganizationOwner
+}
+
+func toOrganizationResponse(organization repository.OrganizationEntity, role string) response.OrganizationResponse {
+	return response.OrganizationResponse{
+		ID:        organization.Id.String(),
+		Name:      organization.Name,
+		Role:      role,
+		CreatedAt: organization.CreatedAt,
+		UpdatedAt: organization.UpdatedAt,
+	}
+}
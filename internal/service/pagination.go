@@ -0,0 +1,46 @@
+package service
+
+import (
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+)
+
+// toPage turns pagination parameters into a repository page, reading by
+// keyset when a cursor was given.
+func toPage(listReq request.ListRequest) (repository.Page, error) {
+	page := repository.Page{Limit: listReq.GetLimit(), Offset: listReq.GetOffset()}
+
+	cursor, err := listReq.GetCursor()
+	if err != nil {
+		return repository.Page{}, err
+	}
+	if cursor != nil {
+		key := &repository.Cursor{CreatedAt: cursor.CreatedAt, Id: cursor.ID}
+		if cursor.Backward {
+			page.Before = key
+		} else {
+			page.After = key
+		}
+		page.Offset = 0
+	}
+	return page, nil
+}
+
+// newPageResponse builds the list response for a page read with toPage. The
+// total is left out; callers add it with WithTotal when it was asked for.
+func newPageResponse[T any](items []T, listReq request.ListRequest, info repository.PageInfo) response.ListResponse[T] {
+	var next, prev string
+	if info.HasNext && info.Last != nil {
+		next = request.Cursor{CreatedAt: info.Last.CreatedAt, ID: info.Last.Id}.Encode()
+	}
+	if info.HasPrev && info.First != nil {
+		prev = request.Cursor{CreatedAt: info.First.CreatedAt, ID: info.First.Id, Backward: true}.Encode()
+	}
+
+	resp := response.NewCursorListResponse(items, listReq.GetLimit(), next, prev)
+	if listReq.Cursor == "" {
+		resp.Meta.Page = listReq.GetPage()
+	}
+	return resp
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var (
+	ErrPasskeySessionInvalid = exception.NewError("passkey_session_invalid", "passkey session is invalid or expired")
+	ErrPasskeyNotFound       = exception.NewError("passkey_not_found", "passkey not found")
+	ErrPasskeyCloned         = exception.NewError("passkey_cloned", "passkey sign counter did not increase, authenticator may be cloned")
+)
+
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, userID uuid.UUID) (response.PasskeyOptionsResponse, error)
+	FinishRegistration(ctx context.Context, userID uuid.UUID, req request.FinishPasskeyRegistrationRequest) (response.PasskeyResponse, error)
+	BeginLogin(ctx context.Context, req request.BeginPasskeyLoginRequest) (response.PasskeyOptionsResponse, error)
+	FinishLogin(ctx context.Context, req request.FinishPasskeyLoginRequest) (response.SignInResponse, error)
+	GetPasskeys(ctx context.Context, userID uuid.UUID) ([]response.PasskeyResponse, error)
+	DeletePasskey(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type DefaultPasskeyService struct {
+	webAuthn               *webauthn.WebAuthn
+	decoyKey               []byte
+	config                 runtime.WebAuthnConfig
+	userRepository         repository.UserRepository
+	passkeyRepository      repository.PasskeyRepository
+	jwtService             JWTService
+	accountDeletionService AccountDeletionService
+	avatars                AvatarURLResolver
+}
+
+func NewPasskeyService(config runtime.WebAuthnConfig, userRepository repository.UserRepository, passkeyRepository repository.PasskeyRepository, jwtService JWTService, accountDeletionService AccountDeletionService, avatars AvatarURLResolver) (PasskeyService, error) {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          config.RPID,
+		RPDisplayName: config.RPDisplayName,
+		RPOrigins:     config.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: config.SessionTimeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: config.SessionTimeout},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a configured secret the decoys change on every restart.
+	decoyKey := []byte(config.DecoySecret)
+	if len(decoyKey) == 0 {
+		decoyKey = make([]byte, 32)
+		if _, err := rand.Read(decoyKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &DefaultPasskeyService{
+		webAuthn:               webAuthn,
+		decoyKey:               decoyKey,
+		config:                 config,
+		userRepository:         userRepository,
+		passkeyRepository:      passkeyRepository,
+		jwtService:             jwtService,
+		accountDeletionService: accountDeletionService,
+		avatars:                avatars,
+	}, nil
+}
+
+func (s *DefaultPasskeyService) BeginRegistration(ctx context.Context, userID uuid.UUID) (resp response.PasskeyOptionsResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PasskeyService.BeginRegistration")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.loadPasskeyUser(ctx, userID)
+	if err != nil {
+		return response.PasskeyOptionsResponse{}, err
+	}
+
+	creation, session, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return response.PasskeyOptionsResponse{}, err
+	}
+
+	sessionID, err := s.saveSession(ctx, &userID, repository.PasskeyCeremonyRegistration, session)
+	if err != nil {
+		return response.PasskeyOptionsResponse{}, err
+	}
+
+	return response.PasskeyOptionsResponse{SessionID: sessionID.String(), Options: creation}, nil
+}
+
+func (s *DefaultPasskeyService) FinishRegistration(ctx context.Context, userID uuid.UUID, req request.FinishPasskeyRegistrationRequest) (resp response.PasskeyResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PasskeyService.FinishRegistration")
+	defer func() { tracing.End(span, err) }()
+
+	session, err := s.consumeSession(ctx, req.SessionID, repository.PasskeyCeremonyRegistration)
+	if err != nil {
+		return response.PasskeyResponse{}, err
+	}
+
+	user, err := s.loadPasskeyUser(ctx, userID)
+	if err != nil {
+		return response.PasskeyResponse{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return response.PasskeyResponse{}, err
+	}
+
+	credential, err := s.webAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return response.PasskeyResponse{}, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	entity, err := s.passkeyRepository.InsertCredential(ctx, repository.PasskeyCredentialEntity{
+		Id:              uuid.New(),
+		UserId:          userID,
+		CredentialId:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+		CreatedAt:       time.Now(),
+	})
+	if err != nil {
+		return response.PasskeyResponse{}, err
+	}
+
+	return toPasskeyResponse(entity), nil
+}
+
+func (s *DefaultPasskeyService) BeginLogin(ctx context.Context, req request.BeginPasskeyLoginRequest) (resp response.PasskeyOptionsResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PasskeyService.BeginLogin")
+	defer func() { tracing.End(span, err) }()
+
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+		userID    *uuid.UUID
+	)
+
+	if req.Email == "" {
+		assertion, session, err = s.webAuthn.BeginDiscoverableLogin()
+	} else {
+		var entity repository.UserEntity
+		entity, err = s.userRepository.GetUserByEmail(ctx, req.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return response.PasskeyOptionsResponse{}, err
+		}
+
+		user := &passkeyUser{entity: entity}
+		if err == nil {
+			if user, err = s.toPasskeyUser(ctx, entity); err != nil {
+				return response.PasskeyOptionsResponse{}, err
+			}
+		}
+
+		if len(user.credentials) == 0 {
+			// Addresses without passkeys get decoy options shaped like a real
+			// account's, so the response does not reveal which addresses are
+			// registered. No credential can complete the ceremony.
+			assertion, session, err = s.webAuthn.BeginDiscoverableLogin(webauthn.WithAllowedCredentials(s.decoyCredentials(req.Email)))
+		} else {
+			userID = &entity.Id
+			assertion, session, err = s.webAuthn.BeginLogin(user)
+		}
+	}
+	if err != nil {
+		return response.PasskeyOptionsResponse{}, err
+	}
+
+	sessionID, err := s.saveSession(ctx, userID, repository.PasskeyCeremonyLogin, session)
+	if err != nil {
+		return response.PasskeyOptionsResponse{}, err
+	}
+
+	return response.PasskeyOptionsResponse{SessionID: sessionID.String(), Options: assertion}, nil
+}
+
+// decoyCredentials derives the credential a login for an address without
+// passkeys pretends to allow. It is stable per address, so repeated logins
+// cannot tell it apart from a real one.
+func (s *DefaultPasskeyService) decoyCredentials(email string) []protocol.CredentialDescriptor {
+	mac := hmac.New(sha256.New, s.decoyKey)
+	mac.Write([]byte(strings.ToLower(email)))
+
+	return []protocol.CredentialDescriptor{{
+		Type:         protocol.PublicKeyCredentialType,
+		CredentialID: mac.Sum(nil),
+		Transport:    []protocol.AuthenticatorTransport{protocol.Internal, protocol.Hybrid},
+	}}
+}
+
+func (s *DefaultPasskeyService) FinishLogin(ctx context.Context, req request.FinishPasskeyLoginRequest) (resp response.SignInResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PasskeyService.FinishLogin")
+	defer func() { tracing.End(span, err) }()
+
+	session, err := s.consumeSession(ctx, req.SessionID, repository.PasskeyCeremonyLogin)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	var (
+		user       *passkeyUser
+		credential *webauthn.Credential
+	)
+	if len(session.UserID) == 0 {
+		var found webauthn.User
+		found, credential, err = s.webAuthn.ValidatePasskeyLogin(s.discoverableUserHandler(ctx), session, parsed)
+		if err == nil {
+			user = found.(*passkeyUser)
+		}
+	} else {
+		var userID uuid.UUID
+		userID, err = uuid.FromBytes(session.UserID)
+		if err != nil {
+			return response.SignInResponse{}, ErrPasskeySessionInvalid
+		}
+		user, err = s.loadPasskeyUser(ctx, userID)
+		if err != nil {
+			return response.SignInResponse{}, err
+		}
+		credential, err = s.webAuthn.ValidateLogin(user, session, parsed)
+	}
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	if credential.Authenticator.CloneWarning {
+		return response.SignInResponse{}, ErrPasskeyCloned
+	}
+
+	stored, err := s.passkeyRepository.GetCredentialByCredentialId(ctx, credential.ID)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+
+	updated, err := s.passkeyRepository.UpdateCredentialUsage(ctx, stored.Id, stored.SignCount, credential.Authenticator.SignCount, credential.Flags.BackupState)
+	if err != nil {
+		return response.SignInResponse{}, err
+	}
+	if !updated {
+		return response.SignInResponse{}, ErrPasskeyCloned
+	}
+
+	return newSignInResponse(ctx, s.jwtService, s.accountDeletionService, s.avatars, user.entity)
+}
+
+func (s *DefaultPasskeyService) GetPasskeys(ctx context.Context, userID uuid.UUID) (resp []response.PasskeyResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PasskeyService.GetPasskeys")
+	defer func() { tracing.End(span, err) }()
+
+	credentials, err := s.passkeyRepository.GetCredentialsByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]response.PasskeyResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		responses = append(responses, toPasskeyResponse(credential))
+	}
+	return responses, nil
+}
+
+func (s *DefaultPasskeyService) DeletePasskey(ctx context.Context, userID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "PasskeyService.DeletePasskey")
+	defer func() { tracing.End(span, err) }()
+
+	deleted, err := s.passkeyRepository.DeleteCredential(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (s *DefaultPasskeyService) saveSession(ctx context.Context, userID *uuid.UUID, ceremony string, session *webauthn.SessionData) (uuid.UUID, error) {
+	// Abandoned ceremonies are only ever read back by id, so clean them up lazily.
+	if err := s.passkeyRepository.DeleteExpiredSessions(ctx); err != nil {
+		return uuid.Nil, err
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	entity := repository.PasskeySessionEntity{
+		Id:        uuid.New(),
+		UserId:    userID,
+		Ceremony:  ceremony,
+		Data:      data,
+		ExpiresAt: time.Now().Add(s.config.SessionTimeout),
+		CreatedAt: time.Now(),
+	}
+	if err := s.passkeyRepository.InsertSession(ctx, entity); err != nil {
+		return uuid.Nil, err
+	}
+	return entity.Id, nil
+}
+
+func (s *DefaultPasskeyService) consumeSession(ctx context.Context, id string, ceremony string) (webauthn.SessionData, error) {
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return webauthn.SessionData{}, ErrPasskeySessionInvalid
+	}
+
+	entity, err := s.passkeyRepository.ConsumeSession(ctx, sessionID, ceremony)
+	if err != nil || entity.ExpiresAt.Before(time.Now()) {
+		return webauthn.SessionData{}, ErrPasskeySessionInvalid
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(entity.Data, &session); err != nil {
+		return webauthn.SessionData{}, err
+	}
+	return session, nil
+}
+
+func (s *DefaultPasskeyService) discoverableUserHandler(ctx context.Context) webauthn.DiscoverableUserHandler {
+	return func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		return s.loadPasskeyUser(ctx, userID)
+	}
+}
+
+func (s *DefaultPasskeyService) loadPasskeyUser(ctx context.Context, userID uuid.UUID) (*passkeyUser, error) {
+	entity, err := s.userRepository.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toPasskeyUser(ctx, entity)
+}
+
+func (s *DefaultPasskeyService) toPasskeyUser(ctx context.Context, entity repository.UserEntity) (*passkeyUser, error) {
+	stored, err := s.passkeyRepository.GetCredentialsByUserId(ctx, entity.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, transport := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.CredentialId,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+
+	return &passkeyUser{entity: entity, credentials: credentials}, nil
+}
+
+// passkeyUser adapts a UserEntity to the webauthn.User interface. The user
+// handle is the raw 16 bytes of the user's UUID.
+type passkeyUser struct {
+	entity      repository.UserEntity
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	id := u.entity.Id
+	return id[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.entity.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	if u.entity.Name != "" {
+		return u.entity.Name
+	}
+	return u.entity.Email
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func toPasskeyResponse(entity repository.PasskeyCredentialEntity) response.PasskeyResponse {
+	transports := entity.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	return response.PasskeyResponse{
+		ID:             entity.Id.String(),
+		Name:           entity.Name,
+		Transports:     transports,
+		BackupEligible: entity.BackupEligible,
+		BackupState:    entity.BackupState,
+		CreatedAt:      entity.CreatedAt,
+		LastUsedAt:     entity.LastUsedAt,
+	}
+}
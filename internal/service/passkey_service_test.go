@@ -0,0 +1,566 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8080"
+)
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	user := env.addUser("alice@example.com")
+	authenticator := env.register(t, ctx, user)
+
+	// Sign in by email, then without one using the discoverable credential.
+	for _, email := range []string{user.Email, ""} {
+		options, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: email})
+		if err != nil {
+			t.Fatalf("BeginLogin(%q): %v", email, err)
+		}
+
+		resp, err := env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{
+			SessionID:  options.SessionID,
+			Credential: authenticator.get(t, options),
+		})
+		if err != nil {
+			t.Fatalf("FinishLogin(%q): %v", email, err)
+		}
+		if resp.User.ID != user.Id.String() || resp.Token == "" {
+			t.Fatalf("FinishLogin(%q) signed in %q with token %q", email, resp.User.ID, resp.Token)
+		}
+	}
+
+	stored := env.passkeys.credentials[authenticator.entityID(t, env)]
+	if stored.SignCount != authenticator.signCount || stored.LastUsedAt == nil {
+		t.Fatalf("stored sign count %d, last used %v; want %d and a time", stored.SignCount, stored.LastUsedAt, authenticator.signCount)
+	}
+}
+
+func TestPasskeyLoginRejectsReplayedChallenge(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	user := env.addUser("alice@example.com")
+	authenticator := env.register(t, ctx, user)
+
+	options, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: user.Email})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	assertion := authenticator.get(t, options)
+	if _, err := env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: assertion}); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+
+	// The session is single-use
+	_, err = env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: assertion})
+	if !errors.Is(err, ErrPasskeySessionInvalid) {
+		t.Fatalf("replay on the same session: got %v, want ErrPasskeySessionInvalid", err)
+	}
+
+	// and the assertion only answers the challenge it was made for.
+	fresh, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: user.Email})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if _, err := env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: fresh.SessionID, Credential: assertion}); err == nil {
+		t.Fatal("replay on a new session succeeded")
+	}
+}
+
+func TestPasskeyLoginRejectsSignCountGoingBackwards(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	user := env.addUser("alice@example.com")
+	authenticator := env.register(t, ctx, user)
+
+	authenticator.signCount = 5
+	env.login(t, ctx, user.Email, authenticator)
+
+	authenticator.signCount = 3
+	options, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: user.Email})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	_, err = env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: authenticator.get(t, options)})
+	if !errors.Is(err, ErrPasskeyCloned) {
+		t.Fatalf("got %v, want ErrPasskeyCloned", err)
+	}
+}
+
+func TestPasskeyLoginRejectsExpiredSession(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	user := env.addUser("alice@example.com")
+	authenticator := env.register(t, ctx, user)
+
+	options, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: user.Email})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	env.passkeys.expireSession(t, options.SessionID)
+
+	_, err = env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: authenticator.get(t, options)})
+	if !errors.Is(err, ErrPasskeySessionInvalid) {
+		t.Fatalf("got %v, want ErrPasskeySessionInvalid", err)
+	}
+}
+
+func TestPasskeyLoginRejectsCredentialOfAnotherUser(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	alice := env.addUser("alice@example.com")
+	bob := env.addUser("bob@example.com")
+	env.register(t, ctx, alice)
+	bobs := env.register(t, ctx, bob)
+
+	// Bob's passkey answering a sign-in to Alice's account
+	options, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: alice.Email})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if _, err := env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: bobs.get(t, options)}); err == nil {
+		t.Fatal("signed in to alice with bob's passkey")
+	}
+
+	// Bob's passkey claiming Alice's user handle in a discoverable sign-in
+	options, err = env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	bobs.userHandle = alice.Id[:]
+	if _, err := env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: bobs.get(t, options)}); err == nil {
+		t.Fatal("signed in to alice with bob's passkey and her user handle")
+	}
+}
+
+func TestPasskeyBeginLoginDoesNotRevealAccounts(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	alice := env.addUser("alice@example.com")
+	authenticator := env.register(t, ctx, alice)
+	withoutPasskey := env.addUser("bob@example.com")
+
+	real := allowedCredentials(t, env.beginLogin(t, ctx, alice.Email))
+	for _, email := range []string{"nobody@example.com", withoutPasskey.Email} {
+		first := allowedCredentials(t, env.beginLogin(t, ctx, email))
+		second := allowedCredentials(t, env.beginLogin(t, ctx, email))
+
+		if len(first) != len(real) {
+			t.Fatalf("%s: %d allowed credentials, a real account has %d", email, len(first), len(real))
+		}
+		if !bytes.Equal(first[0].CredentialID, second[0].CredentialID) {
+			t.Fatalf("%s: decoy credential changed between sign-ins", email)
+		}
+	}
+
+	// Nothing completes a decoy ceremony, not even a real passkey.
+	options := env.beginLogin(t, ctx, "nobody@example.com")
+	if _, err := env.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: authenticator.get(t, options)}); err == nil {
+		t.Fatal("signed in through a decoy ceremony")
+	}
+}
+
+func TestPasskeyBeginLoginFailsWhenCeremonyCannotStart(t *testing.T) {
+	ctx := context.Background()
+	env := newPasskeyTestEnv(t)
+	alice := env.addUser("alice@example.com")
+	env.register(t, ctx, alice)
+
+	// Without a relying party ID the WebAuthn library refuses to start any
+	// login ceremony.
+	env.service.(*DefaultPasskeyService).webAuthn.Config.RPID = ""
+
+	for _, email := range []string{"", alice.Email, "nobody@example.com"} {
+		options, err := env.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: email})
+		if err == nil {
+			t.Fatalf("BeginLogin(%q) returned options %+v, want an error", email, options)
+		}
+		if status := exception.ToApplicationError(err, exception.ErrorCodeInternalServerError).HTTPStatus(); status < 400 {
+			t.Fatalf("BeginLogin(%q) maps to status %d, want an error status", email, status)
+		}
+	}
+	if len(env.passkeys.sessions) != 0 {
+		t.Fatalf("%d sessions saved for failed ceremonies", len(env.passkeys.sessions))
+	}
+}
+
+type passkeyTestEnv struct {
+	service  PasskeyService
+	users    *fakeUserRepository
+	passkeys *fakePasskeyRepository
+}
+
+func newPasskeyTestEnv(t *testing.T) *passkeyTestEnv {
+	t.Helper()
+
+	users := &fakeUserRepository{users: map[uuid.UUID]repository.UserEntity{}}
+	passkeys := &fakePasskeyRepository{
+		credentials: map[uuid.UUID]repository.PasskeyCredentialEntity{},
+		sessions:    map[uuid.UUID]repository.PasskeySessionEntity{},
+	}
+	service, err := NewPasskeyService(
+		runtime.WebAuthnConfig{RPID: testRPID, RPDisplayName: "Test", RPOrigins: []string{testOrigin}, SessionTimeout: time.Minute},
+		users,
+		passkeys,
+		NewJWTService(runtime.JWTConfig{SecretKey: "test", Expiration: time.Hour, RefreshExpiry: time.Hour}),
+		fakeAccountDeletionService{},
+		fakeAvatars{},
+	)
+	if err != nil {
+		t.Fatalf("NewPasskeyService: %v", err)
+	}
+	return &passkeyTestEnv{service: service, users: users, passkeys: passkeys}
+}
+
+func (e *passkeyTestEnv) addUser(email string) repository.UserEntity {
+	user := repository.UserEntity{Id: uuid.New(), Email: email, IsActive: true}
+	e.users.users[user.Id] = user
+	return user
+}
+
+func (e *passkeyTestEnv) register(t *testing.T, ctx context.Context, user repository.UserEntity) *softAuthenticator {
+	t.Helper()
+
+	authenticator := newSoftAuthenticator(user)
+	options, err := e.service.BeginRegistration(ctx, user.Id)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	_, err = e.service.FinishRegistration(ctx, user.Id, request.FinishPasskeyRegistrationRequest{
+		SessionID:  options.SessionID,
+		Credential: authenticator.create(options),
+	})
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return authenticator
+}
+
+func (e *passkeyTestEnv) beginLogin(t *testing.T, ctx context.Context, email string) response.PasskeyOptionsResponse {
+	t.Helper()
+
+	options, err := e.service.BeginLogin(ctx, request.BeginPasskeyLoginRequest{Email: email})
+	if err != nil {
+		t.Fatalf("BeginLogin(%q): %v", email, err)
+	}
+	return options
+}
+
+func (e *passkeyTestEnv) login(t *testing.T, ctx context.Context, email string, authenticator *softAuthenticator) {
+	t.Helper()
+
+	options := e.beginLogin(t, ctx, email)
+	if _, err := e.service.FinishLogin(ctx, request.FinishPasskeyLoginRequest{SessionID: options.SessionID, Credential: authenticator.get(t, options)}); err != nil {
+		t.Fatalf("FinishLogin(%q): %v", email, err)
+	}
+}
+
+func allowedCredentials(t *testing.T, options response.PasskeyOptionsResponse) []protocol.CredentialDescriptor {
+	t.Helper()
+
+	var assertion protocol.CredentialAssertion
+	remarshal(options.Options, &assertion)
+	if len(assertion.Response.AllowedCredentials) == 0 {
+		t.Fatal("options allow no credentials")
+	}
+	return assertion.Response.AllowedCredentials
+}
+
+// softAuthenticator is a software passkey: a P-256 key pair that answers
+// WebAuthn ceremonies with "none" attestation.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(user repository.UserEntity) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		panic(err)
+	}
+	return &softAuthenticator{key: key, credentialID: credentialID, userHandle: user.Id[:]}
+}
+
+// create answers a registration with a new credential.
+func (a *softAuthenticator) create(options response.PasskeyOptionsResponse) json.RawMessage {
+	var creation protocol.CredentialCreation
+	remarshal(options.Options, &creation)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	authData := a.authData(protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(struct {
+		Format    string         `cbor:"fmt"`
+		Statement map[string]any `cbor:"attStmt"`
+		AuthData  []byte         `cbor:"authData"`
+	}{Format: "none", Statement: map[string]any{}, AuthData: authData})
+	if err != nil {
+		panic(err)
+	}
+
+	return a.credential(map[string]string{
+		"clientDataJSON":    encode(clientData("webauthn.create", creation.Response.Challenge)),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// get answers a sign-in with an assertion signed by the credential.
+func (a *softAuthenticator) get(t *testing.T, options response.PasskeyOptionsResponse) json.RawMessage {
+	t.Helper()
+
+	var assertion protocol.CredentialAssertion
+	remarshal(options.Options, &assertion)
+
+	authData := a.authData(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	clientDataJSON := clientData("webauthn.get", assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	return a.credential(map[string]string{
+		"clientDataJSON":    encode(clientDataJSON),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) authData(flags protocol.AuthenticatorFlags) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], byte(flags))
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func (a *softAuthenticator) credential(resp map[string]string) json.RawMessage {
+	body, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": resp,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+func (a *softAuthenticator) entityID(t *testing.T, env *passkeyTestEnv) uuid.UUID {
+	t.Helper()
+
+	for id, credential := range env.passkeys.credentials {
+		if bytes.Equal(credential.CredentialId, a.credentialID) {
+			return id
+		}
+	}
+	t.Fatal("credential not stored")
+	return uuid.Nil
+}
+
+func clientData(ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   challenge.String(),
+		"origin":      testOrigin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func remarshal(from, to any) {
+	data, err := json.Marshal(from)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(data, to); err != nil {
+		panic(err)
+	}
+}
+
+func notFound() error {
+	return fmt.Errorf("%w: %w", exception.ErrNotFound, sql.ErrNoRows)
+}
+
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[uuid.UUID]repository.UserEntity
+	// avatarKeys records every avatar key set, by key.
+	avatarKeys map[string]uuid.UUID
+}
+
+func (r *fakeUserRepository) GetUserById(_ context.Context, id uuid.UUID, _ ...repository.ReadOption) (repository.UserEntity, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return repository.UserEntity{}, notFound()
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetUserByEmail(_ context.Context, email string, _ ...repository.ReadOption) (repository.UserEntity, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return repository.UserEntity{}, notFound()
+}
+
+type fakePasskeyRepository struct {
+	credentials map[uuid.UUID]repository.PasskeyCredentialEntity
+	sessions    map[uuid.UUID]repository.PasskeySessionEntity
+}
+
+func (r *fakePasskeyRepository) GetCredentialsByUserId(_ context.Context, userId uuid.UUID) ([]repository.PasskeyCredentialEntity, error) {
+	var out []repository.PasskeyCredentialEntity
+	for _, credential := range r.credentials {
+		if credential.UserId == userId {
+			out = append(out, credential)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakePasskeyRepository) GetCredentialByCredentialId(_ context.Context, credentialId []byte) (repository.PasskeyCredentialEntity, error) {
+	for _, credential := range r.credentials {
+		if bytes.Equal(credential.CredentialId, credentialId) {
+			return credential, nil
+		}
+	}
+	return repository.PasskeyCredentialEntity{}, notFound()
+}
+
+func (r *fakePasskeyRepository) InsertCredential(_ context.Context, credential repository.PasskeyCredentialEntity) (repository.PasskeyCredentialEntity, error) {
+	if _, err := r.GetCredentialByCredentialId(context.Background(), credential.CredentialId); err == nil {
+		return repository.PasskeyCredentialEntity{}, exception.ErrConflict
+	}
+	r.credentials[credential.Id] = credential
+	return credential, nil
+}
+
+func (r *fakePasskeyRepository) UpdateCredentialUsage(_ context.Context, id uuid.UUID, previousSignCount, signCount uint32, backupState bool) (bool, error) {
+	credential, ok := r.credentials[id]
+	if !ok || credential.SignCount != previousSignCount {
+		return false, nil
+	}
+	now := time.Now()
+	credential.SignCount = signCount
+	credential.BackupState = backupState
+	credential.LastUsedAt = &now
+	r.credentials[id] = credential
+	return true, nil
+}
+
+func (r *fakePasskeyRepository) DeleteCredential(_ context.Context, userId, id uuid.UUID) (bool, error) {
+	credential, ok := r.credentials[id]
+	if !ok || credential.UserId != userId {
+		return false, nil
+	}
+	delete(r.credentials, id)
+	return true, nil
+}
+
+func (r *fakePasskeyRepository) InsertSession(_ context.Context, session repository.PasskeySessionEntity) error {
+	r.sessions[session.Id] = session
+	return nil
+}
+
+func (r *fakePasskeyRepository) ConsumeSession(_ context.Context, id uuid.UUID, ceremony string) (repository.PasskeySessionEntity, error) {
+	session, ok := r.sessions[id]
+	if !ok || session.Ceremony != ceremony {
+		return repository.PasskeySessionEntity{}, notFound()
+	}
+	delete(r.sessions, id)
+	return session, nil
+}
+
+func (r *fakePasskeyRepository) DeleteExpiredSessions(_ context.Context) error {
+	for id, session := range r.sessions {
+		if session.ExpiresAt.Before(time.Now()) {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakePasskeyRepository) expireSession(t *testing.T, id string) {
+	t.Helper()
+
+	session, ok := r.sessions[uuid.MustParse(id)]
+	if !ok {
+		t.Fatalf("session %s not found", id)
+	}
+	session.ExpiresAt = time.Now().Add(-time.Second)
+	r.sessions[session.Id] = session
+}
+
+type fakeAccountDeletionService struct {
+	AccountDeletionService
+}
+
+func (fakeAccountDeletionService) CancelOnSignIn(context.Context, uuid.UUID) error {
+	return nil
+}
+
+type fakeAvatars struct{}
+
+func (fakeAvatars) AvatarURLs(string) map[string]string {
+	return nil
+}
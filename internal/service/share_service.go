@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var (
+	ErrShareForbidden    = exception.NewError("share_forbidden", "only the owner can manage who this is shared with")
+	ErrShareUserNotFound = exception.NewError("share_user_not_found", "no user with that email")
+	ErrShareWithOwner    = exception.NewError("share_with_owner", "cannot share with its owner")
+	ErrShareNotFound     = exception.NewError("share_not_found", "not shared with that user")
+)
+
+// ShareService shares categories and todos of the caller's workspace with
+// other users as viewers or editors.
+type ShareService interface {
+	GetShares(ctx context.Context, workspace repository.Workspace, resource repository.Resource) ([]response.ShareResponse, error)
+	Share(ctx context.Context, workspace repository.Workspace, resource repository.Resource, req request.ShareRequest) (response.ShareResponse, error)
+	Unshare(ctx context.Context, workspace repository.Workspace, resource repository.Resource, email string) error
+}
+
+type DefaultShareService struct {
+	resourceGrantRepository repository.ResourceGrantRepository
+	categoryRepository      repository.CategoryRepository
+	todoRepository          repository.TodoRepository
+	userRepository          repository.UserRepository
+}
+
+func NewShareService(resourceGrantRepository repository.ResourceGrantRepository, categoryRepository repository.CategoryRepository, todoRepository repository.TodoRepository, userRepository repository.UserRepository) ShareService {
+	return &DefaultShareService{
+		resourceGrantRepository: resourceGrantRepository,
+		categoryRepository:      categoryRepository,
+		todoRepository:          todoRepository,
+		userRepository:          userRepository,
+	}
+}
+
+func (s *DefaultShareService) GetShares(ctx context.Context, workspace repository.Workspace, resource repository.Resource) (resp []response.ShareResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ShareService.GetShares")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.owned(ctx, workspace, resource); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.resourceGrantRepository.GetGrants(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]response.ShareResponse, 0, len(grants))
+	for _, grant := range grants {
+		// Grants of soft-deleted users come back without their user
+		if grant.User == nil || grant.User.Id == uuid.Nil {
+			continue
+		}
+		shares = append(shares, toShareResponse(grant, *grant.User))
+	}
+	return shares, nil
+}
+
+// Share gives the user with req.Email access to the resource, or changes the
+// role of an existing share.
+func (s *DefaultShareService) Share(ctx context.Context, workspace repository.Workspace, resource repository.Resource, req request.ShareRequest) (resp response.ShareResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ShareService.Share")
+	defer func() { tracing.End(span, err) }()
+
+	owner, err := s.owned(ctx, workspace, resource)
+	if err != nil {
+		return response.ShareResponse{}, err
+	}
+
+	user, err := s.grantee(ctx, req.Email)
+	if err != nil {
+		return response.ShareResponse{}, err
+	}
+	if user.Id == workspace.UserId || owner.SameOwner(repository.PersonalWorkspace(user.Id)) {
+		return response.ShareResponse{}, ErrShareWithOwner
+	}
+
+	grant, err := s.resourceGrantRepository.SaveGrant(ctx, resource, repository.ResourceGrantEntity{
+		Id:        uuid.New(),
+		UserId:    user.Id,
+		Role:      req.Role,
+		GrantedBy: &workspace.UserId,
+	})
+	if err != nil {
+		return response.ShareResponse{}, err
+	}
+	return toShareResponse(grant, user), nil
+}
+
+func (s *DefaultShareService) Unshare(ctx context.Context, workspace repository.Workspace, resource repository.Resource, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "ShareService.Unshare")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.owned(ctx, workspace, resource); err != nil {
+		return err
+	}
+
+	user, err := s.grantee(ctx, email)
+	if errors.Is(err, ErrShareUserNotFound) {
+		return ErrShareNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.resourceGrantRepository.DeleteGrant(ctx, resource, user.Id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// owned returns the owner of a resource the workspace owns. Resources the
+// workspace cannot see are not found; shared ones are forbidden.
+func (s *DefaultShareService) owned(ctx context.Context, workspace repository.Workspace, resource repository.Resource) (repository.Workspace, error) {
+	var owner repository.Workspace
+	var access string
+	var err error
+	switch resource.Type {
+	case repository.ResourceTypeTodo:
+		var todo repository.TodoEntity
+		todo, err = s.todoRepository.GetTodoById(ctx, workspace, resource.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.Workspace{}, ErrTodoNotFound
+		}
+		owner, access = todo.Owner(), todo.Access
+	default:
+		var category repository.CategoryEntity
+		category, err = s.categoryRepository.GetCategoryById(ctx, workspace, resource.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.Workspace{}, ErrCategoryNotFound
+		}
+		owner, access = category.Owner(), category.Access
+	}
+	if err != nil {
+		return repository.Workspace{}, err
+	}
+	if access != repository.AccessOwner {
+		return repository.Workspace{}, ErrShareForbidden
+	}
+	return owner, nil
+}
+
+func (s *DefaultShareService) grantee(ctx context.Context, email string) (repository.UserEntity, error) {
+	user, err := s.userRepository.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.UserEntity{}, ErrShareUserNotFound
+	}
+	return user, err
+}
+
+func toShareResponse(grant repository.ResourceGrantEntity, user repository.UserEntity) response.ShareResponse {
+	return response.ShareResponse{
+		UserID:    user.Id.String(),
+		Name:      user.Name,
+		Email:     user.Email,
+		Role:      grant.Role,
+		SharedAt:  grant.CreatedAt,
+		UpdatedAt: grant.UpdatedAt,
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var (
+	ErrTodoNotFound        = exception.NewError("todo_not_found", "todo not found")
+	ErrTodoForbidden       = exception.NewError("todo_forbidden", "you do not have permission to change this todo")
+	ErrTodoCategoryInvalid = exception.NewError("todo_category_invalid", "category does not exist or belongs to someone else")
+)
+
+// TodoService manages the todos of the caller's active workspace and the todos
+// other users shared with the caller.
+type TodoService interface {
+	GetTodos(ctx context.Context, workspace repository.Workspace, listReq request.TodoListRequest) (response.ListResponse[response.TodoResponse], error)
+	GetTodoById(ctx context.Context, workspace repository.Workspace, id uuid.UUID) (response.TodoResponse, error)
+	CreateTodo(ctx context.Context, workspace repository.Workspace, req request.TodoRequest) (response.TodoResponse, error)
+	UpdateTodo(ctx context.Context, workspace repository.Workspace, id uuid.UUID, req request.TodoRequest) (response.TodoResponse, error)
+	DeleteTodo(ctx context.Context, workspace repository.Workspace, id uuid.UUID) error
+	GetSharedTodos(ctx context.Context, userID uuid.UUID, listReq request.TodoListRequest) (response.ListResponse[response.TodoResponse], error)
+}
+
+type DefaultTodoService struct {
+	todoRepository     repository.TodoRepository
+	categoryRepository repository.CategoryRepository
+}
+
+func NewTodoService(todoRepository repository.TodoRepository, categoryRepository repository.CategoryRepository) TodoService {
+	return &DefaultTodoService{todoRepository: todoRepository, categoryRepository: categoryRepository}
+}
+
+func (s *DefaultTodoService) GetTodos(ctx context.Context, workspace repository.Workspace, listReq request.TodoListRequest) (resp response.ListResponse[response.TodoResponse], err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.GetTodos")
+	defer func() { tracing.End(span, err) }()
+
+	return s.listTodos(listReq, func(filter repository.TodoFilter, page repository.Page) ([]repository.TodoEntity, repository.PageInfo, error) {
+		return s.todoRepository.GetTodos(ctx, workspace, filter, page)
+	}, func(filter repository.TodoFilter) (int64, error) {
+		return s.todoRepository.GetTodosCount(ctx, workspace, filter)
+	})
+}
+
+// GetSharedTodos lists the todos shared with the user, directly or through a
+// shared category.
+func (s *DefaultTodoService) GetSharedTodos(ctx context.Context, userID uuid.UUID, listReq request.TodoListRequest) (resp response.ListResponse[response.TodoResponse], err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.GetSharedTodos")
+	defer func() { tracing.End(span, err) }()
+
+	return s.listTodos(listReq, func(filter repository.TodoFilter, page repository.Page) ([]repository.TodoEntity, repository.PageInfo, error) {
+		return s.todoRepository.GetSharedTodos(ctx, userID, filter, page)
+	}, func(filter repository.TodoFilter) (int64, error) {
+		return s.todoRepository.GetSharedTodosCount(ctx, userID, filter)
+	})
+}
+
+func (s *DefaultTodoService) GetTodoById(ctx context.Context, workspace repository.Workspace, id uuid.UUID) (resp response.TodoResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.GetTodoById")
+	defer func() { tracing.End(span, err) }()
+
+	todo, err := s.todoRepository.GetTodoById(ctx, workspace, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.TodoResponse{}, ErrTodoNotFound
+	}
+	if err != nil {
+		return response.TodoResponse{}, err
+	}
+	return toTodoResponse(todo), nil
+}
+
+// CreateTodo adds a todo to the workspace. A todo put in a category shared
+// with the caller as editor belongs to the category's owner instead.
+func (s *DefaultTodoService) CreateTodo(ctx context.Context, workspace repository.Workspace, req request.TodoRequest) (resp response.TodoResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.CreateTodo")
+	defer func() { tracing.End(span, err) }()
+
+	todo := toTodoEntity(uuid.New(), req, nil)
+	todo.CreatedBy = &workspace.UserId
+	owner := workspace
+
+	if req.CategoryID != nil {
+		category, err := s.category(ctx, workspace, *req.CategoryID)
+		if err != nil {
+			return response.TodoResponse{}, err
+		}
+		if category.Access == repository.GrantRoleViewer {
+			return response.TodoResponse{}, ErrTodoForbidden
+		}
+		if category.Access != repository.AccessOwner {
+			owner = category.Owner()
+		}
+		todo.CategoryId = &category.Id
+	}
+
+	created, err := s.todoRepository.InsertTodo(ctx, owner, todo)
+	if err != nil {
+		return response.TodoResponse{}, err
+	}
+	created.Access = repository.AccessOwner
+	if !owner.SameOwner(workspace) {
+		created.Access = repository.GrantRoleEditor
+	}
+	return toTodoResponse(created), nil
+}
+
+// UpdateTodo replaces the todo's fields. Completing it records when; any
+// other status clears that again. The category must belong to the todo's
+// owner.
+func (s *DefaultTodoService) UpdateTodo(ctx context.Context, workspace repository.Workspace, id uuid.UUID, req request.TodoRequest) (resp response.TodoResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.UpdateTodo")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.todoRepository.GetTodoById(ctx, workspace, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.TodoResponse{}, ErrTodoNotFound
+	}
+	if err != nil {
+		return response.TodoResponse{}, err
+	}
+	if existing.Access == repository.GrantRoleViewer {
+		return response.TodoResponse{}, ErrTodoForbidden
+	}
+
+	todo := toTodoEntity(id, req, existing.CompletedAt)
+	if req.CategoryID != nil {
+		category, err := s.category(ctx, workspace, *req.CategoryID)
+		if err != nil {
+			return response.TodoResponse{}, err
+		}
+		if !category.Owner().SameOwner(existing.Owner()) {
+			return response.TodoResponse{}, ErrTodoCategoryInvalid
+		}
+		todo.CategoryId = &category.Id
+	}
+
+	updated, err := s.todoRepository.UpdateTodo(ctx, workspace, todo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.TodoResponse{}, s.explainDenied(ctx, workspace, id)
+	}
+	if err != nil {
+		return response.TodoResponse{}, err
+	}
+	return toTodoResponse(updated), nil
+}
+
+// DeleteTodo deletes a todo of the workspace, or one in a category shared with
+// the caller as editor.
+func (s *DefaultTodoService) DeleteTodo(ctx context.Context, workspace repository.Workspace, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.DeleteTodo")
+	defer func() { tracing.End(span, err) }()
+
+	deleted, err := s.todoRepository.DeleteTodo(ctx, workspace, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return s.explainDenied(ctx, workspace, id)
+	}
+	return nil
+}
+
+func (s *DefaultTodoService) listTodos(
+	listReq request.TodoListRequest,
+	list func(repository.TodoFilter, repository.Page) ([]repository.TodoEntity, repository.PageInfo, error),
+	count func(repository.TodoFilter) (int64, error),
+) (response.ListResponse[response.TodoResponse], error) {
+	filter := repository.TodoFilter{Status: listReq.Status}
+	if listReq.CategoryID != "" {
+		categoryID, err := uuid.Parse(listReq.CategoryID)
+		if err != nil {
+			return response.ListResponse[response.TodoResponse]{}, err
+		}
+		filter.CategoryId = &categoryID
+	}
+
+	page, err := toPage(listReq.ListRequest)
+	if err != nil {
+		return response.ListResponse[response.TodoResponse]{}, err
+	}
+
+	todos, info, err := list(filter, page)
+	if err != nil {
+		return response.ListResponse[response.TodoResponse]{}, err
+	}
+
+	responses := make([]response.TodoResponse, 0, len(todos))
+	for _, todo := range todos {
+		responses = append(responses, toTodoResponse(todo))
+	}
+
+	resp := newPageResponse(responses, listReq.ListRequest, info)
+	if listReq.WantsTotal() {
+		total, err := count(filter)
+		if err != nil {
+			return response.ListResponse[response.TodoResponse]{}, err
+		}
+		resp = resp.WithTotal(total)
+	}
+	return resp, nil
+}
+
+// category looks up a category the workspace owns or that is shared with it.
+func (s *DefaultTodoService) category(ctx context.Context, workspace repository.Workspace, id string) (repository.CategoryEntity, error) {
+	categoryID, err := uuid.Parse(id)
+	if err != nil {
+		return repository.CategoryEntity{}, ErrTodoCategoryInvalid
+	}
+	category, err := s.categoryRepository.GetCategoryById(ctx, workspace, categoryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.CategoryEntity{}, ErrTodoCategoryInvalid
+	}
+	return category, err
+}
+
+// explainDenied tells why a change to a todo matched no row: it is either
+// invisible to the workspace or only shared with a lesser role.
+func (s *DefaultTodoService) explainDenied(ctx context.Context, workspace repository.Workspace, id uuid.UUID) error {
+	_, err := s.todoRepository.GetTodoById(ctx, workspace, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTodoNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrTodoForbidden
+}
+
+func toTodoEntity(id uuid.UUID, req request.TodoRequest, completedAt *time.Time) repository.TodoEntity {
+	todo := repository.TodoEntity{
+		Id:          id,
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		Status:      req.Status,
+		DueDate:     req.DueDate,
+	}
+	if todo.Priority == "" {
+		todo.Priority = "medium"
+	}
+	if todo.Status == "" {
+		todo.Status = repository.TodoStatusPending
+	}
+
+	if todo.Status == repository.TodoStatusCompleted {
+		if completedAt == nil {
+			now := time.Now()
+			completedAt = &now
+		}
+		todo.CompletedAt = completedAt
+	}
+	return todo
+}
+
+func toTodoResponse(todo repository.TodoEntity) response.TodoResponse {
+	return response.TodoResponse{
+		ID:             todo.Id.String(),
+		UserID:         optionalUUID(todo.UserId),
+		OrganizationID: optionalUUID(todo.OrganizationId),
+		CategoryID:     optionalUUID(todo.CategoryId),
+		Title:          todo.Title,
+		Description:    todo.Description,
+		Priority:       todo.Priority,
+		Status:         todo.Status,
+		DueDate:        todo.DueDate,
+		CompletedAt:    todo.CompletedAt,
+		Access:         todo.Access,
+		CreatedAt:      todo.CreatedAt,
+		UpdatedAt:      todo.UpdatedAt,
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
+	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+)
+
+var (
+	ErrImportEmpty          = exception.NewError("import_empty", "the file has no header row")
+	ErrImportMissingColumns = exception.NewError("import_missing_columns", "the header row must have name and email columns")
+	ErrImportTooManyRows    = exception.NewError("import_too_many_rows", "the file has too many rows")
+	ErrImportMalformed      = exception.NewError("import_malformed", "the file is not valid CSV")
+)
+
+// UserImportService creates users in bulk from a CSV file with a header row
+// naming at least the name and email columns. Every row is checked like a
+// single invitation; rows that pass are created as pending invitations.
+type UserImportService interface {
+	ImportUsers(ctx context.Context, inviterID *uuid.UUID, file io.Reader, req request.UserImportRequest) (response.UserImportResponse, error)
+}
+
+type DefaultUserImportService struct {
+	config            runtime.UserImportConfig
+	userRepository    repository.UserRepository
+	invitationService InvitationService
+	validator         *validator.Validate
+}
+
+// NewUserImportService returns the import service. Without an invitation
+// service, imports cannot send invitations.
+func NewUserImportService(config runtime.UserImportConfig, userRepository repository.UserRepository, invitationService InvitationService) UserImportService {
+	return &DefaultUserImportService{
+		config:            config,
+		userRepository:    userRepository,
+		invitationService: invitationService,
+		validator:         i18n.Validator(),
+	}
+}
+
+// ImportUsers validates every row, then creates the accepted ones in a single
+// transaction unless req.DryRun is set. Rejected rows do not stop the others.
+func (s *DefaultUserImportService) ImportUsers(ctx context.Context, inviterID *uuid.UUID, file io.Reader, req request.UserImportRequest) (resp response.UserImportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserImportService.ImportUsers")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.readRows(file)
+	if err != nil {
+		return response.UserImportResponse{}, err
+	}
+
+	report := response.UserImportResponse{DryRun: req.DryRun, Total: len(rows), Rows: rows}
+	if err := s.checkRows(ctx, report.Rows); err != nil {
+		return response.UserImportResponse{}, err
+	}
+
+	now := time.Now()
+	var users []repository.UserEntity
+	var accepted []int
+	for i, row := range report.Rows {
+		if row.Status != response.UserImportRowValid {
+			report.Failed++
+			continue
+		}
+		report.Valid++
+		if req.DryRun {
+			continue
+		}
+		users = append(users, repository.UserEntity{
+			Id:        uuid.New(),
+			Name:      row.Name,
+			Email:     row.Email,
+			IsActive:  false,
+			InvitedAt: &now,
+			InvitedBy: inviterID,
+		})
+		accepted = append(accepted, i)
+	}
+	if len(users) == 0 {
+		return report, nil
+	}
+
+	if err := s.userRepository.InsertUsers(ctx, users, s.config.BatchSize); err != nil {
+		return response.UserImportResponse{}, err
+	}
+	for n, i := range accepted {
+		row := &report.Rows[i]
+		row.Status = response.UserImportRowCreated
+		row.UserID = users[n].Id.String()
+		report.Created++
+
+		if req.SendInvitations && s.invitationService != nil {
+			if _, err := s.invitationService.ResendInvitation(ctx, users[n].Id); err != nil {
+				row.Errors = append(row.Errors, exception.ErrorDetail{
+					Key:     "invitation",
+					Field:   "invitation",
+					Message: "Failed to send the invitation: " + err.Error(),
+				})
+			}
+		}
+	}
+	return report, nil
+}
+
+// readRows parses the file into rows with their line numbers, still unchecked.
+func (s *DefaultUserImportService) readRows(file io.Reader) ([]response.UserImportRowResponse, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrImportEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImportMalformed, err)
+	}
+	nameColumn, emailColumn := -1, -1
+	for i, column := range header {
+		// Spreadsheet exports often start with a byte order mark
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) {
+		case "name":
+			nameColumn = i
+		case "email":
+			emailColumn = i
+		}
+	}
+	if nameColumn < 0 || emailColumn < 0 {
+		return nil, ErrImportMissingColumns
+	}
+
+	rows := []response.UserImportRowResponse{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImportMalformed, err)
+		}
+		if len(rows) == s.config.MaxRows {
+			return nil, fmt.Errorf("%w: at most %d are allowed", ErrImportTooManyRows, s.config.MaxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, response.UserImportRowResponse{
+			Row:    line,
+			Name:   csvField(record, nameColumn),
+			Email:  csvField(record, emailColumn),
+			Status: response.UserImportRowValid,
+		})
+	}
+	return rows, nil
+}
+
+// checkRows validates the rows like NewUserRequest and flags emails that
+// appear earlier in the file or already belong to a user.
+func (s *DefaultUserImportService) checkRows(ctx context.Context, rows []response.UserImportRowResponse) error {
+	firstRow := map[string]int{}
+	var emails []string
+	for i := range rows {
+		row := &rows[i]
+		err := s.validator.Struct(request.NewUserRequest{Name: row.Name, Email: row.Email})
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			row.Status = response.UserImportRowInvalid
+			row.Errors = exception.ValidationDetails(validationErrs)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		key := strings.ToLower(row.Email)
+		if first, ok := firstRow[key]; ok {
+			row.Status = response.UserImportRowDuplicate
+			row.Errors = []exception.ErrorDetail{{
+				Key:         "NewUserRequest.Email",
+				Field:       "Email",
+				Message:     fmt.Sprintf("Duplicates the email of row %d", first),
+				MessageID:   "import_duplicate_email",
+				MessageArgs: []string{strconv.Itoa(first)},
+			}}
+			continue
+		}
+		firstRow[key] = row.Row
+		emails = append(emails, row.Email)
+	}
+
+	existing, err := s.userRepository.GetExistingEmails(ctx, emails)
+	if err != nil {
+		return err
+	}
+	taken := map[string]bool{}
+	for _, email := range existing {
+		taken[strings.ToLower(email)] = true
+	}
+	for i := range rows {
+		row := &rows[i]
+		if row.Status == response.UserImportRowValid && taken[strings.ToLower(row.Email)] {
+			row.Status = response.UserImportRowExists
+			row.Errors = []exception.ErrorDetail{{
+				Key:       "NewUserRequest.Email",
+				Field:     "Email",
+				Message:   ErrEmailTaken.Error(),
+				MessageID: "email_taken",
+			}}
+		}
+	}
+	return nil
+}
+
+func csvField(record []string, i int) string {
+	if i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
@@ -1,83 +1,243 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/lamkn06/user-app-golang.git/internal/repository"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
 
 	"github.com/lamkn06/user-app-golang.git/pkg/api/request"
 	"github.com/lamkn06/user-app-golang.git/pkg/api/response"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var (
+	ErrUserNotFound          = exception.NewError("user_not_found", "user not found")
+	ErrUserVersionMismatch   = exception.NewError("user_version_mismatch", "user has been modified since it was read")
+	ErrEmailTaken            = exception.NewError("email_taken", "email is already in use")
+	ErrUserInactive          = exception.NewError("user_inactive", "user account is deactivated")
+	ErrEmailChangeNotAllowed = exception.NewError("email_change_not_allowed", "email can only be changed by confirming the new address")
 )
 
 type UserService interface {
-	GetUsers(listReq request.ListRequest) (response.ListResponse[response.NewUserResponse], error)
-	NewUser(user request.NewUserRequest) (response.NewUserResponse, error)
-	GetUserById(id uuid.UUID) (response.NewUserResponse, error)
+	GetUsers(ctx context.Context, listReq request.UserListRequest) (response.ListResponse[response.NewUserResponse], error)
+	GetUserById(ctx context.Context, id uuid.UUID) (response.NewUserResponse, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, expectedUpdatedAt time.Time, user request.NewUserRequest) (response.NewUserResponse, error)
+	GetActiveUser(ctx context.Context, id uuid.UUID) (response.NewUserResponse, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (response.NewUserResponse, error)
+	SetUserActive(ctx context.Context, id uuid.UUID, active bool) (response.NewUserResponse, error)
 }
 
 type DefaultUserService struct {
 	userRepository repository.UserRepository
+	avatars        AvatarURLResolver
 }
 
-func NewUserService(userRepository repository.UserRepository) UserService {
-	return &DefaultUserService{userRepository: userRepository}
+func NewUserService(userRepository repository.UserRepository, avatars AvatarURLResolver) UserService {
+	return &DefaultUserService{userRepository: userRepository, avatars: avatars}
 }
 
-func (s *DefaultUserService) GetUsers(listReq request.ListRequest) (response.ListResponse[response.NewUserResponse], error) {
-	// Get total count
-	total, err := s.userRepository.GetUsersCount()
+func (s *DefaultUserService) GetUsers(ctx context.Context, listReq request.UserListRequest) (resp response.ListResponse[response.NewUserResponse], err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsers")
+	defer func() { tracing.End(span, err) }()
+
+	sort, err := listReq.SortFields()
 	if err != nil {
 		return response.ListResponse[response.NewUserResponse]{}, err
 	}
 
-	// Get paginated users
-	users, err := s.userRepository.GetUsers(listReq.GetOffset(), listReq.GetLimit())
+	filter := repository.UserFilter{
+		Query:         strings.TrimSpace(listReq.Query),
+		Active:        listReq.Active,
+		Verified:      listReq.Verified,
+		Deleted:       toDeletedFilter(listReq.Deleted),
+		CreatedAfter:  listReq.CreatedAfter,
+		CreatedBefore: listReq.CreatedBefore,
+	}
+	for _, field := range sort {
+		filter.OrderBy = append(filter.OrderBy, repository.OrderBy{Field: field.Field, Desc: field.Desc})
+	}
+
+	page, err := toPage(listReq.ListRequest)
+	if err != nil {
+		return response.ListResponse[response.NewUserResponse]{}, err
+	}
+
+	// Get the page of users
+	users, info, err := s.userRepository.GetUsers(ctx, filter, page)
 	if err != nil {
 		return response.ListResponse[response.NewUserResponse]{}, err
 	}
 
 	// Convert to response format
-	var responses []response.NewUserResponse
+	responses := make([]response.NewUserResponse, 0, len(users))
 	for _, user := range users {
-		responses = append(responses, response.NewUserResponse{
-			ID:    user.Id.String(),
-			Name:  user.Name,
-			Email: user.Email,
-		})
+		responses = append(responses, toUserResponse(user, s.avatars))
 	}
 
-	// Create list response with metadata
-	return response.NewListResponse(responses, total, listReq.GetPage(), listReq.GetLimit()), nil
+	// Create list response with metadata; the exact count is optional
+	resp = newPageResponse(responses, listReq.ListRequest, info)
+	if listReq.WantsTotal() {
+		total, err := s.userRepository.GetUsersCount(ctx, filter)
+		if err != nil {
+			return response.ListResponse[response.NewUserResponse]{}, err
+		}
+		resp = resp.WithTotal(total)
+	}
+	return resp, nil
 }
 
-func (s *DefaultUserService) NewUser(user request.NewUserRequest) (response.NewUserResponse, error) {
-	entity := repository.UserEntity{
-		Id:    uuid.New(),
-		Name:  user.Name,
-		Email: user.Email,
+func (s *DefaultUserService) GetUserById(ctx context.Context, id uuid.UUID) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserById")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
 	}
+	return toUserResponse(user, s.avatars), nil
+}
 
-	newUser, err := s.userRepository.InsertUser(entity)
+// UpdateUser replaces the user's profile if it is still at the version the
+// caller read, identified by its updated_at timestamp. The email address is
+// changed through EmailChangeService instead.
+func (s *DefaultUserService) UpdateUser(ctx context.Context, id uuid.UUID, expectedUpdatedAt time.Time, user request.NewUserRequest) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.userRepository.GetUserById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
 	if err != nil {
 		return response.NewUserResponse{}, err
 	}
+	if user.Email != existing.Email {
+		return response.NewUserResponse{}, ErrEmailChangeNotAllowed
+	}
 
-	return response.NewUserResponse{
-		ID:    newUser.Id.String(),
-		Name:  newUser.Name,
-		Email: newUser.Email,
-	}, nil
+	updated, err := s.userRepository.UpdateUser(ctx, repository.UserEntity{
+		Id:   id,
+		Name: user.Name,
+	}, expectedUpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.userRepository.GetUserById(ctx, id); errors.Is(err, sql.ErrNoRows) {
+			return response.NewUserResponse{}, ErrUserNotFound
+		}
+		return response.NewUserResponse{}, ErrUserVersionMismatch
+	}
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
 
+	return toUserResponse(updated, s.avatars), nil
 }
 
-func (s *DefaultUserService) GetUserById(id uuid.UUID) (response.NewUserResponse, error) {
-	user, err := s.userRepository.GetUserById(id)
+// GetActiveUser returns the user only if it exists, is not deleted, has not
+// been deactivated and is not being purged. It is used to re-check accounts
+// on every request.
+func (s *DefaultUserService) GetActiveUser(ctx context.Context, id uuid.UUID) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetActiveUser")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.GetUserById(ctx, id)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	if !user.IsActive {
+		return response.NewUserResponse{}, ErrUserInactive
+	}
+	purging, err := s.userRepository.IsBeingPurged(ctx, id)
 	if err != nil {
 		return response.NewUserResponse{}, err
 	}
+	if purging {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *DefaultUserService) DeleteUser(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer func() { tracing.End(span, err) }()
+
+	deleted, err := s.userRepository.SoftDeleteUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *DefaultUserService) RestoreUser(ctx context.Context, id uuid.UUID) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.RestoreUser")
+	defer func() { tracing.End(span, err) }()
+
+	restored, err := s.userRepository.RestoreUser(ctx, id)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	if !restored {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	return s.GetUserById(ctx, id)
+}
+
+func (s *DefaultUserService) SetUserActive(ctx context.Context, id uuid.UUID, active bool) (resp response.NewUserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetUserActive")
+	defer func() { tracing.End(span, err) }()
+
+	updated, err := s.userRepository.SetUserActive(ctx, id, active)
+	if err != nil {
+		return response.NewUserResponse{}, err
+	}
+	if !updated {
+		return response.NewUserResponse{}, ErrUserNotFound
+	}
+	return s.GetUserById(ctx, id)
+}
+
+// toDeletedFilter maps the deleted query parameter to the repository filter.
+func toDeletedFilter(deleted string) repository.DeletedFilter {
+	switch deleted {
+	case request.DeletedInclude:
+		return repository.WithDeleted
+	case request.DeletedOnly:
+		return repository.OnlyDeleted
+	default:
+		return repository.WithoutDeleted
+	}
+}
+
+func toUserResponse(user repository.UserEntity, avatars AvatarURLResolver) response.NewUserResponse {
+	var deletedAt *time.Time
+	if !user.DeletedAt.IsZero() {
+		deletedAt = &user.DeletedAt
+	}
+
 	return response.NewUserResponse{
-		ID:    user.Id.String(),
-		Name:  user.Name,
-		Email: user.Email,
-	}, nil
+		ID:              user.Id.String(),
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            user.Role,
+		IsActive:        user.IsActive,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		AvatarURLs:      avatars.AvatarURLs(user.AvatarKey),
+		InvitedAt:       user.InvitedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		DeletedAt:       deletedAt,
+	}
 }
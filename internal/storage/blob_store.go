@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary objects such as avatars under slash-separated keys.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (body io.ReadCloser, contentType string, err error)
+	Delete(ctx context.Context, key string) error
+	// URL is the public address of the blob at key.
+	URL(key string) string
+	// Ping checks that the store can be reached.
+	Ping(ctx context.Context) error
+}
+
+// NewBlobStore returns the store selected by BLOB_STORE_DRIVER.
+func NewBlobStore(ctx context.Context, config runtime.BlobStoreConfig) (BlobStore, error) {
+	switch config.Driver {
+	case "local", "":
+		return NewLocalBlobStore(config)
+	case "s3":
+		return NewS3BlobStore(ctx, config)
+	default:
+		return nil, fmt.Errorf("unknown blob store driver %q", config.Driver)
+	}
+}
+
+func publicURL(base string, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
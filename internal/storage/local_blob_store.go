@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+)
+
+// LocalBlobStore keeps blobs as files below a directory. It is meant for
+// development and single-instance deployments.
+type LocalBlobStore struct {
+	root      string
+	publicURL string
+}
+
+func NewLocalBlobStore(config runtime.BlobStoreConfig) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(config.LocalDir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{root: config.LocalDir, publicURL: config.PublicURL}, nil
+}
+
+func (s *LocalBlobStore) Put(_ context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial blob.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalBlobStore) Get(_ context.Context, key string) (io.ReadCloser, string, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return f, mime.TypeByExtension(path.Ext(key)), nil
+}
+
+func (s *LocalBlobStore) Delete(_ context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) URL(key string) string {
+	return publicURL(s.publicURL, key)
+}
+
+func (s *LocalBlobStore) Ping(_ context.Context) error {
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.root)
+	}
+	return nil
+}
+
+// path maps a key to a file below the root, refusing keys that would escape it.
+func (s *LocalBlobStore) path(key string) (string, error) {
+	local := filepath.FromSlash(key)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, local), nil
+}
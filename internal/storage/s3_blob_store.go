@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// S3BlobStore keeps blobs in an S3-compatible bucket. Path-style addressing
+// is used so it also works with local stand-ins such as MinIO.
+type S3BlobStore struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3BlobStore connects to the bucket and creates it if it does not exist.
+func NewS3BlobStore(ctx context.Context, config runtime.BlobStoreConfig) (*S3BlobStore, error) {
+	transport, err := minio.DefaultTransport(config.S3UseSSL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := minio.New(config.S3Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.S3AccessKey, config.S3SecretKey, ""),
+		Secure:       config.S3UseSSL,
+		Region:       config.S3Region,
+		BucketLookup: minio.BucketLookupPath,
+		// Trace the calls and pass the trace context on to the store
+		Transport: otelhttp.NewTransport(transport),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, config.S3Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, config.S3Bucket, minio.MakeBucketOptions{Region: config.S3Region}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3BlobStore{client: client, bucket: config.S3Bucket, publicURL: config.PublicURL}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", toBlobError(err)
+	}
+	// GetObject is lazy; Stat makes the request and reports a missing key.
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, "", toBlobError(err)
+	}
+	return obj, info.ContentType, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	return toBlobError(s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}))
+}
+
+func (s *S3BlobStore) URL(key string) string {
+	return publicURL(s.publicURL, key)
+}
+
+func (s *S3BlobStore) Ping(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %q does not exist", s.bucket)
+	}
+	return nil
+}
+
+func toBlobError(err error) error {
+	if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrBlobNotFound
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+)
+
+// TestS3BlobStore runs against the MinIO started by `docker-compose up minio`,
+// or the S3_* variables when set, and is skipped when it cannot be reached.
+func TestS3BlobStore(t *testing.T) {
+	config := runtime.BlobStoreConfig{
+		PublicURL:   "http://localhost:8080/media",
+		S3Endpoint:  getenv("S3_ENDPOINT", "localhost:9000"),
+		S3Region:    getenv("S3_REGION", "us-east-1"),
+		S3Bucket:    getenv("S3_BUCKET", "user-app-test"),
+		S3AccessKey: getenv("S3_ACCESS_KEY", "minioadmin"),
+		S3SecretKey: getenv("S3_SECRET_KEY", "minioadmin"),
+		S3UseSSL:    os.Getenv("S3_USE_SSL") == "true",
+	}
+	conn, err := net.DialTimeout("tcp", config.S3Endpoint, time.Second)
+	if err != nil {
+		t.Skipf("S3 endpoint %s is unreachable: %v", config.S3Endpoint, err)
+	}
+	conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store, err := NewS3BlobStore(ctx, config)
+	if err != nil {
+		t.Fatalf("NewS3BlobStore: %v", err)
+	}
+	if err := store.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	key := "test/" + uuid.NewString() + "/blob.txt"
+	t.Cleanup(func() { store.Delete(context.Background(), key) })
+
+	const body = "hello blob"
+	if err := store.Put(ctx, key, strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	r, contentType, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if string(got) != body || contentType != "text/plain" {
+		t.Fatalf("Get returned %q as %q, want %q as text/plain", got, contentType, body)
+	}
+
+	if url := store.URL(key); url != "http://localhost:8080/media/"+key {
+		t.Fatalf("URL = %q", url)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Get after Delete: got %v, want ErrBlobNotFound", err)
+	}
+
+	// A cancelled call fails instead of waiting on the store
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := store.Put(cancelled, key, strings.NewReader(body), int64(len(body)), "text/plain"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Put with a cancelled context: got %v, want context.Canceled", err)
+	}
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
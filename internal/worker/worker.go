@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/lamkn06/user-app-golang.git/pkg/logging"
+)
+
+// Worker is a background job that runs until its context is cancelled.
+type Worker interface {
+	Name() string
+	Run(ctx context.Context)
+}
+
+// PollingWorker calls task every interval until the context is cancelled. The
+// task gets the same context and should return soon after it is cancelled.
+type PollingWorker struct {
+	name     string
+	interval time.Duration
+	task     func(ctx context.Context) error
+}
+
+func NewPollingWorker(name string, interval time.Duration, task func(ctx context.Context) error) *PollingWorker {
+	return &PollingWorker{name: name, interval: interval, task: task}
+}
+
+func (w *PollingWorker) Name() string {
+	return w.name
+}
+
+func (w *PollingWorker) Run(ctx context.Context) {
+	logger := logging.NewSugaredLogger(w.name)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	logger.Infow("Worker started", "interval", w.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infow("Worker stopped")
+			return
+		case <-ticker.C:
+			// A task cut short by the shutdown is not a failure
+			if err := w.task(ctx); err != nil && ctx.Err() == nil {
+				logger.Errorw("Worker task failed", "error", err)
+			}
+		}
+	}
+}
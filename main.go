@@ -21,38 +21,90 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/lamkn06/user-app-golang.git/internal/buildinfo"
+	"github.com/lamkn06/user-app-golang.git/internal/health"
+	"github.com/lamkn06/user-app-golang.git/internal/lifecycle"
+	"github.com/lamkn06/user-app-golang.git/internal/metrics"
 	"github.com/lamkn06/user-app-golang.git/internal/middleware"
 	"github.com/lamkn06/user-app-golang.git/internal/repository"
 	"github.com/lamkn06/user-app-golang.git/internal/route"
 	"github.com/lamkn06/user-app-golang.git/internal/runtime"
+	"github.com/lamkn06/user-app-golang.git/internal/tracing"
+	"github.com/lamkn06/user-app-golang.git/internal/worker"
 	"github.com/lamkn06/user-app-golang.git/pkg/logging"
 	"go.uber.org/zap"
 
 	"github.com/labstack/echo/v4"
 	_ "github.com/lamkn06/user-app-golang.git/docs" // This is required for swagger
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 var (
-	runtimeConfig runtime.ServerConfig
-	dbConfig      runtime.DatabaseConfig
-	jwtConfig     runtime.JWTConfig
+	runtimeConfig     runtime.ServerConfig
+	dbConfig          runtime.DatabaseConfig
+	jwtConfig         runtime.JWTConfig
+	webAuthnConfig    runtime.WebAuthnConfig
+	mailerConfig      runtime.MailerConfig
+	magicLinkConfig   runtime.MagicLinkConfig
+	dataExportConfig  runtime.DataExportConfig
+	deletionConfig    runtime.AccountDeletionConfig
+	blobStoreConfig   runtime.BlobStoreConfig
+	avatarConfig      runtime.AvatarConfig
+	emailChangeConfig runtime.EmailChangeConfig
+	invitationConfig  runtime.InvitationConfig
+	userImportConfig  runtime.UserImportConfig
+	loggingConfig     runtime.LoggingConfig
+	rateLimitConfig   runtime.RateLimitConfig
+	metricsConfig     runtime.MetricsConfig
+	tracingConfig     runtime.TracingConfig
+	healthConfig      runtime.HealthConfig
+	shutdownConfig    runtime.ShutdownConfig
+	timeoutConfig     runtime.TimeoutConfig
 )
 
 type Server struct {
-	config  runtime.ServerConfig
-	routers []route.Router
-	logger  *zap.SugaredLogger
+	config      runtime.ServerConfig
+	metrics     runtime.MetricsConfig
+	tracing     runtime.TracingConfig
+	shutdown    runtime.ShutdownConfig
+	routers     []route.Router
+	middlewares []echo.MiddlewareFunc
+	workers     []worker.Worker
+	checks      *health.Registry
+	logger      *zap.SugaredLogger
 }
 
-func (s *Server) start() {
+func (s *Server) newEcho() *echo.Echo {
 	server := echo.New()
 
-	server.HTTPErrorHandler = middleware.ErrorHandler
+	server.HTTPErrorHandler = middleware.ErrorHandler(strings.TrimSuffix(s.config.PublicURL, "/") + "/problems/")
+	// Only trust X-Forwarded-For from proxies on private networks, so clients
+	// cannot pick the IP they are rate limited by
+	server.IPExtractor = echo.ExtractIPFromXFFHeader()
+	// Tracing comes first so the request logger can add the trace ID. Probes
+	// and scrapes are not traced.
+	server.Use(otelecho.Middleware(s.tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		switch c.Path() {
+		case "/livez", "/readyz":
+			return true
+		}
+		return false
+	})))
+	server.Use(middleware.RequestLogger(logging.NewSugaredLogger("http")))
+	if s.metrics.Enabled {
+		server.Use(middleware.Metrics())
+	}
+	server.Use(s.middlewares...)
 
 	// Add Swagger route
 	server.GET("/swagger/*", echoSwagger.WrapHandler)
@@ -60,54 +112,159 @@ func (s *Server) start() {
 	for _, r := range s.routers {
 		r.Configure(server)
 	}
+	return server
+}
 
-	channel := make(chan error)
-	go func() {
-		channel <- server.Start(":" + s.config.Port)
-	}()
-
-	s.logger.Infof("Server started on port %s", s.config.Port)
+// hooks returns the components run by the server in start order: the
+// background workers, the metrics admin server and the API server.
+func (s *Server) hooks(app *lifecycle.Manager) []lifecycle.Hook {
+	hooks := []lifecycle.Hook{s.workersHook()}
 
-	select {
-	case sig := <-shutdownSignals():
-		s.logger.Infof("Shutting down server... Received signal: %v", sig)
-	case err := <-channel:
-		s.logger.Errorf("Failed to start server: %v", err)
+	// The admin server only serves metrics, which are unauthenticated; keep
+	// METRICS_PORT off the public network.
+	if s.metrics.Enabled {
+		admin := echo.New()
+		admin.HideBanner = true
+		admin.HidePort = true
+		admin.GET(s.metrics.Path, echo.WrapHandler(metrics.Handler()))
+		hooks = append(hooks, s.serverHook(app, "metrics_server", admin, s.metrics.Port, false))
 	}
+	return append(hooks, s.serverHook(app, "http_server", s.newEcho(), s.config.Port, true))
+}
 
-	ctx := context.Background()
-	if err := server.Shutdown(ctx); err != nil {
-		s.logger.Errorf("Failed to shutdown server: %v", err)
+// workersHook runs the background workers until shutdown, then waits for
+// the task each one is running to finish.
+func (s *Server) workersHook() lifecycle.Hook {
+	var stopWorkers context.CancelFunc
+	var wg sync.WaitGroup
+	return lifecycle.Hook{
+		Name: "workers",
+		Start: func(ctx context.Context) error {
+			var workerCtx context.Context
+			workerCtx, stopWorkers = context.WithCancel(context.WithoutCancel(ctx))
+			for _, w := range s.workers {
+				wg.Add(1)
+				go func(w worker.Worker) {
+					defer wg.Done()
+					w.Run(workerCtx)
+				}(w)
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopWorkers()
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("workers still running: %w", ctx.Err())
+			}
+		},
 	}
+}
 
-	s.logger.Info("Server shutdown complete")
+// serverHook listens on port when started, so a port in use fails startup.
+// When drain is set, stopping first fails readiness and waits for the drain
+// delay; the server then stops accepting connections and waits for the
+// requests in flight.
+func (s *Server) serverHook(app *lifecycle.Manager, name string, server *echo.Echo, port string, drain bool) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", ":"+port)
+			if err != nil {
+				return err
+			}
+			server.Listener = listener
+			go func() {
+				if err := server.Start(""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					app.Fail(name, err)
+				}
+			}()
+			s.logger.Infow("Server started", "server", name, "port", port)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if drain {
+				s.checks.Drain()
+				s.logger.Infow("Draining before shutdown", "server", name, "delay", s.shutdown.DrainDelay)
+				select {
+				case <-time.After(s.shutdown.DrainDelay):
+				case <-ctx.Done():
+				}
+			}
+			return server.Shutdown(ctx)
+		},
+	}
 }
 
 func main() {
-	runtime.LoadConfigs([]any{&runtimeConfig, &dbConfig, &jwtConfig})
+	runtime.LoadConfigs([]any{&runtimeConfig, &dbConfig, &jwtConfig, &webAuthnConfig, &mailerConfig, &magicLinkConfig, &dataExportConfig, &deletionConfig, &blobStoreConfig, &avatarConfig, &emailChangeConfig, &invitationConfig, &userImportConfig, &loggingConfig, &rateLimitConfig, &metricsConfig, &tracingConfig, &healthConfig, &shutdownConfig, &timeoutConfig})
 
-	logging.Init()
+	runtime.FailOnError(logging.Configure(logging.Options{
+		Development:        runtimeConfig.Environment == "development",
+		Level:              loggingConfig.Level,
+		SamplingInitial:    loggingConfig.SamplingInitial,
+		SamplingThereafter: loggingConfig.SamplingThereafter,
+		Unsampled:          []string{"http"},
+		Redact:             loggingConfig.Redact,
+		File:               loggingConfig.File,
+		FileMaxSizeMB:      loggingConfig.FileMaxSizeMB,
+		FileMaxBackups:     loggingConfig.FileMaxBackups,
+		FileMaxAgeDays:     loggingConfig.FileMaxAgeDays,
+		FileCompress:       loggingConfig.FileCompress,
+	}), "could not configure logging")
 	logger := logging.NewSugaredLogger("server")
-	logger.Infow("starting app")
+	logger.Infow("starting app", "version", buildinfo.Version, "commit", buildinfo.Commit, "build_time", buildinfo.BuildTime)
 
 	// Create startup context
 	ctx := logging.AddLoggerToContext(context.Background(), logger)
 
-	connectionString := dbConfig.PrimaryConnectionString()
-	logger.Infow("Database connection string", "connection", connectionString)
-	db, _ := repository.NewBunDB(ctx, connectionString)
+	app := lifecycle.NewManager(logger)
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingConfig, runtimeConfig.Environment)
+	runtime.FailOnError(err, "could not set up tracing")
+	app.Append(lifecycle.Hook{Name: "tracing", Stop: shutdownTracing})
+
+	logger.Infow("Connecting to database", "host", dbConfig.Host, "port", dbConfig.Port, "database", dbConfig.DBName, "user", dbConfig.User)
+	db, closeDB := repository.NewBunDB(ctx, dbConfig.PrimaryConnectionString())
+	app.Append(lifecycle.Hook{Name: "database", Stop: func(context.Context) error { return closeDB() }})
 
-	routers, err := route.Routers(ctx, runtimeConfig, db, jwtConfig)
+	if metricsConfig.Enabled {
+		if err := metrics.RegisterDB(db.DB, "postgres"); err != nil {
+			logger.Errorw("Failed to register database metrics", "error", err)
+		}
+	}
+
+	if len(os.Args) > 1 {
+		code := runCommand(ctx, db, os.Args[1:])
+		if err := app.Stop(ctx); err != nil {
+			code = 1
+		}
+		os.Exit(code)
+	}
+
+	checks := health.NewRegistry(healthConfig.CheckTimeout)
+	routers, middlewares, workers, err := route.Routers(ctx, runtimeConfig, db, jwtConfig, webAuthnConfig, mailerConfig, magicLinkConfig, dataExportConfig, deletionConfig, blobStoreConfig, avatarConfig, emailChangeConfig, invitationConfig, userImportConfig, rateLimitConfig, timeoutConfig, checks)
 	if err != nil {
 		logger.Errorw("Failed to get routers", "error", err)
+		app.Stop(ctx)
+		os.Exit(1)
 	}
 
-	s := Server{routers: routers, config: runtimeConfig, logger: logger}
-	s.start()
-}
+	s := Server{routers: routers, middlewares: middlewares, metrics: metricsConfig, tracing: tracingConfig, shutdown: shutdownConfig, workers: workers, checks: checks, config: runtimeConfig, logger: logger}
+	for _, hook := range s.hooks(app) {
+		app.Append(hook)
+	}
 
-func shutdownSignals() (signals <-chan os.Signal) {
-	channel := make(chan os.Signal, 1)
-	signal.Notify(channel, syscall.SIGABRT, syscall.SIGTERM)
-	return channel
+	if err := app.Run(ctx, shutdownConfig.Timeout); err != nil {
+		logger.Errorw("Server stopped with an error", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Server shutdown complete")
 }
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_webauthn_sessions_expires_at;
+DROP TABLE IF EXISTS webauthn_sessions;
+DROP INDEX IF EXISTS idx_webauthn_credentials_user_id;
+DROP TABLE IF EXISTS webauthn_credentials;
@@ -0,0 +1,4 @@
+-- Users signing in with magic links or passkeys may never set a password
+ALTER TABLE users ALTER COLUMN password DROP NOT NULL;
+
+UPDATE users SET password = NULL WHERE password = '';
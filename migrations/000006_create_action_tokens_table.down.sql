@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_action_tokens_expires_at;
+DROP INDEX IF EXISTS idx_action_tokens_user_id;
+DROP TABLE IF EXISTS action_tokens;
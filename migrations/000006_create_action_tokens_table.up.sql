@@ -0,0 +1,13 @@
+CREATE TABLE action_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL, -- magic_link
+    email VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_action_tokens_user_id ON action_tokens (user_id);
+CREATE INDEX idx_action_tokens_expires_at ON action_tokens (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user'; -- user, admin
+
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
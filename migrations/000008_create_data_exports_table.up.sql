@@ -0,0 +1,16 @@
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processing, completed, failed, expired
+    error TEXT,
+    archive BYTEA,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports (user_id);
+CREATE INDEX idx_data_exports_status ON data_exports (status);
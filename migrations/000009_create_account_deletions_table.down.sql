@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_tombstones;
+DROP TABLE IF EXISTS account_deletions;
@@ -0,0 +1,27 @@
+-- user_id has no foreign key: the row outlives the user so purge progress can still be read.
+CREATE TABLE account_deletions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled', -- scheduled, cancelled, purging, completed
+    scheduled_for TIMESTAMP NOT NULL,
+    total_rows BIGINT NOT NULL DEFAULT 0,
+    deleted_rows BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    cancelled_at TIMESTAMP NULL,
+    started_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_account_deletions_user_id ON account_deletions (user_id);
+CREATE INDEX idx_account_deletions_status_scheduled_for ON account_deletions (status, scheduled_for);
+CREATE UNIQUE INDEX idx_account_deletions_open_user_id ON account_deletions (user_id)
+    WHERE status IN ('scheduled', 'purging');
+
+-- The only record left of a purged user.
+CREATE TABLE user_tombstones (
+    user_id UUID PRIMARY KEY,
+    deletion_id UUID NOT NULL REFERENCES account_deletions(id),
+    purged_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_name_trgm;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;
+
+-- Trigram indexes keep substring search on name and email usable on large tables.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
+CREATE INDEX idx_users_created_at ON users (created_at);
@@ -0,0 +1,2 @@
+-- Prefix of the user's avatar thumbnails in the blob store, e.g. avatars/<user id>/<version>.
+ALTER TABLE users ADD COLUMN avatar_key VARCHAR(255) NULL;
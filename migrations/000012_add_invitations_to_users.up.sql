@@ -0,0 +1,5 @@
+-- Users created by an admin stay inactive with invited_at set until they accept the emailed invitation.
+ALTER TABLE users ADD COLUMN invited_at TIMESTAMP NULL;
+ALTER TABLE users ADD COLUMN invited_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_users_invited_at ON users (invited_at) WHERE invited_at IS NOT NULL;
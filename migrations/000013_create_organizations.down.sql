@@ -0,0 +1,16 @@
+-- Organization data has no user to fall back to
+DELETE FROM todos WHERE organization_id IS NOT NULL;
+DELETE FROM categories WHERE organization_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_todos_organization_id;
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS chk_todos_workspace;
+ALTER TABLE todos DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE todos ALTER COLUMN user_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_categories_organization_id;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS chk_categories_workspace;
+ALTER TABLE categories DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE categories ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
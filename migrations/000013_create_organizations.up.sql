@@ -0,0 +1,28 @@
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL, -- owner, admin, member
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members (user_id);
+
+-- Categories and todos belong to exactly one workspace: a user or an organization.
+ALTER TABLE categories ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE categories ADD COLUMN organization_id UUID NULL REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE categories ADD CONSTRAINT chk_categories_workspace CHECK (num_nonnulls(user_id, organization_id) = 1);
+CREATE INDEX idx_categories_organization_id ON categories (organization_id);
+
+ALTER TABLE todos ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE todos ADD COLUMN organization_id UUID NULL REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE todos ADD CONSTRAINT chk_todos_workspace CHECK (num_nonnulls(user_id, organization_id) = 1);
+CREATE INDEX idx_todos_organization_id ON todos (organization_id);
@@ -0,0 +1,16 @@
+-- A grant shares one category or one todo with another user.
+CREATE TABLE resource_grants (
+    id UUID PRIMARY KEY,
+    category_id UUID NULL REFERENCES categories(id) ON DELETE CASCADE,
+    todo_id UUID NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL, -- viewer, editor
+    granted_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_resource_grants_resource CHECK (num_nonnulls(category_id, todo_id) = 1)
+);
+
+CREATE UNIQUE INDEX idx_resource_grants_category_user ON resource_grants (category_id, user_id) WHERE category_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_resource_grants_todo_user ON resource_grants (todo_id, user_id) WHERE todo_id IS NOT NULL;
+CREATE INDEX idx_resource_grants_user_id ON resource_grants (user_id);
@@ -0,0 +1,12 @@
+-- Token buckets of the postgres rate limit backend. The table is unlogged:
+-- losing it in a crash only resets the limits, and it avoids WAL writes on
+-- every request.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    full_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_todos_created_by;
+DROP INDEX IF EXISTS idx_categories_created_by;
+ALTER TABLE todos DROP COLUMN IF EXISTS created_by;
+ALTER TABLE categories DROP COLUMN IF EXISTS created_by;
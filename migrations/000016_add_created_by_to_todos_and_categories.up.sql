@@ -0,0 +1,12 @@
+-- The user who created a row, which differs from the owner for rows created in
+-- an organization or in a category shared by another user.
+ALTER TABLE categories ADD COLUMN created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+-- Earlier personal rows are attributed to their owner; the authors of earlier
+-- organization rows are unknown.
+UPDATE categories SET created_by = user_id WHERE user_id IS NOT NULL;
+UPDATE todos SET created_by = user_id WHERE user_id IS NOT NULL;
+
+CREATE INDEX idx_categories_created_by ON categories (created_by);
+CREATE INDEX idx_todos_created_by ON todos (created_by);
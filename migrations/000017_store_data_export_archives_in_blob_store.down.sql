@@ -0,0 +1,3 @@
+UPDATE data_exports SET status = 'expired' WHERE status = 'completed';
+ALTER TABLE data_exports DROP COLUMN IF EXISTS archive_key;
+ALTER TABLE data_exports ADD COLUMN archive BYTEA;
@@ -0,0 +1,5 @@
+-- Archives move to the blob store; the row only keeps their key. Archives
+-- already built are not copied over, so their exports expire.
+UPDATE data_exports SET status = 'expired' WHERE status = 'completed';
+ALTER TABLE data_exports DROP COLUMN archive;
+ALTER TABLE data_exports ADD COLUMN archive_key VARCHAR(255) NULL;
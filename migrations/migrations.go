@@ -0,0 +1,31 @@
+// Package migrations embeds the SQL migrations applied by golang-migrate, so
+// the binary knows which schema version it was built for.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion is the version of the newest embedded migration.
+func LatestVersion() (uint, error) {
+	names, err := fs.Glob(FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
@@ -0,0 +1,7 @@
+package request
+
+type CategoryRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Color       string `json:"color" validate:"omitempty,hexcolor,max=7"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,45 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+var ErrInvalidCursor = exception.NewError("invalid_cursor", "invalid cursor")
+
+// Cursor is the decoded form of the opaque cursor parameter. It points at the
+// row a page starts after, or before when Backward is set.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Backward  bool
+}
+
+type cursorPayload struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// Encode returns the opaque string clients send back as ?cursor=.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(cursorPayload{CreatedAt: c.CreatedAt, ID: c.ID, Backward: c.Backward})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a cursor produced by Encode.
+func ParseCursor(raw string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.ID == uuid.Nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: payload.CreatedAt, ID: payload.ID, Backward: payload.Backward}, nil
+}
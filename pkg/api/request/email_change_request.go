@@ -0,0 +1,9 @@
+package request
+
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email,max=100"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
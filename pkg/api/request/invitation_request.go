@@ -0,0 +1,8 @@
+package request
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+	// Name replaces the name the admin entered, if set.
+	Name string `json:"name" validate:"omitempty,max=100"`
+}
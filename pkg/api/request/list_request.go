@@ -1,9 +1,15 @@
 package request
 
-// ListRequest represents pagination parameters for list endpoints
+// ListRequest represents pagination parameters for list endpoints. Lists are
+// paged either by page number or, when Cursor is set, by cursor.
 type ListRequest struct {
 	Page  int `json:"page" form:"page" query:"page" validate:"min=1"`
 	Limit int `json:"limit" form:"limit" query:"limit" validate:"min=1,max=100"`
+	// Cursor is a nextCursor or prevCursor from an earlier response
+	Cursor string `json:"cursor" form:"cursor" query:"cursor"`
+	// IncludeTotal asks for the exact total; it defaults to true for page
+	// numbers and false for cursors
+	IncludeTotal *bool `json:"include_total" form:"include_total" query:"include_total"`
 }
 
 // NewListRequest creates a new ListRequest with default values
@@ -37,3 +43,23 @@ func (r *ListRequest) GetLimit() int {
 func (r *ListRequest) GetOffset() int {
 	return (r.GetPage() - 1) * r.GetLimit()
 }
+
+// GetCursor decodes the cursor parameter; it returns nil when none was given
+func (r *ListRequest) GetCursor() (*Cursor, error) {
+	if r.Cursor == "" {
+		return nil, nil
+	}
+	cursor, err := ParseCursor(r.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// WantsTotal reports whether the exact total should be counted
+func (r *ListRequest) WantsTotal() bool {
+	if r.IncludeTotal != nil {
+		return *r.IncludeTotal
+	}
+	return r.Cursor == ""
+}
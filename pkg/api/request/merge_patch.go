@@ -0,0 +1,37 @@
+package request
+
+import "encoding/json"
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396) to a JSON document and
+// returns the patched document.
+func ApplyMergePatch(document, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, changes))
+}
+
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,24 @@
+package request
+
+type OrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type OrganizationInviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type OrganizationMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type OrganizationInvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// SwitchWorkspaceRequest selects the active workspace; without an
+// organization the personal workspace becomes active.
+type SwitchWorkspaceRequest struct {
+	OrganizationID *string `json:"organization_id" validate:"omitempty,uuid"`
+}
@@ -0,0 +1,20 @@
+package request
+
+import "encoding/json"
+
+type FinishPasskeyRegistrationRequest struct {
+	SessionID  string          `json:"session_id" validate:"required,uuid"`
+	Name       string          `json:"name" validate:"omitempty,max=100"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// BeginPasskeyLoginRequest starts a passkey sign-in. When Email is empty the
+// ceremony is discoverable and the authenticator picks the account.
+type BeginPasskeyLoginRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type FinishPasskeyLoginRequest struct {
+	SessionID  string          `json:"session_id" validate:"required,uuid"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
@@ -0,0 +1,10 @@
+package request
+
+type ShareRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=viewer editor"`
+}
+
+type UnshareRequest struct {
+	Email string `query:"email" validate:"required,email"`
+}
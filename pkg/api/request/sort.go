@@ -0,0 +1,40 @@
+package request
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+// SortField is one key of a sort parameter such as "-created_at,name".
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma-separated sort parameter. A leading "-" sorts the
+// field in descending order. Fields not in allowed are rejected.
+func ParseSort(raw string, allowed []string) ([]SortField, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+		if !slices.Contains(allowed, name) {
+			fields := strings.Join(allowed, ", ")
+			return nil, &exception.Error{
+				ID:      "invalid_sort_field",
+				Message: fmt.Sprintf("cannot sort by %q; allowed fields are %s", name, fields),
+				Args:    []string{name, fields},
+			}
+		}
+		fields = append(fields, SortField{Field: name, Desc: desc})
+	}
+	return fields, nil
+}
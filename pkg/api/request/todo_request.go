@@ -0,0 +1,23 @@
+package request
+
+import "time"
+
+type TodoRequest struct {
+	Title       string     `json:"title" validate:"required,max=255"`
+	Description string     `json:"description"`
+	CategoryID  *string    `json:"category_id" validate:"omitempty,uuid"`
+	Priority    string     `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	Status      string     `json:"status" validate:"omitempty,oneof=pending in_progress completed cancelled"`
+	DueDate     *time.Time `json:"due_date"`
+}
+
+// TodoListRequest is a page of todos, optionally narrowed by status or category.
+type TodoListRequest struct {
+	ListRequest
+	Status     string `query:"status" validate:"omitempty,oneof=pending in_progress completed cancelled"`
+	CategoryID string `query:"category_id" validate:"omitempty,uuid"`
+}
+
+func NewTodoListRequest() TodoListRequest {
+	return TodoListRequest{ListRequest: NewListRequest()}
+}
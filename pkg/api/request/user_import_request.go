@@ -0,0 +1,10 @@
+package request
+
+// UserImportRequest holds the options of a CSV user import.
+type UserImportRequest struct {
+	// DryRun validates the file and reports what would happen without
+	// creating anyone.
+	DryRun bool `query:"dry_run"`
+	// SendInvitations emails every created user an invite link.
+	SendInvitations bool `query:"send_invitations"`
+}
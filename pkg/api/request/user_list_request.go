@@ -0,0 +1,54 @@
+package request
+
+import (
+	"time"
+
+	"github.com/lamkn06/user-app-golang.git/pkg/exception"
+)
+
+const (
+	DeletedExclude = "exclude"
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+// UserSortFields are the fields GET /users can be sorted by.
+var UserSortFields = []string{"name", "email", "created_at", "updated_at"}
+
+// UserListRequest holds the search, filter and sort parameters of GET /users
+// on top of the pagination parameters.
+type UserListRequest struct {
+	ListRequest
+	Query         string     `query:"q" validate:"max=100"`
+	Active        *bool      `query:"active"`
+	Verified      *bool      `query:"verified"`
+	Deleted       string     `query:"deleted" validate:"omitempty,oneof=exclude include only"`
+	CreatedAfter  *time.Time `query:"created_after"`
+	CreatedBefore *time.Time `query:"created_before"`
+	Sort          string     `query:"sort"`
+}
+
+// NewUserListRequest creates a UserListRequest with default values
+func NewUserListRequest() UserListRequest {
+	return UserListRequest{ListRequest: NewListRequest(), Deleted: DeletedExclude}
+}
+
+// SortFields parses the sort parameter against UserSortFields
+func (r *UserListRequest) SortFields() ([]SortField, error) {
+	return ParseSort(r.Sort, UserSortFields)
+}
+
+// Validate checks the parameters the validator tags cannot: the sort fields
+// and the cursor, which only works with the default newest-first order.
+func (r *UserListRequest) Validate() error {
+	if _, err := r.SortFields(); err != nil {
+		return err
+	}
+	if _, err := r.GetCursor(); err != nil {
+		return err
+	}
+	if r.Cursor != "" && r.Sort != "" {
+		return exception.NewError("cursor_with_sort", "cursor pagination cannot be combined with sort")
+	}
+	return nil
+}
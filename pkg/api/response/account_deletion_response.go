@@ -0,0 +1,19 @@
+package response
+
+import "time"
+
+type AccountDeletionResponse struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	Status       string    `json:"status"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	TotalRows    int64     `json:"total_rows"`
+	DeletedRows  int64     `json:"deleted_rows"`
+	// Progress is the share of rows purged so far, from 0 to 100.
+	Progress    float64    `json:"progress"`
+	Error       string     `json:"error,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
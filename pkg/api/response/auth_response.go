@@ -9,8 +9,15 @@ type SignInResponse struct {
 	Token        string          `json:"token"`
 	RefreshToken string          `json:"refresh_token"`
 	User         NewUserResponse `json:"user"`
+	// OrganizationID is the active organization of the token; it is left out
+	// for the personal workspace.
+	OrganizationID *string `json:"organization_id,omitempty"`
 }
 
 type SignOutResponse struct {
 	Message string `json:"message"`
 }
+
+type MagicLinkResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,16 @@
+package response
+
+import "time"
+
+type CategoryResponse struct {
+	ID             string  `json:"id"`
+	UserID         *string `json:"user_id,omitempty"`
+	OrganizationID *string `json:"organization_id,omitempty"`
+	Name           string  `json:"name"`
+	Color          string  `json:"color,omitempty"`
+	Description    string  `json:"description,omitempty"`
+	// Access is what the caller may do: owner, editor or viewer
+	Access    string    `json:"access,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,9 @@
+package response
+
+import "time"
+
+type EmailChangeResponse struct {
+	Message   string    `json:"message"`
+	NewEmail  string    `json:"new_email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
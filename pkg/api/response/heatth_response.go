@@ -5,3 +5,26 @@ type HealthResponse struct {
 	Version string `json:"version"`
 	Message string `json:"message"`
 }
+
+type BuildInfoResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+}
+
+type LivenessResponse struct {
+	Status string            `json:"status"`
+	Build  BuildInfoResponse `json:"build"`
+}
+
+type ReadinessResponse struct {
+	Status string                         `json:"status"`
+	Build  BuildInfoResponse              `json:"build"`
+	Checks map[string]HealthCheckResponse `json:"checks"`
+}
+
+type HealthCheckResponse struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Detail     string  `json:"detail,omitempty"`
+}
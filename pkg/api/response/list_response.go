@@ -1,11 +1,15 @@
 package response
 
-// ListMeta represents metadata for list responses
+// ListMeta represents metadata for list responses. Total and TotalPages are
+// only set when the total was counted; Page is only set for page-number
+// pagination.
 type ListMeta struct {
-	Total      int64 `json:"total"`
-	Page       int   `json:"page"`
-	Limit      int   `json:"limit"`
-	TotalPages int   `json:"totalPages"`
+	Total      *int64 `json:"total,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	TotalPages *int   `json:"totalPages,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
 }
 
 // ListResponse represents a paginated list response
@@ -18,19 +22,29 @@ type ListResponse[T any] struct {
 
 // NewListResponse creates a new list response
 func NewListResponse[T any](items []T, total int64, page, limit int) ListResponse[T] {
-	totalPages := int((total + int64(limit) - 1) / int64(limit))
-	if totalPages == 0 {
-		totalPages = 1
-	}
+	return ListResponse[T]{
+		Meta:    ListMeta{Page: page, Limit: limit},
+		Items:   items,
+		Success: true,
+	}.WithTotal(total)
+}
 
+// NewCursorListResponse creates a list response for cursor pagination
+func NewCursorListResponse[T any](items []T, limit int, nextCursor, prevCursor string) ListResponse[T] {
 	return ListResponse[T]{
-		Meta: ListMeta{
-			Total:      total,
-			Page:       page,
-			Limit:      limit,
-			TotalPages: totalPages,
-		},
+		Meta:    ListMeta{Limit: limit, NextCursor: nextCursor, PrevCursor: prevCursor},
 		Items:   items,
 		Success: true,
 	}
 }
+
+// WithTotal sets the total and the number of pages it makes
+func (r ListResponse[T]) WithTotal(total int64) ListResponse[T] {
+	totalPages := int((total + int64(r.Meta.Limit) - 1) / int64(r.Meta.Limit))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	r.Meta.Total = &total
+	r.Meta.TotalPages = &totalPages
+	return r
+}
@@ -0,0 +1,27 @@
+package response
+
+import "time"
+
+type OrganizationResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Role is the caller's role in the organization
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OrganizationMemberResponse struct {
+	UserID   string    `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type OrganizationInvitationResponse struct {
+	Message   string    `json:"message"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,21 @@
+package response
+
+import "time"
+
+// PasskeyOptionsResponse carries the WebAuthn options to pass to
+// navigator.credentials.create() or navigator.credentials.get(), and the
+// session that must be echoed back when finishing the ceremony.
+type PasskeyOptionsResponse struct {
+	SessionID string `json:"session_id"`
+	Options   any    `json:"options"`
+}
+
+type PasskeyResponse struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Transports     []string   `json:"transports"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backup_state"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,14 @@
+package response
+
+// ProblemResponse is an RFC 9457 problem details body, sent as
+// application/problem+json. Code and Details are extension members carrying
+// the same values as ErrorResponse.
+type ProblemResponse struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	Details  any    `json:"details,omitempty"`
+}
@@ -0,0 +1,13 @@
+package response
+
+import "time"
+
+// ShareResponse is a user a category or todo is shared with.
+type ShareResponse struct {
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	SharedAt  time.Time `json:"shared_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package response
+
+import "time"
+
+type TodoResponse struct {
+	ID             string     `json:"id"`
+	UserID         *string    `json:"user_id,omitempty"`
+	OrganizationID *string    `json:"organization_id,omitempty"`
+	CategoryID     *string    `json:"category_id,omitempty"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description,omitempty"`
+	Priority       string     `json:"priority"`
+	Status         string     `json:"status"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	// Access is what the caller may do: owner, editor or viewer
+	Access    string    `json:"access,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
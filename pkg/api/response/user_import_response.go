@@ -0,0 +1,35 @@
+package response
+
+import "github.com/lamkn06/user-app-golang.git/pkg/exception"
+
+const (
+	UserImportRowCreated   = "created"
+	UserImportRowValid     = "valid"
+	UserImportRowInvalid   = "invalid"
+	UserImportRowDuplicate = "duplicate"
+	UserImportRowExists    = "exists"
+)
+
+// UserImportResponse reports the outcome of a CSV import row by row.
+type UserImportResponse struct {
+	DryRun bool `json:"dry_run"`
+	Total  int  `json:"total"`
+	// Valid counts the rows that passed every check; outside a dry run they
+	// were all created.
+	Valid   int                     `json:"valid"`
+	Created int                     `json:"created"`
+	Failed  int                     `json:"failed"`
+	Rows    []UserImportRowResponse `json:"rows"`
+}
+
+type UserImportRowResponse struct {
+	// Row is the line of the row in the file; the header is line 1.
+	Row    int    `json:"row"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	UserID string `json:"user_id,omitempty"`
+	// Errors explains why the row was rejected or, for created users, why
+	// the invitation could not be sent.
+	Errors []exception.ErrorDetail `json:"errors,omitempty"`
+}
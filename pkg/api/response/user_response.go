@@ -1,7 +1,25 @@
 package response
 
+import (
+	"strconv"
+	"time"
+)
+
 type NewUserResponse struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Email           string            `json:"email"`
+	Role            string            `json:"role"`
+	IsActive        bool              `json:"is_active"`
+	EmailVerifiedAt *time.Time        `json:"email_verified_at,omitempty"`
+	AvatarURLs      map[string]string `json:"avatar_urls,omitempty"`
+	InvitedAt       *time.Time        `json:"invited_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       *time.Time        `json:"deleted_at,omitempty"`
+}
+
+// ETag identifies the version of the user. It changes whenever updated_at does.
+func (r NewUserResponse) ETag() string {
+	return `"` + strconv.FormatInt(r.UpdatedAt.UnixMicro(), 10) + `"`
 }
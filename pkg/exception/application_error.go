@@ -1,25 +1,80 @@
 package exception
 
 import (
-	"strings"
+	"context"
+	"errors"
+	"net/http"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
 )
 
 type ErrorDetail struct {
 	Key     string `json:"key"`
 	Field   string `json:"field"`
 	Message string `json:"message"`
+	// MessageID names Message in the translation catalogue of package i18n,
+	// and MessageArgs fill its placeholders.
+	MessageID   string   `json:"-"`
+	MessageArgs []string `json:"-"`
+	// fieldError is the failed rule a validation detail describes, kept to
+	// translate Message.
+	fieldError validator.FieldError
 }
 
 type ApplicationError struct {
 	Code    string        `json:"code"`
 	Message string        `json:"message"`
 	Details []ErrorDetail `json:"details"`
+	// MessageID names Message in the translation catalogue of package i18n,
+	// and MessageArgs fill its placeholders. Messages without an ID are not
+	// translated.
+	MessageID   string   `json:"-"`
+	MessageArgs []string `json:"-"`
 }
 
 func (e *ApplicationError) Error() string {
 	return e.Message
 }
 
+// Localize returns a copy of e with its message and details in the language
+// of trans. Messages without a translation are kept.
+func (e ApplicationError) Localize(trans ut.Translator) ApplicationError {
+	e.Message = translate(trans, e.MessageID, e.Message, e.MessageArgs...)
+	e.Details = LocalizeDetails(e.Details, trans)
+	return e
+}
+
+// LocalizeDetails returns details with their messages in the language of
+// trans.
+func LocalizeDetails(details []ErrorDetail, trans ut.Translator) []ErrorDetail {
+	if details == nil {
+		return nil
+	}
+	localized := make([]ErrorDetail, len(details))
+	for i, detail := range details {
+		if detail.fieldError != nil {
+			detail.Message = detail.fieldError.Translate(trans)
+		} else {
+			detail.Message = translate(trans, detail.MessageID, detail.Message, detail.MessageArgs...)
+		}
+		localized[i] = detail
+	}
+	return localized
+}
+
+// translate looks up the message named id, falling back to message.
+func translate(trans ut.Translator, id string, message string, args ...string) string {
+	if id == "" {
+		return message
+	}
+	if translated, err := trans.T(id, args...); err == nil {
+		return translated
+	}
+	return message
+}
+
 func (e *ApplicationError) HTTPStatus() int {
 	switch e.Code {
 	case ErrorCodeValidation:
@@ -32,66 +87,139 @@ func (e *ApplicationError) HTTPStatus() int {
 		return 403
 	case ErrorCodeNotFound:
 		return 404
+	case ErrorCodeConflict:
+		return 409
+	case ErrorCodeGone:
+		return 410
+	case ErrorCodePreconditionFailed:
+		return 412
+	case ErrorCodePayloadTooLarge:
+		return 413
+	case ErrorCodeUnsupportedMediaType:
+		return 415
+	case ErrorCodePreconditionRequired:
+		return 428
 	case ErrorCodeTooManyRequests:
 		return 429
+	case ErrorCodeTimeout:
+		return 503
 	default:
 		return 500
 	}
 }
 
 func ToApplicationError(err error, code string) *ApplicationError {
-	appErr, ok := err.(*ApplicationError)
-	if ok {
+	var appErr *ApplicationError
+	if errors.As(err, &appErr) {
 		return appErr
 	}
 
-	// Hide database-related errors for security
-	if code == ErrorCodeInternalServerError || isDatabaseError(err) {
+	// Echo's errors, such as failed binding, describe parser internals
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return FromHTTPError(he)
+	}
+
+	// Typed errors decide the code themselves; what they wrap is not shown
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return &ApplicationError{Code: ErrorCodeNotFound, Message: "Resource not found", MessageID: "resource_not_found", Details: []ErrorDetail{}}
+	case errors.Is(err, ErrConflict):
+		return &ApplicationError{Code: ErrorCodeConflict, Message: "Resource conflicts with existing data", MessageID: "resource_conflict", Details: []ErrorDetail{}}
+	case errors.Is(err, ErrForbidden):
+		return &ApplicationError{Code: ErrorCodeForbidden, Message: "Access denied", MessageID: "access_denied", Details: []ErrorDetail{}}
+	}
+
+	// The request ran out of time, whatever the handler was doing
+	if errors.Is(err, context.DeadlineExceeded) {
 		return &ApplicationError{
-			Code:    code,
-			Message: "Internal server error",
-			Details: []ErrorDetail{},
+			Code:      ErrorCodeTimeout,
+			Message:   "The request took too long to complete",
+			MessageID: "request_timeout",
+			Details:   []ErrorDetail{},
 		}
 	}
 
-	return &ApplicationError{
+	// Internal errors keep their text to the logs
+	if code == ErrorCodeInternalServerError {
+		return internalError(code)
+	}
+
+	appErr = &ApplicationError{
 		Code:    code,
 		Message: err.Error(),
 		Details: []ErrorDetail{},
 	}
+	var clientErr *Error
+	if errors.As(err, &clientErr) {
+		appErr.MessageID, appErr.MessageArgs = clientErr.ID, clientErr.Args
+	}
+	return appErr
 }
 
-// isDatabaseError checks if the error is related to database operations
-func isDatabaseError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	errMsg := strings.ToLower(err.Error())
-
-	// Check for common database error patterns
-	databaseKeywords := []string{
-		"database",
-		"connection",
-		"sql",
-		"postgres",
-		"mysql",
-		"constraint",
-		"foreign key",
-		"duplicate",
-		"unique",
-		"not null",
-		"timeout",
-		"deadlock",
-		"rollback",
-		"transaction",
-	}
-
-	for _, keyword := range databaseKeywords {
-		if strings.Contains(errMsg, keyword) {
-			return true
-		}
+func internalError(code string) *ApplicationError {
+	return &ApplicationError{
+		Code:      code,
+		Message:   "Internal server error",
+		MessageID: "internal_server_error",
+		Details:   []ErrorDetail{},
 	}
+}
 
-	return false
+// httpMessages are the messages of echo errors by status. Echo's own texts
+// are not shown: those of binding errors quote the parser.
+var httpMessages = map[int]struct{ id, message string }{
+	http.StatusBadRequest:            {"malformed_request", "The request could not be read"},
+	http.StatusUnauthorized:          {"authentication_required", "Authentication required"},
+	http.StatusForbidden:             {"access_denied", "Access denied"},
+	http.StatusNotFound:              {"resource_not_found", "Resource not found"},
+	http.StatusMethodNotAllowed:      {"method_not_allowed", "Method not allowed"},
+	http.StatusRequestEntityTooLarge: {"request_too_large", "The request is too large"},
+	http.StatusUnsupportedMediaType:  {"unsupported_media_type", "Unsupported media type"},
+	http.StatusTooManyRequests:       {"too_many_requests", "Too many requests"},
+}
+
+// FromHTTPError converts an echo error. Its code follows the status, and its
+// message only names the status; server errors keep theirs to the logs.
+func FromHTTPError(he *echo.HTTPError) *ApplicationError {
+	code := codeForStatus(he.Code)
+	if he.Code >= http.StatusInternalServerError {
+		return internalError(code)
+	}
+	appErr := &ApplicationError{Code: code, Message: http.StatusText(he.Code), Details: []ErrorDetail{}}
+	if m, ok := httpMessages[he.Code]; ok {
+		appErr.Message, appErr.MessageID = m.message, m.id
+	}
+	return appErr
+}
+
+// codeForStatus names the error code of errors that only have a status, such
+// as echo's own 404 and 405 errors.
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed:
+		return ErrorCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrorCodeForbidden
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusConflict:
+		return ErrorCodeConflict
+	case http.StatusGone:
+		return ErrorCodeGone
+	case http.StatusPreconditionFailed:
+		return ErrorCodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return ErrorCodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrorCodeUnsupportedMediaType
+	case http.StatusPreconditionRequired:
+		return ErrorCodePreconditionRequired
+	case http.StatusTooManyRequests:
+		return ErrorCodeTooManyRequests
+	default:
+		return ErrorCodeInternalServerError
+	}
 }
@@ -1,12 +1,19 @@
 package exception
 
 const (
-	ErrorCodeInternalServerError = "INTERNAL_SERVER_ERROR"
-	ErrorCodeNotFound            = "NOT_FOUND"
-	ErrorCodeBadRequest          = "BAD_REQUEST"
-	ErrorCodeUnauthorized        = "UNAUTHORIZED"
-	ErrorCodeForbidden           = "FORBIDDEN"
-	ErrorCodeTooManyRequests     = "TOO_MANY_REQUESTS"
-	ErrorCodeValidation          = "VALIDATION"
-	ErrorCodeFailedBindingData   = "FAILED_BINDING_DATA"
+	ErrorCodeInternalServerError  = "INTERNAL_SERVER_ERROR"
+	ErrorCodeNotFound             = "NOT_FOUND"
+	ErrorCodeBadRequest           = "BAD_REQUEST"
+	ErrorCodeUnauthorized         = "UNAUTHORIZED"
+	ErrorCodeForbidden            = "FORBIDDEN"
+	ErrorCodeConflict             = "CONFLICT"
+	ErrorCodeGone                 = "GONE"
+	ErrorCodePreconditionFailed   = "PRECONDITION_FAILED"
+	ErrorCodePreconditionRequired = "PRECONDITION_REQUIRED"
+	ErrorCodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	ErrorCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeTooManyRequests      = "TOO_MANY_REQUESTS"
+	ErrorCodeTimeout              = "TIMEOUT"
+	ErrorCodeValidation           = "VALIDATION"
+	ErrorCodeFailedBindingData    = "FAILED_BINDING_DATA"
 )
@@ -0,0 +1,30 @@
+package exception
+
+import "errors"
+
+// Typed errors say what kind of failure happened, whichever layer it comes
+// from. Wrap them with %w; ToApplicationError gives the wrapping error the
+// matching code and status without showing its message.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrConflict  = errors.New("conflict")
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Error is an error whose message is shown to clients. ID names the message
+// in the translation catalogue of package i18n, and Args fill its
+// placeholders.
+type Error struct {
+	ID      string
+	Message string
+	Args    []string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError returns an error with a client-facing message, translated by id.
+func NewError(id, message string) error {
+	return &Error{ID: id, Message: message}
+}
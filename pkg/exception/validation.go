@@ -0,0 +1,21 @@
+package exception
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/lamkn06/user-app-golang.git/pkg/i18n"
+)
+
+// ValidationDetails describes every failed validator rule in English. The
+// errors must come from i18n.Validator.
+func ValidationDetails(errs validator.ValidationErrors) []ErrorDetail {
+	details := make([]ErrorDetail, 0, len(errs))
+	for _, vErr := range errs {
+		details = append(details, ErrorDetail{
+			Key:        vErr.StructNamespace(),
+			Field:      vErr.Field(),
+			Message:    vErr.Translate(i18n.Default()),
+			fieldError: vErr,
+		})
+	}
+	return details
+}
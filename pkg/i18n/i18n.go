@@ -0,0 +1,62 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/vi"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// universal holds a translator per supported language; English is the
+// fallback.
+var universal = ut.New(en.New(), en.New(), vi.New())
+
+func init() {
+	trans, _ := universal.GetTranslator("vi")
+	for message, text := range viMessages {
+		if err := trans.Add(message, text, false); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Default returns the English translator.
+func Default() ut.Translator {
+	return universal.GetFallback()
+}
+
+// Translator returns the translator of the supported language the
+// Accept-Language header prefers, or the English one when it names none.
+func Translator(acceptLanguage string) ut.Translator {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if tag != "" && q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		// Regional variants such as vi-VN use the language's translator
+		primary, _, _ := strings.Cut(strings.ToLower(t.tag), "-")
+		if trans, found := universal.GetTranslator(primary); found {
+			return trans
+		}
+	}
+	return Default()
+}
@@ -0,0 +1,78 @@
+package i18n
+
+// viMessages translates the error messages of the API by message ID. The
+// English text stays with the code that raises the error; messages missing
+// here are shown in English. Placeholders such as {0} are filled from the
+// error's arguments.
+var viMessages = map[string]string{
+	// Errors built by handlers and middleware
+	"access_denied":                   "Truy cập bị từ chối",
+	"authorization_header_required":   "Cần có header Authorization",
+	"data_export_gone":                "Bản xuất dữ liệu không còn khả dụng",
+	"file_too_large":                  "Tệp quá lớn",
+	"if_match_required":               "Cần có header If-Match",
+	"insufficient_permissions":        "Không đủ quyền",
+	"internal_server_error":           "Lỗi máy chủ nội bộ",
+	"invalid_authorization_header":    "Header Authorization không đúng định dạng",
+	"invalid_credentials":             "Thông tin đăng nhập không hợp lệ",
+	"invalid_link":                    "Liên kết không hợp lệ hoặc đã hết hạn",
+	"invalid_token":                   "Token không hợp lệ",
+	"invalid_token_claims":            "Thông tin trong token không hợp lệ",
+	"merge_patch_content_type":        "Content-Type phải là application/merge-patch+json hoặc application/json",
+	"organization_membership_revoked": "Bạn không còn là thành viên của tổ chức đang hoạt động",
+	"rate_limited":                    "Quá nhiều yêu cầu, hãy thử lại sau {0} giây",
+	"request_timeout":                 "Yêu cầu mất quá nhiều thời gian để hoàn thành",
+	"resource_conflict":               "Tài nguyên xung đột với dữ liệu hiện có",
+	"resource_not_found":              "Không tìm thấy tài nguyên",
+	"validation_failed":               "Dữ liệu không hợp lệ",
+
+	// Errors echo raises itself, by status
+	"authentication_required": "Cần xác thực",
+	"malformed_request":       "Không thể đọc yêu cầu",
+	"method_not_allowed":      "Phương thức không được hỗ trợ",
+	"request_too_large":       "Yêu cầu quá lớn",
+	"too_many_requests":       "Quá nhiều yêu cầu",
+	"unsupported_media_type":  "Kiểu nội dung không được hỗ trợ",
+
+	// Service errors shown to clients
+	"account_deletion_not_found":    "Không tìm thấy yêu cầu xóa tài khoản",
+	"action_token_invalid":          "Token không hợp lệ, đã hết hạn hoặc đã được sử dụng",
+	"already_organization_member":   "Người dùng đã là thành viên của tổ chức",
+	"avatar_invalid":                "Ảnh đại diện không phải là ảnh hợp lệ",
+	"avatar_too_large":              "Tệp ảnh đại diện quá lớn",
+	"avatar_unsupported_type":       "Ảnh đại diện phải là ảnh JPEG, PNG, GIF hoặc WebP",
+	"category_forbidden":            "Bạn không có quyền thay đổi danh mục này",
+	"category_not_found":            "Không tìm thấy danh mục",
+	"cursor_with_sort":              "Không thể kết hợp phân trang bằng con trỏ với sắp xếp",
+	"data_export_not_found":         "Không tìm thấy bản xuất dữ liệu",
+	"data_export_not_ready":         "Bản xuất dữ liệu chưa sẵn sàng để tải xuống",
+	"email_change_not_allowed":      "Chỉ có thể đổi email bằng cách xác nhận địa chỉ mới",
+	"email_taken":                   "Email đã được sử dụng",
+	"email_unchanged":               "Email mới trùng với email hiện tại",
+	"import_duplicate_email":        "Trùng email với dòng {0}",
+	"import_empty":                  "Tệp không có dòng tiêu đề",
+	"import_malformed":              "Tệp không phải là CSV hợp lệ",
+	"import_missing_columns":        "Dòng tiêu đề phải có cột name và email",
+	"import_too_many_rows":          "Tệp có quá nhiều dòng",
+	"invalid_cursor":                "Con trỏ phân trang không hợp lệ",
+	"invalid_sort_field":            "Không thể sắp xếp theo {0}; các trường được phép là {1}",
+	"invitation_not_found":          "Người dùng không có lời mời nào đang chờ",
+	"last_organization_owner":       "Một tổ chức cần có ít nhất một chủ sở hữu",
+	"organization_forbidden":        "Vai trò của bạn trong tổ chức không cho phép thao tác này",
+	"organization_member_not_found": "Không tìm thấy thành viên của tổ chức",
+	"organization_not_found":        "Không tìm thấy tổ chức",
+	"passkey_cloned":                "Bộ đếm chữ ký của passkey không tăng, thiết bị xác thực có thể đã bị sao chép",
+	"passkey_not_found":             "Không tìm thấy passkey",
+	"passkey_session_invalid":       "Phiên passkey không hợp lệ hoặc đã hết hạn",
+	"share_forbidden":               "Chỉ chủ sở hữu mới có thể quản lý việc chia sẻ",
+	"share_not_found":               "Chưa được chia sẻ với người dùng này",
+	"share_user_not_found":          "Không có người dùng nào với email này",
+	"share_with_owner":              "Không thể chia sẻ với chính chủ sở hữu",
+	"sole_organization_owner":       "Hãy chuyển quyền sở hữu hoặc xóa các tổ chức mà chỉ bạn sở hữu trước khi xóa tài khoản",
+	"todo_category_invalid":         "Danh mục không tồn tại hoặc thuộc về người khác",
+	"todo_forbidden":                "Bạn không có quyền thay đổi công việc này",
+	"todo_not_found":                "Không tìm thấy công việc",
+	"user_inactive":                 "Tài khoản người dùng đã bị vô hiệu hóa",
+	"user_not_found":                "Không tìm thấy người dùng",
+	"user_version_mismatch":         "Người dùng đã bị thay đổi kể từ lần đọc trước",
+}
@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	vitranslations "github.com/go-playground/validator/v10/translations/vi"
+)
+
+var (
+	validate     *validator.Validate
+	validateOnce sync.Once
+)
+
+// viRules replaces the upstream Vietnamese messages of rules that are left
+// partly in English.
+var viRules = map[string]string{
+	"email":    "{0} phải là một địa chỉ email hợp lệ",
+	"hexcolor": "{0} phải là một mã màu HEX hợp lệ",
+	"oneof":    "{0} phải là một trong các giá trị [{1}]",
+}
+
+// Validator returns the shared validator. It names fields by their JSON or
+// query parameter names, and its errors can be translated with every
+// translator of this package.
+func Validator() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New()
+		validate.RegisterTagNameFunc(fieldName)
+
+		enTrans, _ := universal.GetTranslator("en")
+		if err := entranslations.RegisterDefaultTranslations(validate, enTrans); err != nil {
+			panic(err)
+		}
+		viTrans, _ := universal.GetTranslator("vi")
+		if err := vitranslations.RegisterDefaultTranslations(validate, viTrans); err != nil {
+			panic(err)
+		}
+		for tag, text := range viRules {
+			err := validate.RegisterTranslation(tag, viTrans, func(trans ut.Translator) error {
+				return trans.Add(tag, text, true)
+			}, translateRule)
+			if err != nil {
+				panic(err)
+			}
+		}
+	})
+	return validate
+}
+
+// fieldName is the name clients use for a field: its JSON name, or its
+// query parameter name for fields bound from the URL.
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func translateRule(trans ut.Translator, fe validator.FieldError) string {
+	message, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
@@ -1,17 +1,44 @@
 package logging
 
 import (
+	"fmt"
+	"os"
 	"sync"
 
 	"github.com/mattn/go-colorable"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
 	once sync.Once
 )
 
+// Options configures the global logger built by Configure.
+type Options struct {
+	// Development selects the coloured console output; otherwise logs are JSON.
+	Development bool
+	// Level is a zap level name. Empty means debug in development and info
+	// otherwise.
+	Level string
+	// SamplingInitial and SamplingThereafter limit repeated entries outside
+	// development. Zero SamplingThereafter disables sampling.
+	SamplingInitial    int
+	SamplingThereafter int
+	// Unsampled names the loggers whose entries are never sampled.
+	Unsampled []string
+	// Redact masks secrets in messages and fields.
+	Redact bool
+	// File, when set, also writes JSON logs to this path, rotated by size.
+	File           string
+	FileMaxSizeMB  int
+	FileMaxBackups int
+	FileMaxAgeDays int
+	FileCompress   bool
+}
+
+// Init installs the development logger unless a logger is already set up.
 func Init() {
 	once.Do(func() {
 		zap.ReplaceGlobals(newLocalLogger())
@@ -19,14 +46,82 @@ func Init() {
 	})
 }
 
+// Configure replaces the global logger according to opts.
+func Configure(opts Options) error {
+	level := zapcore.InfoLevel
+	if opts.Development {
+		level = zapcore.DebugLevel
+	}
+	if opts.Level != "" {
+		var err error
+		if level, err = zapcore.ParseLevel(opts.Level); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", opts.Level, err)
+		}
+	}
+
+	var cores []zapcore.Core
+	if opts.Development {
+		cores = append(cores, newLocalCore(level))
+	} else {
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(jsonEncoderConfig()), zapcore.Lock(os.Stdout), level))
+	}
+	if opts.File != "" {
+		cores = append(cores, zapcore.NewCore(
+			zapcore.NewJSONEncoder(jsonEncoderConfig()),
+			zapcore.AddSync(&lumberjack.Logger{
+				Filename:   opts.File,
+				MaxSize:    opts.FileMaxSizeMB,
+				MaxBackups: opts.FileMaxBackups,
+				MaxAge:     opts.FileMaxAgeDays,
+				Compress:   opts.FileCompress,
+			}),
+			level,
+		))
+	}
+
+	var zapOpts []zap.Option
+	if !opts.Development {
+		zapOpts = append(zapOpts, zap.AddStacktrace(zapcore.ErrorLevel))
+	}
+	once.Do(func() {})
+	zap.ReplaceGlobals(zap.New(wrapCore(zapcore.NewTee(cores...), opts), zapOpts...))
+	zap.S().Infow("logger initialized", "development", opts.Development, "log_level", level.String(), "redact", opts.Redact)
+	return nil
+}
+
+// wrapCore adds redaction and sampling to core. Redaction sits below the
+// sampler: a wrapping core that adds itself to the checked entry would skip
+// the sampler's decision.
+func wrapCore(core zapcore.Core, opts Options) zapcore.Core {
+	if opts.Redact {
+		core = newRedactingCore(core)
+	}
+	if !opts.Development && opts.SamplingThereafter > 0 {
+		core = newSampledCore(core, opts.SamplingInitial, opts.SamplingThereafter, opts.Unsampled)
+	}
+	return core
+}
+
 func newLocalLogger() *zap.Logger {
+	return zap.New(newRedactingCore(newLocalCore(zapcore.DebugLevel)))
+}
+
+func newLocalCore(level zapcore.Level) zapcore.Core {
 	logCfg := zap.NewDevelopmentEncoderConfig()
 	logCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	return zap.New(zapcore.NewCore(
+	return zapcore.NewCore(
 		zapcore.NewConsoleEncoder(logCfg),
 		zapcore.AddSync(colorable.NewColorableStdout()),
-		zapcore.DebugLevel,
-	))
+		level,
+	)
+}
+
+func jsonEncoderConfig() zapcore.EncoderConfig {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.TimeKey = "time"
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	cfg.EncodeDuration = zapcore.MillisDurationEncoder
+	return cfg
 }
 
 func NewSugaredLogger(name string) *zap.SugaredLogger {
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWrapCoreSamplesRedactedEntries(t *testing.T) {
+	for _, redact := range []bool{false, true} {
+		inner, logs := observer.New(zapcore.InfoLevel)
+		logger := zap.New(wrapCore(inner, Options{Redact: redact, SamplingInitial: 1, SamplingThereafter: 49}))
+
+		// The first entry and every 49th after it pass the sampler.
+		for range 50 {
+			logger.Info("repeated", zap.String("password", "hunter2"))
+		}
+
+		if got := logs.Len(); got != 2 {
+			t.Fatalf("redact=%v: %d entries logged, want 2", redact, got)
+		}
+		password := logs.All()[0].ContextMap()["password"]
+		if redact && password != Redacted {
+			t.Fatalf("password logged as %q, want it redacted", password)
+		}
+	}
+}
+
+func TestWrapCoreKeepsUnsampledLoggers(t *testing.T) {
+	inner, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(wrapCore(inner, Options{Redact: true, SamplingInitial: 1, SamplingThereafter: 100, Unsampled: []string{"access"}}))
+
+	for range 50 {
+		logger.Named("access").Info("request", zap.String("authorization", "Bearer abc"))
+	}
+
+	if got := logs.Len(); got != 50 {
+		t.Fatalf("%d access log entries, want 50", got)
+	}
+	if got := logs.All()[0].ContextMap()["authorization"]; got != Redacted {
+		t.Fatalf("authorization logged as %q, want it redacted", got)
+	}
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"slices"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// unsampledCore samples entries except those of the exempt loggers. Zap
+// samples by level and message, so loggers that repeat one message for
+// distinct events, such as the access log, would otherwise lose entries.
+type unsampledCore struct {
+	sampled   zapcore.Core
+	unsampled zapcore.Core
+	exempt    []string
+}
+
+func newSampledCore(core zapcore.Core, initial, thereafter int, exempt []string) zapcore.Core {
+	return &unsampledCore{
+		sampled:   zapcore.NewSamplerWithOptions(core, time.Second, initial, thereafter),
+		unsampled: core,
+		exempt:    exempt,
+	}
+}
+
+func (c *unsampledCore) Enabled(level zapcore.Level) bool {
+	return c.unsampled.Enabled(level)
+}
+
+func (c *unsampledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &unsampledCore{
+		sampled:   c.sampled.With(fields),
+		unsampled: c.unsampled.With(fields),
+		exempt:    c.exempt,
+	}
+}
+
+func (c *unsampledCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if slices.Contains(c.exempt, entry.LoggerName) {
+		return c.unsampled.Check(entry, checked)
+	}
+	return c.sampled.Check(entry, checked)
+}
+
+// Write is not reached through Check, which adds the inner cores instead.
+func (c *unsampledCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.unsampled.Write(entry, fields)
+}
+
+func (c *unsampledCore) Sync() error {
+	return c.unsampled.Sync()
+}